@@ -9,6 +9,10 @@ var _ interfaces.ApiGateway = &apiGateway{}
 type apiGateway struct {
 }
 
+func (a *apiGateway) Init(settings map[string]string) error {
+	return nil
+}
+
 func (a *apiGateway) Start() error {
 	return nil
 }
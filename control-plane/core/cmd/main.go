@@ -4,11 +4,20 @@ import (
 	"os"
 
 	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/init/logger"
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/internal/audit"
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/internal/pki"
 	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/internal/process"
 	"go.uber.org/zap"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "pki" {
+		os.Exit(pki.Run(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "audit" {
+		os.Exit(audit.Run(os.Args[2:]))
+	}
+
 	logger.NewLogger()
 	defer handlePanic()
 
@@ -9,12 +9,14 @@ import (
 
 type Args struct {
 	ConfigPath string
+	Dev        bool
 }
 
 func ParseArgs() *Args {
 	args := &Args{}
 
 	flag.StringVar(&args.ConfigPath, "c", "", "path to config file (default: ./config.toml)")
+	flag.BoolVar(&args.Dev, "dev", false, "run an ephemeral development control plane (generated PKI, in-memory state)")
 
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, "Usage of %s:\n", os.Args[0])
@@ -23,10 +25,19 @@ func ParseArgs() *Args {
 		fmt.Fprintf(os.Stderr, "\nExamples:\n")
 		fmt.Fprintf(os.Stderr, "  %s                        # Use default config.toml\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s -c config.dev.toml     # Use specific config file\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s --dev                  # Zero-setup development mode\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "\nCommands:\n")
+		fmt.Fprintf(os.Stderr, "  %s pki help               # Certificate authority tooling\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s audit verify FILE      # Check the audit log hash chain\n", os.Args[0])
 	}
 
 	flag.Parse()
 
+	// Development mode runs on defaults unless a config file is given
+	if args.Dev && args.ConfigPath == "" {
+		return args
+	}
+
 	if args.ConfigPath == "" {
 		wd, err := os.Getwd()
 		if err != nil {
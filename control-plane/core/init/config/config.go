@@ -1,8 +1,11 @@
 package config
 
 import (
+	"crypto/rand"
+	"encoding/base64"
 	"fmt"
 	"os"
+	"slices"
 	"sync"
 	"time"
 
@@ -15,24 +18,225 @@ type TLSConfig struct {
 	CACert   string `toml:"ca_cert"`
 }
 
+type BootstrapTokenConfig struct {
+	Token       string    `toml:"token"`
+	Description string    `toml:"description"`
+	ExpiresAt   time.Time `toml:"expires_at"`
+	MaxUses     int       `toml:"max_uses"`
+	// RequireApproval queues registrations until an operator approves them
+	RequireApproval bool `toml:"require_approval"`
+}
+
 type AuthConfig struct {
-	TokenSecret   string        `toml:"token_secret"`
-	TokenDuration time.Duration `toml:"token_duration"`
-	CACertPath    string        `toml:"ca_cert_path"`
-	CAKeyPath     string        `toml:"ca_key_path"`
+	// TokenSecret signs HS256 tokens
+	TokenSecret        string        `toml:"token_secret"`
+	TokenDuration      time.Duration `toml:"token_duration"`
+	SigningAlgorithm   string        `toml:"signing_algorithm"`
+	SigningKeyRotation time.Duration `toml:"signing_key_rotation"`
+	// AcceptLegacyHS256 keeps HS256 tokens signed with TokenSecret verifiable
+	// under another algorithm until LegacyHS256Until, during a migration
+	AcceptLegacyHS256 bool      `toml:"accept_legacy_hs256"`
+	LegacyHS256Until  time.Time `toml:"legacy_hs256_until"`
+	// SigningKeyKEKFile holds the base64 encoded 256-bit key encrypting the
+	// token signing keys in the data store; they are stored in clear without it
+	SigningKeyKEKFile string `toml:"signing_key_kek_file"`
+	// AllowUnboundTokens accepts tokens issued before certificate binding;
+	// they are bound to the client certificate on their next rotation
+	AllowUnboundTokens bool     `toml:"allow_unbound_tokens"`
+	CACertPath         string   `toml:"ca_cert_path"`
+	CAKeyPath          string   `toml:"ca_key_path"`
+	CABundlePaths      []string `toml:"ca_bundle_paths"`
+	CAKeyPassphrase    string   `toml:"ca_key_passphrase"`
+	CASigner           string   `toml:"ca_signer"`
+	CAAgentSocket      string   `toml:"ca_agent_socket"`
+
+	BootstrapTokens []BootstrapTokenConfig `toml:"bootstrap_tokens"`
+}
+
+type AdminConfig struct {
+	// Token is a break-glass bearer token holding every admin permission;
+	// when empty only operator accounts and API keys are accepted
+	Token string `toml:"token"`
+}
+
+type RoleConfig struct {
+	Name        string   `toml:"name"`
+	Permissions []string `toml:"permissions"`
+}
+
+type AccessConfig struct {
+	// SessionTTL is the lifetime of the token an operator gets on login
+	SessionTTL time.Duration `toml:"session_ttl"`
+	// MaxAPIKeyTTL caps the lifetime of API keys; keys never expire when zero
+	MaxAPIKeyTTL time.Duration `toml:"max_api_key_ttl"`
+	// Roles adds custom roles next to the built-in viewer, operator and admin
+	Roles []RoleConfig `toml:"roles"`
+	// OIDC signs operators in through the identity provider of the organization
+	OIDC OIDCConfig `toml:"oidc"`
+}
+
+type OIDCConfig struct {
+	// Enabled serves the single sign-on endpoints on the HTTP server
+	Enabled bool `toml:"enabled"`
+	// Issuer is the provider URL its discovery document is fetched from
+	Issuer       string `toml:"issuer"`
+	ClientID     string `toml:"client_id"`
+	ClientSecret string `toml:"client_secret"`
+	// RedirectURL is the callback registered at the provider, ending in /auth/oidc/callback
+	RedirectURL string   `toml:"redirect_url"`
+	Scopes      []string `toml:"scopes"`
+	// UsernameClaim names the operator in audit records, such as sub,
+	// preferred_username or email, which is only accepted once verified by the
+	// provider. GroupsClaim lists the groups of the operator.
+	UsernameClaim string `toml:"username_claim"`
+	GroupsClaim   string `toml:"groups_claim"`
+	// GroupRoles maps provider groups to roles; operators matching no group
+	// get DefaultRoles, and are refused when it is empty
+	GroupRoles   map[string][]string `toml:"group_roles"`
+	DefaultRoles []string            `toml:"default_roles"`
+	// PostLoginURL is where the browser lands once signed in
+	PostLoginURL string `toml:"post_login_url"`
+}
+
+type HTTPConfig struct {
+	// ListenAddr of the HTTP server serving the JWKS, metrics and single sign-on; disabled when empty
+	ListenAddr string `toml:"listen_addr"`
+	// Plaintext serves HTTP without TLS, for deployments behind a terminating proxy
+	Plaintext bool `toml:"plaintext"`
+}
+
+type SessionConfig struct {
+	// IdleTimeout expires sessions without stream traffic for that long
+	IdleTimeout time.Duration `toml:"idle_timeout"`
+	// AbsoluteTimeout expires sessions that long after creation regardless of activity
+	AbsoluteTimeout time.Duration `toml:"absolute_timeout"`
+	// CommandQueueSize bounds the commands of each priority waiting to be sent
+	// on the stream of a session; commands are refused while their lane is full
+	CommandQueueSize int `toml:"command_queue_size"`
+}
+
+type OutboxConfig struct {
+	// DefaultTTL expires commands waiting for a node that does not acknowledge
+	// them in time, unless the command sets its own TTL
+	DefaultTTL time.Duration `toml:"default_ttl"`
+	// MaxTTL bounds the TTL a command may ask for
+	MaxTTL time.Duration `toml:"max_ttl"`
+	// MaxPending bounds the commands waiting for one node; commands are refused while it is full
+	MaxPending int `toml:"max_pending"`
+}
+
+type DrainConfig struct {
+	// Timeout bounds the wait for node streams to close once told to
+	// reconnect; the streams still open are then closed
+	Timeout time.Duration `toml:"timeout"`
+	// MinReconnectWait and MaxReconnectWait bound the random delay nodes wait
+	// before reconnecting, so the fleet does not reconnect all at once
+	MinReconnectWait time.Duration `toml:"min_reconnect_wait"`
+	MaxReconnectWait time.Duration `toml:"max_reconnect_wait"`
+}
+
+type RegistrationConfig struct {
+	// PendingTimeout expires registrations nobody approved or rejected in time
+	PendingTimeout time.Duration `toml:"pending_timeout"`
+}
+
+type IdentityConfig struct {
+	// Match lists, in order, how re-registrations are matched to an existing
+	// node: public_key, machine_id or hostname. Nodes always get a new ID when
+	// empty. machine_id and hostname matches without a public_key match are
+	// held for operator approval.
+	Match []string `toml:"match"`
+	// Labels are combined with the hostname to form the hostname identity key
+	Labels []string `toml:"labels"`
+}
+
+type RateLimitConfig struct {
+	// Enabled applies the limits to unauthenticated RPCs
+	Enabled bool `toml:"enabled"`
+	// PeerRate and PeerBurst size the token bucket of each peer address, in requests per second
+	PeerRate  float64 `toml:"peer_rate"`
+	PeerBurst int     `toml:"peer_burst"`
+	// NodeRate and NodeBurst size the token bucket of each node client certificate
+	NodeRate  float64 `toml:"node_rate"`
+	NodeBurst int     `toml:"node_burst"`
+	// LockoutThreshold failures within FailureWindow lock the peer or node out
+	// for LockoutDuration, doubled on each repeated lockout up to LockoutMax
+	LockoutThreshold int           `toml:"lockout_threshold"`
+	FailureWindow    time.Duration `toml:"failure_window"`
+	LockoutDuration  time.Duration `toml:"lockout_duration"`
+	LockoutMax       time.Duration `toml:"lockout_max"`
+}
+
+type AdmissionConfig struct {
+	// Enabled caps node handshakes and new streams, so a reconnecting fleet is
+	// admitted at a sustainable pace
+	Enabled bool `toml:"enabled"`
+	// MaxConcurrentHandshakes bounds the Authenticate calls and stream
+	// authentications running at once
+	MaxConcurrentHandshakes int `toml:"max_concurrent_handshakes"`
+	// MaxQueuedHandshakes wait up to QueueTimeout for a slot, more are refused
+	MaxQueuedHandshakes int           `toml:"max_queued_handshakes"`
+	QueueTimeout        time.Duration `toml:"queue_timeout"`
+	// StreamRate and StreamBurst size the token bucket of new streams, in streams per second
+	StreamRate  float64 `toml:"stream_rate"`
+	StreamBurst int     `toml:"stream_burst"`
+	// MaxRetryDelay caps the delay refused nodes are told to wait before retrying
+	MaxRetryDelay time.Duration `toml:"max_retry_delay"`
+}
+
+type AuditConfig struct {
+	// Path of the hash-chained audit log; records are only forwarded when empty
+	Path string `toml:"path"`
+	// Forward copies records to the data store, which serves admin queries
+	Forward bool `toml:"forward"`
+}
+
+type ConnectionConfig struct {
+	// KeepaliveTime pings connections idle for that long; nodes are told to do the same
+	KeepaliveTime time.Duration `toml:"keepalive_time"`
+	// KeepaliveTimeout closes connections whose ping is not acknowledged in time
+	KeepaliveTimeout time.Duration `toml:"keepalive_timeout"`
+	// MinPingInterval closes connections of clients pinging more often; at most KeepaliveTime
+	MinPingInterval time.Duration `toml:"min_ping_interval"`
+	// PermitWithoutStream allows pings while no RPC is active
+	PermitWithoutStream bool `toml:"permit_without_stream"`
+	// MaxReconnectDelay caps the reconnection backoff of nodes
+	MaxReconnectDelay time.Duration `toml:"max_reconnect_delay"`
+	// MaxConnectionIdle and MaxConnectionAge close connections idle or older
+	// than that, after MaxConnectionAgeGrace for running RPCs; unlimited when zero
+	MaxConnectionIdle     time.Duration `toml:"max_connection_idle"`
+	MaxConnectionAge      time.Duration `toml:"max_connection_age"`
+	MaxConnectionAgeGrace time.Duration `toml:"max_connection_age_grace"`
+	// MaxRecvMessageSize and MaxSendMessageSize bound messages, in bytes
+	MaxRecvMessageSize int `toml:"max_recv_message_size"`
+	MaxSendMessageSize int `toml:"max_send_message_size"`
+	// MaxConcurrentStreams bounds the RPCs running on one connection
+	MaxConcurrentStreams uint32 `toml:"max_concurrent_streams"`
 }
 
 type CoreConfig struct {
-	ListenAddr       string            `toml:"listen_addr"`
-	APIEndpoint      string            `toml:"api_endpoint"`
-	TLS              TLSConfig         `toml:"tls"`
-	Auth             AuthConfig        `toml:"auth"`
-	ConnectionParams map[string]string `toml:"connection_params"`
+	ListenAddr   string             `toml:"listen_addr"`
+	APIEndpoint  string             `toml:"api_endpoint"`
+	TLS          TLSConfig          `toml:"tls"`
+	Auth         AuthConfig         `toml:"auth"`
+	Admin        AdminConfig        `toml:"admin"`
+	Access       AccessConfig       `toml:"access"`
+	HTTP         HTTPConfig         `toml:"http"`
+	Sessions     SessionConfig      `toml:"sessions"`
+	Outbox       OutboxConfig       `toml:"outbox"`
+	Drain        DrainConfig        `toml:"drain"`
+	Registration RegistrationConfig `toml:"registration"`
+	Identity     IdentityConfig     `toml:"identity"`
+	RateLimit    RateLimitConfig    `toml:"rate_limit"`
+	Admission    AdmissionConfig    `toml:"admission"`
+	Audit        AuditConfig        `toml:"audit"`
+	Connection   ConnectionConfig   `toml:"connection_params"`
 }
 
 type PluginsConfig struct {
-	Path string   `toml:"path"`
-	Load []string `toml:"load"`
+	Path     string                       `toml:"path"`
+	Load     []string                     `toml:"load"`
+	Settings map[string]map[string]string `toml:"settings"`
 }
 
 type LogConfig struct {
@@ -44,6 +248,9 @@ type Config struct {
 	Core    CoreConfig    `toml:"core"`
 	Plugins PluginsConfig `toml:"plugins"`
 	Log     LogConfig     `toml:"log"`
+
+	// Dev is set by the --dev flag, never by the config file
+	Dev bool `toml:"-"`
 }
 
 func DefaultConfig() *Config {
@@ -54,16 +261,80 @@ func DefaultConfig() *Config {
 			TLS: TLSConfig{
 				CertFile: "/etc/luminous-mesh/certs/server.crt",
 				KeyFile:  "/etc/luminous-mesh/certs/server.key",
+				CACert:   "/etc/luminous-mesh/certs/ca.crt",
 			},
 			Auth: AuthConfig{
-				TokenSecret:   "default-secret",
-				TokenDuration: 24 * time.Hour,
-				CACertPath:    "/etc/luminous-mesh/certs/ca.crt",
-				CAKeyPath:     "/etc/luminous-mesh/certs/ca.key",
+				TokenDuration:      24 * time.Hour,
+				SigningAlgorithm:   "EdDSA",
+				SigningKeyRotation: 30 * 24 * time.Hour,
+				CACertPath:         "/etc/luminous-mesh/certs/ca.crt",
+				CAKeyPath:          "/etc/luminous-mesh/certs/ca.key",
+				CASigner:           "file",
+			},
+			Access: AccessConfig{
+				SessionTTL:   12 * time.Hour,
+				MaxAPIKeyTTL: 365 * 24 * time.Hour,
+				OIDC: OIDCConfig{
+					Scopes:        []string{"openid", "profile", "email", "groups"},
+					UsernameClaim: "email",
+					GroupsClaim:   "groups",
+					PostLoginURL:  "/",
+				},
+			},
+			HTTP: HTTPConfig{
+				ListenAddr: ":8443",
+			},
+			Sessions: SessionConfig{
+				IdleTimeout:      15 * time.Minute,
+				AbsoluteTimeout:  24 * time.Hour,
+				CommandQueueSize: 100,
+			},
+			Outbox: OutboxConfig{
+				DefaultTTL: 24 * time.Hour,
+				MaxTTL:     7 * 24 * time.Hour,
+				MaxPending: 1000,
+			},
+			Drain: DrainConfig{
+				Timeout:          30 * time.Second,
+				MinReconnectWait: 5 * time.Second,
+				MaxReconnectWait: 60 * time.Second,
+			},
+			Registration: RegistrationConfig{
+				PendingTimeout: 72 * time.Hour,
 			},
-			ConnectionParams: map[string]string{
-				"max_reconnect_delay": "60s",
-				"keepalive_time":      "30s",
+			RateLimit: RateLimitConfig{
+				Enabled:          true,
+				PeerRate:         1,
+				PeerBurst:        10,
+				NodeRate:         0.2,
+				NodeBurst:        5,
+				LockoutThreshold: 5,
+				FailureWindow:    15 * time.Minute,
+				LockoutDuration:  time.Minute,
+				LockoutMax:       time.Hour,
+			},
+			Admission: AdmissionConfig{
+				Enabled:                 true,
+				MaxConcurrentHandshakes: 64,
+				MaxQueuedHandshakes:     512,
+				QueueTimeout:            2 * time.Second,
+				StreamRate:              50,
+				StreamBurst:             100,
+				MaxRetryDelay:           60 * time.Second,
+			},
+			Audit: AuditConfig{
+				Path:    "/var/log/luminous-mesh/audit.log",
+				Forward: true,
+			},
+			Connection: ConnectionConfig{
+				KeepaliveTime:        30 * time.Second,
+				KeepaliveTimeout:     10 * time.Second,
+				MinPingInterval:      15 * time.Second,
+				PermitWithoutStream:  true,
+				MaxReconnectDelay:    60 * time.Second,
+				MaxRecvMessageSize:   4 << 20,
+				MaxSendMessageSize:   4 << 20,
+				MaxConcurrentStreams: 100,
 			},
 		},
 		Plugins: PluginsConfig{
@@ -86,11 +357,22 @@ func Get() *Config {
 	once.Do(func() {
 		args := ParseArgs()
 
-		cfg, err := LoadConfig(args.ConfigPath)
-		if err != nil {
-			panic(fmt.Errorf("❌ config load failed: %w", err))
+		cfg := DefaultConfig()
+		if args.ConfigPath != "" {
+			loaded, err := LoadConfig(args.ConfigPath)
+			if err != nil {
+				panic(fmt.Errorf("❌ config load failed: %w", err))
+			}
+			cfg = loaded
+		}
+
+		if args.Dev {
+			if err := cfg.applyDevMode(); err != nil {
+				panic(fmt.Errorf("❌ development mode setup failed: %w", err))
+			}
 		}
-		err = cfg.Validate()
+
+		err := cfg.Validate()
 		if err != nil {
 			panic(fmt.Errorf("❌ config validation failed: %w", err))
 		}
@@ -99,8 +381,16 @@ func Get() *Config {
 	return instance
 }
 
+// Set installs cfg as the configuration returned by Get, in place of the one
+// read from the command line; tests use it
+func Set(cfg *Config) {
+	once.Do(func() {})
+	instance = cfg
+}
+
+// LoadConfig decodes the file over the defaults, so omitted keys keep their default value
 func LoadConfig(path string) (*Config, error) {
-	var cfg Config
+	cfg := DefaultConfig()
 
 	f, err := os.Open(path)
 	if err != nil {
@@ -108,20 +398,66 @@ func LoadConfig(path string) (*Config, error) {
 	}
 	defer f.Close()
 
-	if _, err := toml.NewDecoder(f).Decode(&cfg); err != nil {
+	if _, err := toml.NewDecoder(f).Decode(cfg); err != nil {
 		return nil, fmt.Errorf("❌ Failed to decode config: %w", err)
 	}
 
-	return &cfg, nil
+	return cfg, nil
 }
 
-func (c *Config) Validate() error {
-	if c.Plugins.Path == "" {
-		return fmt.Errorf("❌ Plugins path is not set")
+// applyDevMode replaces secrets with random values and mints a bootstrap token.
+// Nothing generated here outlives the process.
+func (c *Config) applyDevMode() error {
+	c.Dev = true
+
+	if c.Core.Auth.SigningAlgorithm == "HS256" {
+		secret, err := randomToken(32)
+		if err != nil {
+			return err
+		}
+		c.Core.Auth.TokenSecret = secret
 	}
 
-	if len(c.Plugins.Load) == 0 {
-		return fmt.Errorf("❌ No plugins to load")
+	bootstrap, err := randomToken(24)
+	if err != nil {
+		return err
+	}
+	c.Core.Auth.BootstrapTokens = append(c.Core.Auth.BootstrapTokens, BootstrapTokenConfig{
+		Token:       bootstrap,
+		Description: "development",
+	})
+
+	admin, err := randomToken(32)
+	if err != nil {
+		return err
+	}
+	c.Core.Admin.Token = admin
+
+	// Audit records live in the in-memory data store, a fresh chain every run
+	c.Core.Audit.Path = ""
+	c.Core.Audit.Forward = true
+
+	c.Log.Level = "debug"
+	return nil
+}
+
+func randomToken(size int) (string, error) {
+	buf := make([]byte, size)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate random token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func (c *Config) Validate() error {
+	if !c.Dev {
+		if c.Plugins.Path == "" {
+			return fmt.Errorf("❌ Plugins path is not set")
+		}
+
+		if len(c.Plugins.Load) == 0 {
+			return fmt.Errorf("❌ No plugins to load")
+		}
 	}
 
 	if c.Core.ListenAddr == "" {
@@ -140,7 +476,43 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("invalid auth configuration: %w", err)
 	}
 
-	if err := validateConnectionParams(&c.Core.ConnectionParams); err != nil {
+	if err := validateSessionConfig(&c.Core.Sessions); err != nil {
+		return fmt.Errorf("invalid session configuration: %w", err)
+	}
+
+	if err := validateOutboxConfig(&c.Core.Outbox); err != nil {
+		return fmt.Errorf("invalid outbox configuration: %w", err)
+	}
+
+	if err := validateDrainConfig(&c.Core.Drain); err != nil {
+		return fmt.Errorf("invalid drain configuration: %w", err)
+	}
+
+	if c.Core.Registration.PendingTimeout <= 0 {
+		return fmt.Errorf("invalid registration configuration: pending_timeout is required")
+	}
+
+	if err := validateIdentityConfig(&c.Core.Identity); err != nil {
+		return fmt.Errorf("invalid identity configuration: %w", err)
+	}
+
+	if err := validateRateLimitConfig(&c.Core.RateLimit); err != nil {
+		return fmt.Errorf("invalid rate limit configuration: %w", err)
+	}
+
+	if err := validateAdmissionConfig(&c.Core.Admission); err != nil {
+		return fmt.Errorf("invalid admission configuration: %w", err)
+	}
+
+	if c.Core.Admin.Token != "" && len(c.Core.Admin.Token) < 32 {
+		return fmt.Errorf("admin token must be at least 32 characters")
+	}
+
+	if err := validateAccessConfig(&c.Core.Access); err != nil {
+		return fmt.Errorf("invalid access configuration: %w", err)
+	}
+
+	if err := validateConnectionConfig(&c.Core.Connection); err != nil {
 		return fmt.Errorf("invalid connection parameters: %w", err)
 	}
 	return nil
@@ -163,32 +535,263 @@ func validateTLSConfig(config *TLSConfig) error {
 }
 
 func validateAuthConfig(config *AuthConfig) error {
-	if config.TokenSecret == "" {
-		return fmt.Errorf("token_secret is required")
-	}
-
 	if config.TokenDuration == 0 {
 		return fmt.Errorf("token_duration is required")
 	}
 
+	switch config.SigningAlgorithm {
+	case "EdDSA", "ES256", "RS256":
+		if config.SigningKeyRotation < config.TokenDuration {
+			return fmt.Errorf("signing_key_rotation must be at least token_duration")
+		}
+		if config.AcceptLegacyHS256 {
+			if config.TokenSecret == "" {
+				return fmt.Errorf("token_secret is required with accept_legacy_hs256")
+			}
+			if config.LegacyHS256Until.IsZero() {
+				return fmt.Errorf("legacy_hs256_until is required with accept_legacy_hs256")
+			}
+		}
+	case "HS256":
+		if config.TokenSecret == "" {
+			return fmt.Errorf("token_secret is required with HS256 signing")
+		}
+	default:
+		return fmt.Errorf("unknown signing_algorithm %q (expected EdDSA, ES256, RS256 or HS256)", config.SigningAlgorithm)
+	}
+
+	for i, token := range config.BootstrapTokens {
+		if len(token.Token) < 16 {
+			return fmt.Errorf("bootstrap_tokens[%d]: token must be at least 16 characters", i)
+		}
+	}
+
 	if config.CACertPath == "" {
 		return fmt.Errorf("ca_cert_path is required")
 	}
 
-	if config.CAKeyPath == "" {
-		return fmt.Errorf("ca_key_path is required")
+	switch config.CASigner {
+	case "", "file":
+		if config.CAKeyPath == "" {
+			return fmt.Errorf("ca_key_path is required")
+		}
+	case "agent":
+		if config.CAAgentSocket == "" {
+			return fmt.Errorf("ca_agent_socket is required with the agent signer")
+		}
+	default:
+		return fmt.Errorf("unknown ca_signer %q (expected file or agent)", config.CASigner)
 	}
 
 	return nil
 }
 
-func validateConnectionParams(config *map[string]string) error {
-	if _, ok := (*config)["max_reconnect_delay"]; !ok {
-		return fmt.Errorf("max_reconnect_delay is required")
+func validateSessionConfig(config *SessionConfig) error {
+	if config.IdleTimeout <= 0 {
+		return fmt.Errorf("idle_timeout is required")
+	}
+
+	if config.AbsoluteTimeout < config.IdleTimeout {
+		return fmt.Errorf("absolute_timeout must be at least idle_timeout")
+	}
+
+	if config.CommandQueueSize < 1 || config.CommandQueueSize > 10000 {
+		return fmt.Errorf("command_queue_size must be between 1 and 10000")
+	}
+
+	return nil
+}
+
+func validateAdmissionConfig(config *AdmissionConfig) error {
+	if !config.Enabled {
+		return nil
+	}
+
+	if config.MaxConcurrentHandshakes < 1 {
+		return fmt.Errorf("max_concurrent_handshakes must be at least 1")
+	}
+
+	if config.MaxQueuedHandshakes < 0 {
+		return fmt.Errorf("max_queued_handshakes must not be negative")
+	}
+
+	if config.QueueTimeout <= 0 {
+		return fmt.Errorf("queue_timeout is required")
+	}
+
+	if config.StreamRate <= 0 || config.StreamBurst < 1 {
+		return fmt.Errorf("stream_rate must be positive and stream_burst at least 1")
+	}
+
+	if config.MaxRetryDelay < time.Second {
+		return fmt.Errorf("max_retry_delay must be at least 1s")
+	}
+
+	return nil
+}
+
+func validateOutboxConfig(config *OutboxConfig) error {
+	if config.DefaultTTL <= 0 {
+		return fmt.Errorf("default_ttl is required")
+	}
+
+	if config.MaxTTL < config.DefaultTTL {
+		return fmt.Errorf("max_ttl must be at least default_ttl")
+	}
+
+	if config.MaxPending < 1 || config.MaxPending > 100000 {
+		return fmt.Errorf("max_pending must be between 1 and 100000")
+	}
+
+	return nil
+}
+
+func validateDrainConfig(config *DrainConfig) error {
+	if config.Timeout <= 0 {
+		return fmt.Errorf("timeout is required")
+	}
+
+	if config.MinReconnectWait < 0 || config.MaxReconnectWait < config.MinReconnectWait {
+		return fmt.Errorf("max_reconnect_wait must be at least min_reconnect_wait, which must not be negative")
+	}
+
+	if config.MaxReconnectWait > time.Hour {
+		return fmt.Errorf("max_reconnect_wait must be at most 1h")
+	}
+
+	return nil
+}
+
+func validateIdentityConfig(config *IdentityConfig) error {
+	seen := make(map[string]bool)
+	for _, strategy := range config.Match {
+		switch strategy {
+		case "public_key", "machine_id", "hostname":
+		default:
+			return fmt.Errorf("unknown match strategy %q (expected public_key, machine_id or hostname)", strategy)
+		}
+		if seen[strategy] {
+			return fmt.Errorf("match strategy %q is listed twice", strategy)
+		}
+		seen[strategy] = true
+	}
+
+	return nil
+}
+
+func validateRateLimitConfig(config *RateLimitConfig) error {
+	if !config.Enabled {
+		return nil
+	}
+
+	if config.PeerRate <= 0 || config.PeerBurst < 1 {
+		return fmt.Errorf("peer_rate and peer_burst must be positive")
+	}
+
+	if config.NodeRate <= 0 || config.NodeBurst < 1 {
+		return fmt.Errorf("node_rate and node_burst must be positive")
+	}
+
+	if config.LockoutThreshold < 1 || config.FailureWindow <= 0 {
+		return fmt.Errorf("lockout_threshold and failure_window must be positive")
+	}
+
+	if config.LockoutDuration <= 0 || config.LockoutMax < config.LockoutDuration {
+		return fmt.Errorf("lockout_duration must be positive and lockout_max at least lockout_duration")
+	}
+
+	return nil
+}
+
+func validateAccessConfig(config *AccessConfig) error {
+	if config.SessionTTL <= 0 {
+		return fmt.Errorf("session_ttl is required")
+	}
+
+	if config.MaxAPIKeyTTL < 0 {
+		return fmt.Errorf("max_api_key_ttl must not be negative")
+	}
+
+	seen := make(map[string]bool)
+	for i, role := range config.Roles {
+		if role.Name == "" {
+			return fmt.Errorf("roles[%d]: name is required", i)
+		}
+		if seen[role.Name] {
+			return fmt.Errorf("roles[%d]: role %q is defined twice", i, role.Name)
+		}
+		seen[role.Name] = true
+	}
+
+	if err := validateOIDCConfig(&config.OIDC); err != nil {
+		return fmt.Errorf("oidc: %w", err)
+	}
+
+	return nil
+}
+
+func validateOIDCConfig(config *OIDCConfig) error {
+	if !config.Enabled {
+		return nil
+	}
+
+	if config.Issuer == "" || config.ClientID == "" || config.RedirectURL == "" {
+		return fmt.Errorf("issuer, client_id and redirect_url are required")
+	}
+
+	if !slices.Contains(config.Scopes, "openid") {
+		return fmt.Errorf("scopes must include openid")
+	}
+
+	if config.UsernameClaim == "" {
+		return fmt.Errorf("username_claim is required")
+	}
+
+	if len(config.GroupRoles) == 0 && len(config.DefaultRoles) == 0 {
+		return fmt.Errorf("group_roles or default_roles is required, no operator could sign in")
+	}
+
+	return nil
+}
+
+func validateConnectionConfig(config *ConnectionConfig) error {
+	durations := []struct {
+		name     string
+		value    time.Duration
+		min, max time.Duration
+	}{
+		// gRPC clients refuse keepalive times under 10s
+		{"keepalive_time", config.KeepaliveTime, 10 * time.Second, 2 * time.Hour},
+		{"keepalive_timeout", config.KeepaliveTimeout, time.Second, 5 * time.Minute},
+		{"min_ping_interval", config.MinPingInterval, time.Second, config.KeepaliveTime},
+		{"max_reconnect_delay", config.MaxReconnectDelay, time.Second, time.Hour},
+	}
+	for _, d := range durations {
+		if d.value < d.min || d.value > d.max {
+			return fmt.Errorf("%s must be between %s and %s, got %s", d.name, d.min, d.max, d.value)
+		}
+	}
+
+	if config.MaxConnectionIdle < 0 || config.MaxConnectionAgeGrace < 0 {
+		return fmt.Errorf("max_connection_idle and max_connection_age_grace must not be negative")
+	}
+
+	if config.MaxConnectionAge != 0 && config.MaxConnectionAge < time.Minute {
+		return fmt.Errorf("max_connection_age must be at least 1m, or 0 for unlimited")
+	}
+
+	const minMessageSize, maxMessageSize = 1 << 10, 64 << 20
+	for name, size := range map[string]int{
+		"max_recv_message_size": config.MaxRecvMessageSize,
+		"max_send_message_size": config.MaxSendMessageSize,
+	} {
+		if size < minMessageSize || size > maxMessageSize {
+			return fmt.Errorf("%s must be between %d and %d bytes", name, minMessageSize, maxMessageSize)
+		}
 	}
 
-	if _, ok := (*config)["keepalive_time"]; !ok {
-		return fmt.Errorf("keepalive_time is required")
+	if config.MaxConcurrentStreams < 1 || config.MaxConcurrentStreams > 10000 {
+		return fmt.Errorf("max_concurrent_streams must be between 1 and 10000")
 	}
 
 	return nil
@@ -19,3 +19,13 @@ func NewLogger() *zap.Logger {
 func L() *zap.Logger {
 	return logger
 }
+
+// NewDevelopmentLogger replaces the logger with a human-readable console logger
+func NewDevelopmentLogger() *zap.Logger {
+	l, err := zap.NewDevelopment()
+	if err != nil {
+		panic(err)
+	}
+	logger = l
+	return l
+}
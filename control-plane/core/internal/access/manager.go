@@ -0,0 +1,625 @@
+package access
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/init/config"
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/init/logger"
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/shared/interfaces"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Buckets holding operator accounts and API keys
+const (
+	usersBucket   = "access.users"
+	apiKeysBucket = "access.api_keys"
+)
+
+// Credential prefixes, telling API keys and login sessions apart from the admin token
+const (
+	apiKeyPrefix  = "lmk_"
+	sessionPrefix = "lms_"
+)
+
+const minPasswordLength = 12
+
+var (
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrPermissionDenied   = errors.New("permission denied")
+	ErrUserExists         = errors.New("user already exists")
+	ErrUserNotFound       = errors.New("user not found")
+	ErrAPIKeyNotFound     = errors.New("api key not found")
+	ErrInvalidRequest     = errors.New("invalid request")
+)
+
+var usernamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{0,63}$`)
+
+// dummyHash is compared against on logins of unknown users, so they take as long as real ones
+var dummyHash = sync.OnceValue(func() []byte {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("luminous-mesh-dummy-password"), bcrypt.DefaultCost)
+	return hash
+})
+
+// User is a local operator account
+type User struct {
+	Username     string    `json:"username"`
+	PasswordHash []byte    `json:"password_hash"`
+	Roles        []string  `json:"roles"`
+	CreatedAt    time.Time `json:"created_at"`
+	CreatedBy    string    `json:"created_by"`
+}
+
+// APIKey is a scoped credential for automation. Its permissions are also
+// capped by those its owner holds when it is used.
+type APIKey struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	Owner       string    `json:"owner"`
+	SecretHash  string    `json:"secret_hash"`
+	Permissions []string  `json:"permissions"`
+	CreatedAt   time.Time `json:"created_at"`
+	ExpiresAt   time.Time `json:"expires_at,omitempty"`
+}
+
+// Role is a named set of permissions
+type Role struct {
+	Name        string
+	Permissions []string
+	Builtin     bool
+}
+
+// session is a logged in operator: a local user, or a federated identity
+// holding the roles mapped at sign-in
+type session struct {
+	username  string
+	subject   string
+	roles     []string
+	expiresAt time.Time
+}
+
+// Manager authenticates operators and holds their accounts, API keys and roles
+type Manager struct {
+	config     *config.AccessConfig
+	adminToken string
+	store      interfaces.DataStore
+	roles      map[string][]string
+	users      map[string]*User
+	apiKeys    map[string]*APIKey
+	sessions   map[string]*session
+	mu         sync.RWMutex
+}
+
+// NewManager loads the roles from the configuration and the accounts from store
+func NewManager(store interfaces.DataStore) (*Manager, error) {
+	cfg := config.Get()
+
+	m := &Manager{
+		config:     &cfg.Core.Access,
+		adminToken: cfg.Core.Admin.Token,
+		store:      store,
+		roles:      make(map[string][]string),
+		users:      make(map[string]*User),
+		apiKeys:    make(map[string]*APIKey),
+		sessions:   make(map[string]*session),
+	}
+
+	for name, permissions := range builtinRoles {
+		m.roles[name] = permissions
+	}
+	for _, role := range m.config.Roles {
+		if _, ok := builtinRoles[role.Name]; ok {
+			return nil, fmt.Errorf("role %q is built in and cannot be redefined", role.Name)
+		}
+		for _, p := range role.Permissions {
+			if !isPermission(p) {
+				return nil, fmt.Errorf("role %q: unknown permission %q", role.Name, p)
+			}
+		}
+		m.roles[role.Name] = role.Permissions
+	}
+
+	if store == nil {
+		return m, nil
+	}
+
+	users, err := store.List(usersBucket)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load users: %w", err)
+	}
+	for _, data := range users {
+		var user User
+		if err := json.Unmarshal(data, &user); err != nil {
+			return nil, fmt.Errorf("failed to decode user: %w", err)
+		}
+		m.users[user.Username] = &user
+	}
+
+	keys, err := store.List(apiKeysBucket)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load api keys: %w", err)
+	}
+	for _, data := range keys {
+		var key APIKey
+		if err := json.Unmarshal(data, &key); err != nil {
+			return nil, fmt.Errorf("failed to decode api key: %w", err)
+		}
+		m.apiKeys[key.ID] = &key
+	}
+
+	return m, nil
+}
+
+// Authenticate resolves a bearer credential: the admin token, an API key or a login session
+func (m *Manager) Authenticate(credential string) (*Identity, error) {
+	if credential == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	switch {
+	case strings.HasPrefix(credential, apiKeyPrefix):
+		id, secret, ok := strings.Cut(strings.TrimPrefix(credential, apiKeyPrefix), "_")
+		if !ok {
+			return nil, ErrInvalidCredentials
+		}
+		key, ok := m.apiKeys[id]
+		if !ok || subtle.ConstantTimeCompare([]byte(hashSecret(secret)), []byte(key.SecretHash)) != 1 {
+			return nil, ErrInvalidCredentials
+		}
+		if !key.ExpiresAt.IsZero() && now.After(key.ExpiresAt) {
+			return nil, ErrInvalidCredentials
+		}
+		return m.apiKeyIdentity(key)
+
+	case strings.HasPrefix(credential, sessionPrefix):
+		hash := hashSecret(credential)
+		s, ok := m.sessions[hash]
+		if !ok {
+			return nil, ErrInvalidCredentials
+		}
+		if now.After(s.expiresAt) {
+			delete(m.sessions, hash)
+			return nil, ErrInvalidCredentials
+		}
+		if s.username == "" {
+			return m.roleIdentity(s.subject, KindOIDC, s.roles), nil
+		}
+		user, ok := m.users[s.username]
+		if !ok {
+			delete(m.sessions, hash)
+			return nil, ErrInvalidCredentials
+		}
+		return m.userIdentity(user), nil
+
+	case m.adminToken != "" && subtle.ConstantTimeCompare([]byte(credential), []byte(m.adminToken)) == 1:
+		return &Identity{
+			Subject:     KindAdminToken,
+			Kind:        KindAdminToken,
+			Roles:       []string{RoleAdmin},
+			Permissions: permissionSet(AllPermissions),
+		}, nil
+	}
+
+	return nil, ErrInvalidCredentials
+}
+
+// Login checks the password of a user and opens a session, returning its token
+func (m *Manager) Login(username, password string) (string, time.Time, *Identity, error) {
+	m.mu.RLock()
+	user, ok := m.users[username]
+	m.mu.RUnlock()
+
+	hash := dummyHash()
+	if ok {
+		hash = user.PasswordHash
+	}
+	if err := bcrypt.CompareHashAndPassword(hash, []byte(password)); err != nil || !ok {
+		return "", time.Time{}, nil, ErrInvalidCredentials
+	}
+
+	token, expiresAt, err := m.openSession(&session{username: username})
+	if err != nil {
+		return "", time.Time{}, nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return token, expiresAt, m.userIdentity(user), nil
+}
+
+// OpenFederatedSession opens a session for an operator authenticated by an
+// identity provider, holding roles. Unknown roles are dropped.
+func (m *Manager) OpenFederatedSession(subject string, roles []string) (string, time.Time, *Identity, error) {
+	known := make([]string, 0, len(roles))
+	for _, role := range roles {
+		if _, ok := m.roles[role]; ok && !slices.Contains(known, role) {
+			known = append(known, role)
+		}
+	}
+	if len(known) == 0 {
+		return "", time.Time{}, nil, fmt.Errorf("%w: no known role granted to %s", ErrPermissionDenied, subject)
+	}
+
+	token, expiresAt, err := m.openSession(&session{subject: subject, roles: known})
+	if err != nil {
+		return "", time.Time{}, nil, err
+	}
+	return token, expiresAt, m.roleIdentity(subject, KindOIDC, known), nil
+}
+
+// Logout closes the session of token, if any
+func (m *Manager) Logout(token string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.sessions, hashSecret(token))
+}
+
+// openSession stores s under a new token
+func (m *Manager) openSession(s *session) (string, time.Time, error) {
+	token, err := randomSecret()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	token = sessionPrefix + token
+	s.expiresAt = time.Now().Add(m.config.SessionTTL)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.pruneSessions()
+	m.sessions[hashSecret(token)] = s
+
+	return token, s.expiresAt, nil
+}
+
+// CreateUser adds an operator account. The grantor must hold every permission of the roles granted.
+func (m *Manager) CreateUser(grantor *Identity, username, password string, roles []string) (*User, error) {
+	if !usernamePattern.MatchString(username) {
+		return nil, fmt.Errorf("%w: invalid username %q", ErrInvalidRequest, username)
+	}
+	if len(password) < minPasswordLength {
+		return nil, fmt.Errorf("%w: password must be at least %d characters", ErrInvalidRequest, minPasswordLength)
+	}
+	if len(roles) == 0 {
+		return nil, fmt.Errorf("%w: at least one role is required", ErrInvalidRequest)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[username]; ok {
+		return nil, ErrUserExists
+	}
+	for _, role := range roles {
+		permissions, ok := m.roles[role]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown role %q", ErrInvalidRequest, role)
+		}
+		for _, p := range permissions {
+			if !grantor.Can(p) {
+				return nil, fmt.Errorf("%w: granting role %q requires %s", ErrPermissionDenied, role, p)
+			}
+		}
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	user := &User{
+		Username:     username,
+		PasswordHash: hash,
+		Roles:        roles,
+		CreatedAt:    time.Now(),
+		CreatedBy:    grantor.Subject,
+	}
+	if err := m.save(usersBucket, username, user); err != nil {
+		return nil, err
+	}
+	m.users[username] = user
+
+	logger.L().Info("Operator account created",
+		zap.String("username", username),
+		zap.Strings("roles", roles),
+		zap.String("created_by", grantor.Subject),
+	)
+	return user, nil
+}
+
+// DeleteUser removes an operator account, its sessions and its API keys.
+// It returns how many API keys were revoked.
+func (m *Manager) DeleteUser(username string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[username]; !ok {
+		return 0, ErrUserNotFound
+	}
+	if err := m.delete(usersBucket, username); err != nil {
+		return 0, err
+	}
+	delete(m.users, username)
+
+	for hash, s := range m.sessions {
+		if s.username == username {
+			delete(m.sessions, hash)
+		}
+	}
+
+	revoked := 0
+	owner := KindUser + ":" + username
+	for id, key := range m.apiKeys {
+		if key.Owner != owner {
+			continue
+		}
+		if err := m.delete(apiKeysBucket, id); err != nil {
+			return revoked, err
+		}
+		delete(m.apiKeys, id)
+		revoked++
+	}
+
+	logger.L().Info("Operator account deleted",
+		zap.String("username", username),
+		zap.Int("revoked_api_keys", revoked),
+	)
+	return revoked, nil
+}
+
+// ListUsers returns the operator accounts, sorted by username
+func (m *Manager) ListUsers() []*User {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	users := make([]*User, 0, len(m.users))
+	for _, user := range m.users {
+		copied := *user
+		users = append(users, &copied)
+	}
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].Username < users[j].Username
+	})
+	return users
+}
+
+// CreateAPIKey issues an API key scoped to permissions, which the owner must
+// hold. The returned credential is only ever shown once.
+func (m *Manager) CreateAPIKey(owner *Identity, name string, permissions []string, ttl time.Duration) (*APIKey, string, error) {
+	if owner.Kind == KindAPIKey {
+		return nil, "", fmt.Errorf("%w: API keys cannot create API keys", ErrPermissionDenied)
+	}
+	// The roles of federated operators are only known while they are signed
+	// in, so the keys they created could not be capped once they lose them
+	if owner.Kind == KindOIDC {
+		return nil, "", fmt.Errorf("%w: federated operators cannot create API keys", ErrPermissionDenied)
+	}
+	if name == "" {
+		return nil, "", fmt.Errorf("%w: name is required", ErrInvalidRequest)
+	}
+	if len(permissions) == 0 {
+		return nil, "", fmt.Errorf("%w: at least one permission is required", ErrInvalidRequest)
+	}
+	for _, p := range permissions {
+		if !isPermission(p) {
+			return nil, "", fmt.Errorf("%w: unknown permission %q", ErrInvalidRequest, p)
+		}
+		if !owner.Can(p) {
+			return nil, "", fmt.Errorf("%w: %s is not held by %s", ErrPermissionDenied, p, owner.Subject)
+		}
+	}
+
+	maxTTL := m.config.MaxAPIKeyTTL
+	if ttl < 0 || (maxTTL > 0 && ttl > maxTTL) {
+		return nil, "", fmt.Errorf("%w: ttl must be between 0 and %s", ErrInvalidRequest, maxTTL)
+	}
+	if ttl == 0 {
+		ttl = maxTTL
+	}
+
+	idBytes := make([]byte, 8)
+	if _, err := rand.Read(idBytes); err != nil {
+		return nil, "", fmt.Errorf("failed to generate api key id: %w", err)
+	}
+	secret, err := randomSecret()
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := time.Now()
+	key := &APIKey{
+		ID:          hex.EncodeToString(idBytes),
+		Name:        name,
+		Owner:       owner.Subject,
+		SecretHash:  hashSecret(secret),
+		Permissions: sortedPermissions(permissionSet(permissions)),
+		CreatedAt:   now,
+	}
+	if ttl > 0 {
+		key.ExpiresAt = now.Add(ttl)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.save(apiKeysBucket, key.ID, key); err != nil {
+		return nil, "", err
+	}
+	m.apiKeys[key.ID] = key
+
+	logger.L().Info("API key created",
+		zap.String("id", key.ID),
+		zap.String("name", name),
+		zap.String("owner", owner.Subject),
+		zap.Strings("permissions", key.Permissions),
+	)
+	copied := *key
+	return &copied, apiKeyPrefix + key.ID + "_" + secret, nil
+}
+
+// ListAPIKeys returns the API keys, oldest first
+func (m *Manager) ListAPIKeys() []*APIKey {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	keys := make([]*APIKey, 0, len(m.apiKeys))
+	for _, key := range m.apiKeys {
+		copied := *key
+		keys = append(keys, &copied)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+	})
+	return keys
+}
+
+// RevokeAPIKey deletes an API key
+func (m *Manager) RevokeAPIKey(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.apiKeys[id]; !ok {
+		return ErrAPIKeyNotFound
+	}
+	if err := m.delete(apiKeysBucket, id); err != nil {
+		return err
+	}
+	delete(m.apiKeys, id)
+
+	logger.L().Info("API key revoked", zap.String("id", id))
+	return nil
+}
+
+// Roles returns the built-in and configured roles, sorted by name
+func (m *Manager) Roles() []Role {
+	roles := make([]Role, 0, len(m.roles))
+	for name, permissions := range m.roles {
+		_, builtin := builtinRoles[name]
+		roles = append(roles, Role{
+			Name:        name,
+			Permissions: sortedPermissions(permissionSet(permissions)),
+			Builtin:     builtin,
+		})
+	}
+	sort.Slice(roles, func(i, j int) bool {
+		return roles[i].Name < roles[j].Name
+	})
+	return roles
+}
+
+// userIdentity returns the identity of a user. Callers hold m.mu.
+func (m *Manager) userIdentity(user *User) *Identity {
+	return m.roleIdentity(KindUser+":"+user.Username, KindUser, user.Roles)
+}
+
+// roleIdentity returns an identity holding the permissions of roles
+func (m *Manager) roleIdentity(subject, kind string, roles []string) *Identity {
+	permissions := make(map[string]bool)
+	for _, role := range roles {
+		for _, p := range m.roles[role] {
+			permissions[p] = true
+		}
+	}
+
+	return &Identity{
+		Subject:     subject,
+		Kind:        kind,
+		Roles:       roles,
+		Permissions: permissions,
+	}
+}
+
+// apiKeyIdentity returns the identity of an API key, capped by the current
+// permissions of its owner. Keys of federated operators are refused, their
+// roles being unknown outside their session. Callers hold m.mu.
+func (m *Manager) apiKeyIdentity(key *APIKey) (*Identity, error) {
+	permissions := permissionSet(key.Permissions)
+
+	if strings.HasPrefix(key.Owner, KindOIDC+":") {
+		return nil, ErrInvalidCredentials
+	}
+	if username, ok := strings.CutPrefix(key.Owner, KindUser+":"); ok {
+		user, ok := m.users[username]
+		if !ok {
+			return nil, ErrInvalidCredentials
+		}
+		owner := m.userIdentity(user)
+		for p := range permissions {
+			if !owner.Can(p) {
+				delete(permissions, p)
+			}
+		}
+	}
+
+	return &Identity{
+		Subject:     KindAPIKey + ":" + key.ID,
+		Kind:        KindAPIKey,
+		Permissions: permissions,
+	}, nil
+}
+
+// pruneSessions forgets expired login sessions. Callers hold m.mu.
+func (m *Manager) pruneSessions() {
+	now := time.Now()
+	for hash, s := range m.sessions {
+		if now.After(s.expiresAt) {
+			delete(m.sessions, hash)
+		}
+	}
+}
+
+func (m *Manager) save(bucket, key string, value interface{}) error {
+	if m.store == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", bucket, err)
+	}
+	if err := m.store.Put(bucket, key, data); err != nil {
+		return fmt.Errorf("failed to persist %s: %w", bucket, err)
+	}
+	return nil
+}
+
+func (m *Manager) delete(bucket, key string) error {
+	if m.store == nil {
+		return nil
+	}
+	if err := m.store.Delete(bucket, key); err != nil && !errors.Is(err, interfaces.ErrNotFound) {
+		return fmt.Errorf("failed to delete from %s: %w", bucket, err)
+	}
+	return nil
+}
+
+func randomSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate secret: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,75 @@
+package access
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/init/config"
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/internal/store"
+)
+
+// storeAPIKey persists a key as a previous run would have, returning its credential
+func storeAPIKey(t *testing.T, dataStore *store.Memory, owner string, permissions []string) string {
+	t.Helper()
+
+	key := APIKey{
+		ID:          "0123456789abcdef",
+		Name:        "ci",
+		Owner:       owner,
+		SecretHash:  hashSecret("secret"),
+		Permissions: permissions,
+		CreatedAt:   time.Now(),
+	}
+	data, err := json.Marshal(key)
+	if err != nil {
+		t.Fatalf("failed to encode api key: %v", err)
+	}
+	if err := dataStore.Put(apiKeysBucket, key.ID, data); err != nil {
+		t.Fatalf("failed to store api key: %v", err)
+	}
+	return apiKeyPrefix + key.ID + "_secret"
+}
+
+func TestAPIKeyOfFederatedOwnerIsRefused(t *testing.T) {
+	config.Set(config.DefaultConfig())
+
+	dataStore := store.NewMemory()
+	credential := storeAPIKey(t, dataStore, KindOIDC+":alice@example.com", AllPermissions)
+
+	m, err := NewManager(dataStore)
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	if _, err := m.Authenticate(credential); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("Authenticate() error = %v, want %v", err, ErrInvalidCredentials)
+	}
+}
+
+func TestAPIKeyIsCappedByOwnerRoles(t *testing.T) {
+	config.Set(config.DefaultConfig())
+
+	dataStore := store.NewMemory()
+	credential := storeAPIKey(t, dataStore, KindUser+":alice", []string{PermNodesRead, PermNodesDecommission})
+
+	user, err := json.Marshal(User{Username: "alice", Roles: []string{RoleViewer}})
+	if err != nil {
+		t.Fatalf("failed to encode user: %v", err)
+	}
+	if err := dataStore.Put(usersBucket, "alice", user); err != nil {
+		t.Fatalf("failed to store user: %v", err)
+	}
+
+	m, err := NewManager(dataStore)
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	identity, err := m.Authenticate(credential)
+	if err != nil {
+		t.Fatalf("Authenticate() error = %v", err)
+	}
+	if !identity.Can(PermNodesRead) || identity.Can(PermNodesDecommission) {
+		t.Fatalf("permissions = %v, want only those of a viewer", identity.PermissionList())
+	}
+}
@@ -0,0 +1,112 @@
+package access
+
+import "sort"
+
+// Permissions of the admin API
+const (
+	PermNodesRead         = "nodes:read"
+	PermNodesApprove      = "nodes:approve"
+	PermNodesDecommission = "nodes:decommission"
+	PermSessionsRevoke    = "sessions:revoke"
+	PermCommandsSend      = "commands:send"
+	PermTokensRead        = "tokens:read"
+	PermTokensCreate      = "tokens:create"
+	PermTokensRevoke      = "tokens:revoke"
+	PermAuditRead         = "audit:read"
+	PermUsersManage       = "users:manage"
+	PermFleetDrain        = "fleet:drain"
+)
+
+// Built-in roles
+const (
+	RoleViewer   = "viewer"
+	RoleOperator = "operator"
+	RoleAdmin    = "admin"
+)
+
+// AllPermissions lists every permission, held by the admin role
+var AllPermissions = []string{
+	PermNodesRead,
+	PermNodesApprove,
+	PermNodesDecommission,
+	PermSessionsRevoke,
+	PermCommandsSend,
+	PermTokensRead,
+	PermTokensCreate,
+	PermTokensRevoke,
+	PermAuditRead,
+	PermUsersManage,
+	PermFleetDrain,
+}
+
+var builtinRoles = map[string][]string{
+	RoleViewer: {
+		PermNodesRead,
+		PermAuditRead,
+	},
+	RoleOperator: {
+		PermNodesRead,
+		PermAuditRead,
+		PermNodesApprove,
+		PermSessionsRevoke,
+		PermCommandsSend,
+		PermTokensRead,
+		PermTokensRevoke,
+	},
+	RoleAdmin: AllPermissions,
+}
+
+// Identity kinds
+const (
+	KindAdminToken = "admin"
+	KindUser       = "user"
+	KindAPIKey     = "apikey"
+	KindOIDC       = "oidc"
+)
+
+// Identity is an authenticated operator and the permissions it holds
+type Identity struct {
+	// Subject names the operator in audit records, e.g. "user:alice"
+	Subject     string
+	Kind        string
+	Roles       []string
+	Permissions map[string]bool
+}
+
+// Can reports whether the identity holds permission
+func (i *Identity) Can(permission string) bool {
+	return i != nil && i.Permissions[permission]
+}
+
+// PermissionList returns the permissions of the identity, sorted
+func (i *Identity) PermissionList() []string {
+	return sortedPermissions(i.Permissions)
+}
+
+func isPermission(name string) bool {
+	for _, p := range AllPermissions {
+		if p == name {
+			return true
+		}
+	}
+	return false
+}
+
+func permissionSet(permissions []string) map[string]bool {
+	set := make(map[string]bool, len(permissions))
+	for _, p := range permissions {
+		set[p] = true
+	}
+	return set
+}
+
+func sortedPermissions(set map[string]bool) []string {
+	list := make([]string, 0, len(set))
+	for p, ok := range set {
+		if ok {
+			list = append(list, p)
+		}
+	}
+	sort.Strings(list)
+	return list
+}
@@ -0,0 +1,202 @@
+package admission
+
+import (
+	"context"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/init/config"
+)
+
+// Reasons a handshake or stream is refused
+const (
+	ReasonQueueFull    = "queue_full"
+	ReasonQueueTimeout = "queue_timeout"
+	ReasonStreamRate   = "stream_rate"
+)
+
+// Handshake duration assumed until one has been measured
+const defaultHandshakeTime = 100 * time.Millisecond
+
+// Weight of the latest handshake in the moving average of their duration
+const handshakeSmoothing = 0.2
+
+// Decision is the outcome of asking for admission
+type Decision struct {
+	Allowed    bool
+	Reason     string
+	RetryAfter time.Duration
+}
+
+// Controller admits node handshakes and new streams at the pace the control
+// plane sustains. Handshakes hold one of a bounded number of slots and wait a
+// short while in a bounded queue for one; new streams draw from a token
+// bucket. Refused callers are each handed the next free retry slot at the
+// admitted rate, so their retries are spread instead of arriving at once.
+type Controller struct {
+	config *config.AdmissionConfig
+	slots  chan struct{}
+
+	queued        int
+	handshakeTime time.Duration
+	nextHandshake time.Time
+
+	tokens     float64
+	updated    time.Time
+	nextStream time.Time
+
+	mu sync.Mutex
+}
+
+// NewController creates a controller from the admission configuration
+func NewController() *Controller {
+	cfg := config.Get().Core.Admission
+
+	c := &Controller{
+		config:        &cfg,
+		handshakeTime: defaultHandshakeTime,
+		updated:       time.Now(),
+	}
+	if cfg.Enabled {
+		c.slots = make(chan struct{}, cfg.MaxConcurrentHandshakes)
+		c.tokens = float64(cfg.StreamBurst)
+	}
+	return c
+}
+
+// AcquireHandshake takes a handshake slot, waiting up to the queue timeout for
+// one. When admitted, release must be called once the handshake is done.
+func (c *Controller) AcquireHandshake(ctx context.Context) (release func(), decision Decision) {
+	if !c.config.Enabled {
+		return func() {}, Decision{Allowed: true}
+	}
+
+	start := time.Now()
+	select {
+	case c.slots <- struct{}{}:
+		return c.releaser(start), Decision{Allowed: true}
+	default:
+	}
+
+	c.mu.Lock()
+	if c.queued >= c.config.MaxQueuedHandshakes {
+		retry := c.handshakeRetry(start)
+		c.mu.Unlock()
+		return nil, Decision{Reason: ReasonQueueFull, RetryAfter: retry}
+	}
+	c.queued++
+	c.mu.Unlock()
+
+	timer := time.NewTimer(c.config.QueueTimeout)
+	defer timer.Stop()
+
+	var admitted bool
+	select {
+	case c.slots <- struct{}{}:
+		admitted = true
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.queued--
+	if admitted {
+		return c.releaser(time.Now()), Decision{Allowed: true}
+	}
+	return nil, Decision{Reason: ReasonQueueTimeout, RetryAfter: c.handshakeRetry(time.Now())}
+}
+
+// releaser frees a slot and folds the handshake duration into the average
+func (c *Controller) releaser(start time.Time) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			elapsed := time.Since(start)
+			c.mu.Lock()
+			c.handshakeTime += time.Duration(handshakeSmoothing * float64(elapsed-c.handshakeTime))
+			c.mu.Unlock()
+			<-c.slots
+		})
+	}
+}
+
+// handshakeRetry reserves a retry slot once the waiting handshakes are through
+func (c *Controller) handshakeRetry(now time.Time) time.Duration {
+	interval := c.handshakeInterval()
+	backlog := now.Add(time.Duration(c.queued+len(c.slots)) * interval)
+	return c.reserve(&c.nextHandshake, backlog, interval, now)
+}
+
+// handshakeInterval is the time between two handshakes at full throughput
+func (c *Controller) handshakeInterval() time.Duration {
+	return c.handshakeTime / time.Duration(c.config.MaxConcurrentHandshakes)
+}
+
+// AllowStream draws a new stream from the stream bucket
+func (c *Controller) AllowStream() Decision {
+	if !c.config.Enabled {
+		return Decision{Allowed: true}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	c.tokens += now.Sub(c.updated).Seconds() * c.config.StreamRate
+	if burst := float64(c.config.StreamBurst); c.tokens > burst {
+		c.tokens = burst
+	}
+	c.updated = now
+
+	if c.tokens >= 1 {
+		c.tokens--
+		return Decision{Allowed: true}
+	}
+
+	interval := time.Duration(float64(time.Second) / c.config.StreamRate)
+	refill := now.Add(time.Duration((1 - c.tokens) * float64(interval)))
+	return Decision{Reason: ReasonStreamRate, RetryAfter: c.reserve(&c.nextStream, refill, interval, now)}
+}
+
+// reserve hands out the next retry slot, one interval after the previous one
+// and not before earliest. Past the longest retry delay, callers are spread at
+// random over its second half.
+func (c *Controller) reserve(next *time.Time, earliest time.Time, interval time.Duration, now time.Time) time.Duration {
+	slot := *next
+	if slot.Before(earliest) {
+		slot = earliest
+	}
+
+	limit := c.config.MaxRetryDelay
+	if slot.Sub(now) > limit {
+		return limit/2 + rand.N(limit/2)
+	}
+
+	*next = slot.Add(interval)
+	return slot.Sub(now)
+}
+
+// Stats returns the handshakes in flight and waiting for a slot
+func (c *Controller) Stats() (inflight, queued int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.slots), c.queued
+}
+
+// ReconnectSpread returns how long the given number of nodes take to reconnect
+// at the admitted pace, each opening a session and a stream
+func (c *Controller) ReconnectSpread(nodes int) time.Duration {
+	if !c.config.Enabled || nodes <= 0 {
+		return 0
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	streams := time.Duration(float64(nodes) / c.config.StreamRate * float64(time.Second))
+	handshakes := time.Duration(2*nodes) * c.handshakeInterval()
+	return max(streams, handshakes)
+}
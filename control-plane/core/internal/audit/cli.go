@@ -0,0 +1,69 @@
+package audit
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Run executes an `audit` subcommand and returns the process exit code
+func Run(args []string) int {
+	if len(args) == 0 {
+		usage(os.Stderr)
+		return 2
+	}
+
+	var err error
+	switch args[0] {
+	case "verify":
+		err = runVerify(args[1:], os.Stdout)
+	case "help", "-h", "--help":
+		usage(os.Stdout)
+		return 0
+	default:
+		fmt.Fprintf(os.Stderr, "❌ Unknown audit command %q\n\n", args[0])
+		usage(os.Stderr)
+		return 2
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintf(w, "Usage of %s audit:\n", os.Args[0])
+	fmt.Fprintf(w, "  verify         Check the hash chain of audit log files\n")
+	fmt.Fprintf(w, "\nExamples:\n")
+	fmt.Fprintf(w, "  %s audit verify /var/log/luminous-mesh/audit.log\n", os.Args[0])
+}
+
+func runVerify(args []string, w io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("at least one audit log file is required")
+	}
+
+	for _, path := range args {
+		file, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+
+		count, err := Verify(file)
+		file.Close()
+
+		var verifyErr *VerifyError
+		if errors.As(err, &verifyErr) {
+			return fmt.Errorf("%s: chain broken after %d valid records: %w", path, count, err)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+
+		fmt.Fprintf(w, "✅ %s: %d records, chain intact\n", path, count)
+	}
+	return nil
+}
@@ -0,0 +1,382 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/init/config"
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/init/logger"
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/shared/interfaces"
+	"go.uber.org/zap"
+)
+
+// auditBucket receives the records forwarded to the data store, keyed by
+// zero-padded sequence number so keys sort in log order
+const auditBucket = "audit"
+
+// recordKey is the data store key of record seq
+func recordKey(seq uint64) string {
+	return fmt.Sprintf("%020d", seq)
+}
+
+// Outcomes of an audited action
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+	OutcomePending = "pending"
+)
+
+// Actors that are not an authenticated node
+const (
+	ActorAdmin        = "admin"
+	ActorControlPlane = "control-plane"
+	ActorAnonymous    = "anonymous"
+)
+
+// Record is one audited action. Hash covers every other field, PrevHash
+// chains it to the previous record.
+type Record struct {
+	Seq         uint64            `json:"seq"`
+	Time        time.Time         `json:"time"`
+	Actor       string            `json:"actor"`
+	Action      string            `json:"action"`
+	Target      string            `json:"target,omitempty"`
+	Outcome     string            `json:"outcome"`
+	PeerAddress string            `json:"peer_address,omitempty"`
+	Details     map[string]string `json:"details,omitempty"`
+	PrevHash    string            `json:"prev_hash"`
+	Hash        string            `json:"hash,omitempty"`
+}
+
+// computeHash returns the hash of the record without its Hash field
+func (r Record) computeHash() (string, error) {
+	r.Hash = ""
+	data, err := json.Marshal(r)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Filter selects records; zero fields match everything
+type Filter struct {
+	Actor  string
+	Action string
+	Target string
+	Since  time.Time
+	Until  time.Time
+	// Limit keeps the most recent matching records
+	Limit int
+}
+
+func (f *Filter) match(r *Record) bool {
+	switch {
+	case f.Actor != "" && r.Actor != f.Actor:
+		return false
+	case f.Action != "" && r.Action != f.Action:
+		return false
+	case f.Target != "" && r.Target != f.Target:
+		return false
+	case !f.Since.IsZero() && r.Time.Before(f.Since):
+		return false
+	case !f.Until.IsZero() && r.Time.After(f.Until):
+		return false
+	}
+	return true
+}
+
+// Logger appends records to the hash-chained audit file and forwards them to the data store
+type Logger struct {
+	config   *config.AuditConfig
+	store    interfaces.DataStore
+	file     *os.File
+	seq      uint64
+	lastHash string
+	mu       sync.Mutex
+}
+
+// NewLogger opens the audit file and resumes its chain
+func NewLogger(store interfaces.DataStore) (*Logger, error) {
+	cfg := config.Get().Core.Audit
+
+	l := &Logger{
+		config: &cfg,
+		store:  store,
+	}
+
+	if cfg.Path == "" {
+		if store != nil && cfg.Forward {
+			last, err := l.lastStoredRecord()
+			if err != nil {
+				return nil, fmt.Errorf("failed to resume audit chain: %w", err)
+			}
+			if last != nil {
+				l.seq = last.Seq
+				l.lastHash = last.Hash
+			}
+		}
+		return l, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(cfg.Path), 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create audit directory: %w", err)
+	}
+
+	file, err := os.OpenFile(cfg.Path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o640)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+
+	last, err := lastRecord(file)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to resume audit chain: %w", err)
+	}
+	if last != nil {
+		l.seq = last.Seq
+		l.lastHash = last.Hash
+	}
+	l.file = file
+
+	return l, nil
+}
+
+// Log appends a record. The audit trail must not take the control plane
+// down, so failures are reported in the application log only.
+func (l *Logger) Log(record Record) {
+	if l == nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	record.Seq = l.seq + 1
+	record.Time = time.Now().UTC()
+	record.PrevHash = l.lastHash
+
+	hash, err := record.computeHash()
+	if err != nil {
+		logger.L().Error("Failed to hash audit record", zap.String("action", record.Action), zap.Error(err))
+		return
+	}
+	record.Hash = hash
+
+	line, err := json.Marshal(record)
+	if err != nil {
+		logger.L().Error("Failed to encode audit record", zap.String("action", record.Action), zap.Error(err))
+		return
+	}
+
+	if l.file != nil {
+		if _, err := l.file.Write(append(line, '\n')); err != nil {
+			logger.L().Error("Failed to write audit record", zap.String("action", record.Action), zap.Error(err))
+			return
+		}
+	}
+	l.seq = record.Seq
+	l.lastHash = record.Hash
+
+	if l.store != nil && l.config.Forward {
+		if err := l.store.Put(auditBucket, recordKey(record.Seq), line); err != nil {
+			logger.L().Warn("Failed to forward audit record",
+				zap.Uint64("seq", record.Seq),
+				zap.Error(err),
+			)
+		}
+	}
+}
+
+// Query returns the records matching filter, oldest first. Records are read
+// from the data store when they are forwarded there, from the audit file otherwise.
+func (l *Logger) Query(filter Filter) ([]*Record, error) {
+	var records []*Record
+	var err error
+	if l.store != nil && l.config.Forward {
+		records, err = l.queryStore(filter)
+	} else {
+		records, err = l.queryFile(filter)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if filter.Limit > 0 && len(records) > filter.Limit {
+		records = records[len(records)-filter.Limit:]
+	}
+	return records, nil
+}
+
+// queryStore walks the forwarded records from the most recent one down,
+// fetching them by key, and stops once Limit records matched or the records
+// get older than Since
+func (l *Logger) queryStore(filter Filter) ([]*Record, error) {
+	l.mu.Lock()
+	last := l.seq
+	l.mu.Unlock()
+
+	var records []*Record
+	for seq := last; seq > 0; seq-- {
+		if filter.Limit > 0 && len(records) == filter.Limit {
+			break
+		}
+
+		record, err := l.storedRecord(seq)
+		if err != nil {
+			return nil, err
+		}
+		if record == nil {
+			// Forwarding that record failed, Log reported it
+			continue
+		}
+		if !filter.Since.IsZero() && record.Time.Before(filter.Since) {
+			break
+		}
+		if filter.match(record) {
+			records = append(records, record)
+		}
+	}
+
+	slices.Reverse(records)
+	return records, nil
+}
+
+// storedRecord returns the forwarded record seq, nil when the store lacks it
+func (l *Logger) storedRecord(seq uint64) (*Record, error) {
+	data, err := l.store.Get(auditBucket, recordKey(seq))
+	if errors.Is(err, interfaces.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit record %d: %w", seq, err)
+	}
+
+	var record Record
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, fmt.Errorf("failed to decode audit record %d: %w", seq, err)
+	}
+	return &record, nil
+}
+
+// lastStoredRecord finds the most recent forwarded record by probing
+// sequence numbers, doubling then bisecting, so the chain of a logger
+// without audit file resumes where it stopped
+func (l *Logger) lastStoredRecord() (*Record, error) {
+	var last *Record
+	lo, hi := uint64(0), uint64(1)
+	for {
+		record, err := l.storedRecord(hi)
+		if err != nil {
+			return nil, err
+		}
+		if record == nil {
+			break
+		}
+		last, lo, hi = record, hi, hi*2
+	}
+	for hi-lo > 1 {
+		mid := lo + (hi-lo)/2
+		record, err := l.storedRecord(mid)
+		if err != nil {
+			return nil, err
+		}
+		if record == nil {
+			hi = mid
+		} else {
+			last, lo = record, mid
+		}
+	}
+	return last, nil
+}
+
+func (l *Logger) queryFile(filter Filter) ([]*Record, error) {
+	if l.config.Path == "" {
+		return nil, nil
+	}
+
+	file, err := os.Open(l.config.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer file.Close()
+
+	var records []*Record
+	scanner := newScanner(file)
+	for scanner.Scan() {
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			continue
+		}
+		if filter.match(&record) {
+			records = append(records, &record)
+		}
+	}
+	return records, scanner.Err()
+}
+
+// Close closes the audit file
+func (l *Logger) Close() error {
+	if l == nil || l.file == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.file.Close()
+}
+
+// lastRecord returns the last record of the audit file, if any. A torn last
+// line is left for verification to report; the chain resumes from the last
+// intact record.
+func lastRecord(file *os.File) (*Record, error) {
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	var last *Record
+	var torn bool
+	scanner := newScanner(file)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var record Record
+		if err := json.Unmarshal(line, &record); err != nil {
+			torn = true
+			continue
+		}
+		last = &record
+		torn = false
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if torn {
+		logger.L().Warn("Audit log ends with an unreadable record, it will fail verification")
+		if _, err := file.Write([]byte("\n")); err != nil {
+			return nil, err
+		}
+	}
+	return last, nil
+}
+
+func newScanner(r io.Reader) *bufio.Scanner {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	return scanner
+}
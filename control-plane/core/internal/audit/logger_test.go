@@ -0,0 +1,134 @@
+package audit
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/init/config"
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/init/logger"
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/internal/store"
+)
+
+// newTestLogger opens a logger on path, forwarding to dataStore when set
+func newTestLogger(t *testing.T, path string, dataStore *store.Memory) *Logger {
+	t.Helper()
+	if logger.L() == nil {
+		logger.NewDevelopmentLogger()
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.Core.Audit.Path = path
+	cfg.Core.Audit.Forward = dataStore != nil
+	config.Set(cfg)
+
+	// A nil *store.Memory must not reach NewLogger as a non-nil interface
+	var l *Logger
+	var err error
+	if dataStore != nil {
+		l, err = NewLogger(dataStore)
+	} else {
+		l, err = NewLogger(nil)
+	}
+	if err != nil {
+		t.Fatalf("NewLogger() error = %v", err)
+	}
+	t.Cleanup(func() { l.Close() })
+	return l
+}
+
+// logRecords logs count records targeting node-0, node-1, ...
+func logRecords(l *Logger, from, count int) {
+	for i := from; i < from+count; i++ {
+		l.Log(Record{
+			Actor:   ActorAdmin,
+			Action:  "approve_registration",
+			Target:  fmt.Sprintf("node-%d", i),
+			Outcome: OutcomeSuccess,
+		})
+	}
+}
+
+func TestHashChain(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	l := newTestLogger(t, path, nil)
+	logRecords(l, 0, 3)
+
+	// The chain resumes across restarts
+	l.Close()
+	l = newTestLogger(t, path, nil)
+	logRecords(l, 3, 2)
+
+	records, err := l.Query(Filter{})
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if len(records) != 5 {
+		t.Fatalf("Query() returned %d records, want 5", len(records))
+	}
+	prevHash := ""
+	for i, record := range records {
+		if record.Seq != uint64(i+1) {
+			t.Fatalf("record %d has seq %d", i, record.Seq)
+		}
+		if record.PrevHash != prevHash {
+			t.Fatalf("record %d is not chained to its predecessor", record.Seq)
+		}
+		hash, err := record.computeHash()
+		if err != nil || hash != record.Hash {
+			t.Fatalf("record %d hash = %s, want %s (%v)", record.Seq, record.Hash, hash, err)
+		}
+		prevHash = record.Hash
+	}
+}
+
+func TestQueryStore(t *testing.T) {
+	dataStore := store.NewMemory()
+	l := newTestLogger(t, "", dataStore)
+	logRecords(l, 0, 10)
+
+	// Without an audit file the chain resumes from the data store
+	l = newTestLogger(t, "", dataStore)
+	logRecords(l, 10, 10)
+
+	all, err := l.Query(Filter{})
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if len(all) != 20 || all[0].Seq != 1 || all[19].Seq != 20 || all[10].PrevHash != all[9].Hash {
+		t.Fatalf("Query() returned %d records, want the 20 chained records in order", len(all))
+	}
+
+	// A record whose forwarding failed is skipped
+	if err := dataStore.Delete(auditBucket, recordKey(18)); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []uint64
+	}{
+		{"limit keeps the most recent", Filter{Limit: 3}, []uint64{17, 19, 20}},
+		{"target", Filter{Target: "node-4"}, []uint64{5}},
+		{"target with limit", Filter{Target: "node-4", Limit: 1}, []uint64{5}},
+		{"since in the future", Filter{Since: time.Now().Add(time.Hour)}, []uint64{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, err := l.Query(tt.filter)
+			if err != nil {
+				t.Fatalf("Query() error = %v", err)
+			}
+			got := []uint64{}
+			for _, record := range records {
+				got = append(got, record.Seq)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Fatalf("Query() returned records %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
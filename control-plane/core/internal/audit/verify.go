@@ -0,0 +1,84 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// VerifyError locates the first record breaking the chain
+type VerifyError struct {
+	Line   int
+	Seq    uint64
+	Reason string
+}
+
+func (e *VerifyError) Error() string {
+	if e.Seq == 0 {
+		return fmt.Sprintf("line %d: %s", e.Line, e.Reason)
+	}
+	return fmt.Sprintf("line %d (seq %d): %s", e.Line, e.Seq, e.Reason)
+}
+
+// Verify checks the hash chain of an audit log and returns the number of
+// records it holds. Records are checked to be in sequence, linked to their
+// predecessor, unmodified and in canonical encoding, so added fields are caught too.
+func Verify(r io.Reader) (int, error) {
+	var count int
+	var prev *Record
+
+	scanner := newScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		raw := bytes.TrimSpace(scanner.Bytes())
+		if len(raw) == 0 {
+			continue
+		}
+
+		var record Record
+		if err := json.Unmarshal(raw, &record); err != nil {
+			return count, &VerifyError{Line: line, Reason: "unreadable record"}
+		}
+
+		fail := func(reason string) error {
+			return &VerifyError{Line: line, Seq: record.Seq, Reason: reason}
+		}
+
+		if prev == nil {
+			if record.Seq != 1 || record.PrevHash != "" {
+				return count, fail("log does not start at the first record")
+			}
+		} else {
+			if record.Seq != prev.Seq+1 {
+				return count, fail(fmt.Sprintf("expected seq %d, records are missing or reordered", prev.Seq+1))
+			}
+			if record.PrevHash != prev.Hash {
+				return count, fail("not chained to the previous record")
+			}
+		}
+
+		hash, err := record.computeHash()
+		if err != nil {
+			return count, fail(err.Error())
+		}
+		if hash != record.Hash {
+			return count, fail("hash mismatch, record was modified")
+		}
+
+		canonical, err := json.Marshal(record)
+		if err != nil {
+			return count, fail(err.Error())
+		}
+		if !bytes.Equal(canonical, raw) {
+			return count, fail("record is not in canonical encoding, it was modified")
+		}
+
+		prev = &record
+		count++
+	}
+
+	if err := scanner.Err(); err != nil {
+		return count, fmt.Errorf("failed to read audit log: %w", err)
+	}
+	return count, nil
+}
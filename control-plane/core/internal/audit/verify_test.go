@@ -0,0 +1,133 @@
+package audit
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeLog logs count records to a fresh audit file and returns its lines
+func writeLog(t *testing.T, count int) []string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "audit.log")
+	l := newTestLogger(t, path, nil)
+	logRecords(l, 0, count)
+	l.Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read audit log: %v", err)
+	}
+	return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+}
+
+func TestVerify(t *testing.T) {
+	lines := writeLog(t, 4)
+	join := func(lines ...string) string { return strings.Join(lines, "\n") + "\n" }
+
+	tests := []struct {
+		name      string
+		log       string
+		wantCount int
+		// wantLine and wantReason locate the expected failure, none when zero
+		wantLine   int
+		wantReason string
+	}{
+		{name: "intact", log: join(lines...), wantCount: 4},
+		{name: "empty", log: ""},
+		{
+			name:       "tampered field",
+			log:        join(lines[0], strings.Replace(lines[1], OutcomeSuccess, OutcomeFailure, 1), lines[2], lines[3]),
+			wantCount:  1,
+			wantLine:   2,
+			wantReason: "hash mismatch",
+		},
+		{
+			name:       "added field",
+			log:        join(lines[0], lines[1], strings.Replace(lines[2], `{"seq"`, `{"extra":"x","seq"`, 1), lines[3]),
+			wantCount:  2,
+			wantLine:   3,
+			wantReason: "canonical encoding",
+		},
+		{
+			name:       "removed record",
+			log:        join(lines[0], lines[2], lines[3]),
+			wantCount:  1,
+			wantLine:   2,
+			wantReason: "records are missing",
+		},
+		{
+			name:       "missing head",
+			log:        join(lines[1:]...),
+			wantLine:   1,
+			wantReason: "does not start",
+		},
+		{
+			name:       "truncated tail",
+			log:        join(lines[:3]...) + lines[3][:len(lines[3])/2],
+			wantCount:  3,
+			wantLine:   4,
+			wantReason: "unreadable record",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			count, err := Verify(strings.NewReader(tt.log))
+			if count != tt.wantCount {
+				t.Fatalf("Verify() count = %d, want %d", count, tt.wantCount)
+			}
+			if tt.wantReason == "" {
+				if err != nil {
+					t.Fatalf("Verify() error = %v", err)
+				}
+				return
+			}
+			var verifyErr *VerifyError
+			if !errors.As(err, &verifyErr) || verifyErr.Line != tt.wantLine || !strings.Contains(verifyErr.Reason, tt.wantReason) {
+				t.Fatalf("Verify() error = %v, want line %d: %s", err, tt.wantLine, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestTruncatedTailResumed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	l := newTestLogger(t, path, nil)
+	logRecords(l, 0, 3)
+	l.Close()
+
+	// A crash tore the last record
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read audit log: %v", err)
+	}
+	if err := os.WriteFile(path, data[:len(data)-10], 0o640); err != nil {
+		t.Fatalf("failed to truncate audit log: %v", err)
+	}
+
+	// The chain resumes from the last intact record, the torn one still fails verification
+	l = newTestLogger(t, path, nil)
+	logRecords(l, 3, 1)
+	records, err := l.Query(Filter{})
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if n := len(records); n != 3 || records[2].Seq != 3 || records[2].PrevHash != records[1].Hash {
+		t.Fatalf("Query() = %d records, want the new record chained to record 2", n)
+	}
+
+	data, err = os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read audit log: %v", err)
+	}
+	count, err := Verify(bytes.NewReader(data))
+	var verifyErr *VerifyError
+	if count != 2 || !errors.As(err, &verifyErr) || verifyErr.Line != 3 {
+		t.Fatalf("Verify() = %d, %v, want the torn record on line 3 reported", count, err)
+	}
+}
@@ -0,0 +1,74 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"time"
+
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/init/config"
+)
+
+// bootstrapToken is a registration secret, kept only as a hash
+type bootstrapToken struct {
+	hash        [sha256.Size]byte
+	description string
+	expiresAt   time.Time
+	maxUses     int
+	uses        int
+	approval    bool
+}
+
+// BootstrapGrant describes the bootstrap token a registration was made with
+type BootstrapGrant struct {
+	Description     string
+	RequireApproval bool
+}
+
+func newBootstrapTokens(cfgs []config.BootstrapTokenConfig) []*bootstrapToken {
+	tokens := make([]*bootstrapToken, 0, len(cfgs))
+	for _, cfg := range cfgs {
+		tokens = append(tokens, &bootstrapToken{
+			hash:        sha256.Sum256([]byte(cfg.Token)),
+			description: cfg.Description,
+			expiresAt:   cfg.ExpiresAt,
+			maxUses:     cfg.MaxUses,
+			approval:    cfg.RequireApproval,
+		})
+	}
+	return tokens
+}
+
+// ValidateBootstrapToken checks a registration token and consumes one use of it
+func (m *Manager) ValidateBootstrapToken(token string) (*BootstrapGrant, error) {
+	if token == "" {
+		return nil, fmt.Errorf("empty bootstrap token")
+	}
+
+	hash := sha256.Sum256([]byte(token))
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, bt := range m.bootstrapTokens {
+		if subtle.ConstantTimeCompare(hash[:], bt.hash[:]) != 1 {
+			continue
+		}
+
+		if !bt.expiresAt.IsZero() && time.Now().After(bt.expiresAt) {
+			return nil, fmt.Errorf("bootstrap token %q expired", bt.description)
+		}
+
+		if bt.maxUses > 0 && bt.uses >= bt.maxUses {
+			return nil, fmt.Errorf("bootstrap token %q has no uses left", bt.description)
+		}
+
+		bt.uses++
+		return &BootstrapGrant{
+			Description:     bt.description,
+			RequireApproval: bt.approval,
+		}, nil
+	}
+
+	return nil, fmt.Errorf("unknown bootstrap token")
+}
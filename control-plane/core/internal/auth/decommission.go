@@ -0,0 +1,246 @@
+package auth
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/init/logger"
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/pkg/certs"
+	"go.uber.org/zap"
+)
+
+// Buckets holding issued certificates and decommissioned nodes
+const (
+	certificatesBucket = "auth.certificates"
+	tombstonesBucket   = "auth.tombstones"
+)
+
+var (
+	ErrCertificateRevoked = errors.New("certificate revoked")
+	ErrNodeDecommissioned = errors.New("node decommissioned")
+)
+
+// certificateRecord tracks a node certificate so it can be revoked before it expires
+type certificateRecord struct {
+	Thumbprint string    `json:"thumbprint"`
+	NodeID     string    `json:"node_id"`
+	Serial     string    `json:"serial"`
+	NotAfter   time.Time `json:"not_after"`
+	Revoked    bool      `json:"revoked"`
+	RevokedAt  time.Time `json:"revoked_at,omitempty"`
+}
+
+// Tombstone records a decommissioned node. Tombstones are kept forever so the
+// node ID can never authenticate again.
+type Tombstone struct {
+	NodeID                string    `json:"node_id"`
+	Reason                string    `json:"reason"`
+	DecommissionedAt      time.Time `json:"decommissioned_at"`
+	RevokedFamilies       int       `json:"revoked_families"`
+	RevokedCertificates   int       `json:"revoked_certificates"`
+	LastCertificateSerial string    `json:"last_certificate_serial,omitempty"`
+}
+
+// loadDecommissionState restores issued certificates and tombstones
+func (m *Manager) loadDecommissionState() error {
+	m.certificates = make(map[string]*certificateRecord)
+	m.tombstones = make(map[string]*Tombstone)
+
+	if m.store == nil {
+		return nil
+	}
+
+	stored, err := m.store.List(certificatesBucket)
+	if err != nil {
+		return fmt.Errorf("failed to load certificates: %w", err)
+	}
+	for _, data := range stored {
+		var record certificateRecord
+		if err := json.Unmarshal(data, &record); err != nil {
+			return fmt.Errorf("failed to decode certificate record: %w", err)
+		}
+		m.certificates[record.Thumbprint] = &record
+	}
+
+	tombstones, err := m.store.List(tombstonesBucket)
+	if err != nil {
+		return fmt.Errorf("failed to load tombstones: %w", err)
+	}
+	for _, data := range tombstones {
+		var tombstone Tombstone
+		if err := json.Unmarshal(data, &tombstone); err != nil {
+			return fmt.Errorf("failed to decode tombstone: %w", err)
+		}
+		m.tombstones[tombstone.NodeID] = &tombstone
+	}
+
+	return nil
+}
+
+// recordCertificate tracks a certificate issued to a node. Callers hold m.mu.
+func (m *Manager) recordCertificate(nodeID string, cert *x509.Certificate) error {
+	record := &certificateRecord{
+		Thumbprint: certs.Thumbprint(cert),
+		NodeID:     nodeID,
+		Serial:     cert.SerialNumber.Text(16),
+		NotAfter:   cert.NotAfter,
+	}
+
+	if err := m.saveToStore(certificatesBucket, record.Thumbprint, record); err != nil {
+		return err
+	}
+	m.certificates[record.Thumbprint] = record
+
+	now := time.Now()
+	for thumbprint, record := range m.certificates {
+		if now.After(record.NotAfter) {
+			delete(m.certificates, thumbprint)
+			m.deleteFromStore(certificatesBucket, thumbprint)
+		}
+	}
+	return nil
+}
+
+// CheckCertificate rejects revoked certificates. Certificates issued before
+// tracking started are unknown and accepted.
+func (m *Manager) CheckCertificate(cert *x509.Certificate) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if record, ok := m.certificates[certs.Thumbprint(cert)]; ok && record.Revoked {
+		return ErrCertificateRevoked
+	}
+	return nil
+}
+
+// Tombstone returns the tombstone of a decommissioned node
+func (m *Manager) Tombstone(nodeID string) (*Tombstone, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	tombstone, ok := m.tombstones[nodeID]
+	return tombstone, ok
+}
+
+// KnowsNode reports whether credentials were ever issued to a node
+func (m *Manager) KnowsNode(nodeID string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if _, ok := m.tombstones[nodeID]; ok {
+		return true
+	}
+	for _, record := range m.certificates {
+		if record.NodeID == nodeID {
+			return true
+		}
+	}
+	for _, family := range m.families {
+		if family.NodeID == nodeID {
+			return true
+		}
+	}
+	return false
+}
+
+// Decommission revokes every credential of a node and leaves a tombstone.
+// Decommissioning a node twice returns the existing tombstone.
+func (m *Manager) Decommission(nodeID, reason string) (*Tombstone, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if tombstone, ok := m.tombstones[nodeID]; ok {
+		return tombstone, nil
+	}
+
+	now := time.Now()
+	tombstone := &Tombstone{
+		NodeID:           nodeID,
+		Reason:           reason,
+		DecommissionedAt: now,
+	}
+
+	for _, family := range m.families {
+		if family.NodeID != nodeID || family.Revoked {
+			continue
+		}
+		if err := m.revokeFamily(family, "node decommissioned: "+reason); err != nil {
+			return nil, fmt.Errorf("failed to revoke token family %s: %w", family.ID, err)
+		}
+		tombstone.RevokedFamilies++
+	}
+
+	revoked, latest, err := m.revokeCertificates(nodeID, "", now)
+	if err != nil {
+		return nil, err
+	}
+	tombstone.RevokedCertificates = revoked
+	if latest != nil {
+		tombstone.LastCertificateSerial = latest.Serial
+	}
+
+	if err := m.saveToStore(tombstonesBucket, nodeID, tombstone); err != nil {
+		return nil, err
+	}
+	m.tombstones[nodeID] = tombstone
+
+	logger.L().Info("Node decommissioned",
+		zap.String("node_id", nodeID),
+		zap.String("reason", reason),
+		zap.Int("revoked_families", tombstone.RevokedFamilies),
+		zap.Int("revoked_certificates", tombstone.RevokedCertificates),
+	)
+	return tombstone, nil
+}
+
+// RevokeNodeCertificates revokes the certificates issued to a node but keep,
+// when set, and returns how many were revoked
+func (m *Manager) RevokeNodeCertificates(nodeID, reason string, keep *x509.Certificate) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	except := ""
+	if keep != nil {
+		except = certs.Thumbprint(keep)
+	}
+	revoked, _, err := m.revokeCertificates(nodeID, except, time.Now())
+	if err != nil {
+		return revoked, err
+	}
+
+	logger.L().Info("Node certificates revoked",
+		zap.String("node_id", nodeID),
+		zap.Int("certificates", revoked),
+		zap.String("reason", reason),
+	)
+	return revoked, nil
+}
+
+// revokeCertificates revokes the certificates of a node but the one with the
+// except thumbprint, and returns how many were revoked along with the latest
+// one issued. Callers hold m.mu.
+func (m *Manager) revokeCertificates(nodeID, except string, now time.Time) (int, *certificateRecord, error) {
+	revoked := 0
+	var latest *certificateRecord
+	for _, record := range m.certificates {
+		if record.NodeID != nodeID {
+			continue
+		}
+		if latest == nil || record.NotAfter.After(latest.NotAfter) {
+			latest = record
+		}
+		if record.Revoked || record.Thumbprint == except {
+			continue
+		}
+		record.Revoked = true
+		record.RevokedAt = now
+		if err := m.saveToStore(certificatesBucket, record.Thumbprint, record); err != nil {
+			return revoked, latest, err
+		}
+		revoked++
+	}
+	return revoked, latest, nil
+}
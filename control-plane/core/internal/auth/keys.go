@@ -0,0 +1,417 @@
+package auth
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/init/logger"
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/pkg/certs"
+	"go.uber.org/zap"
+)
+
+const signingKeysBucket = "auth.signing_keys"
+
+// Token signing algorithms. HS256 is the legacy shared-secret mode.
+const (
+	AlgEdDSA = "EdDSA"
+	AlgES256 = "ES256"
+	AlgRS256 = "RS256"
+	AlgHS256 = "HS256"
+)
+
+// signingKey is a token signing key identified by its kid. A key is published
+// before it activates, signs until it retires and verifies until it expires.
+// It is stored in clear as PrivateKey, or as SealedKey once a KEK is configured.
+type signingKey struct {
+	ID         string    `json:"kid"`
+	Algorithm  string    `json:"alg"`
+	PrivateKey []byte    `json:"private_key,omitempty"`
+	SealedKey  []byte    `json:"sealed_key,omitempty"`
+	ActivateAt time.Time `json:"activate_at"`
+	RetireAt   time.Time `json:"retire_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+
+	signer crypto.Signer
+}
+
+func (k *signingKey) method() jwt.SigningMethod {
+	return jwt.GetSigningMethod(k.Algorithm)
+}
+
+// loadSigningKeys restores the persisted signing keys
+func (m *Manager) loadSigningKeys() error {
+	if m.config.SigningAlgorithm == AlgHS256 || m.store == nil {
+		return nil
+	}
+
+	if m.config.SigningKeyKEKFile != "" {
+		kek, err := loadKEK(m.config.SigningKeyKEKFile)
+		if err != nil {
+			return fmt.Errorf("failed to load signing key KEK: %w", err)
+		}
+		m.kek = kek
+	}
+
+	stored, err := m.store.List(signingKeysBucket)
+	if err != nil {
+		return fmt.Errorf("failed to load signing keys: %w", err)
+	}
+
+	for id, data := range stored {
+		var key signingKey
+		if err := json.Unmarshal(data, &key); err != nil {
+			return fmt.Errorf("failed to decode signing key %s: %w", id, err)
+		}
+
+		sealed := key.SealedKey != nil
+		if sealed {
+			if m.kek == nil {
+				return fmt.Errorf("signing key %s is encrypted and no signing_key_kek_file is configured", id)
+			}
+			key.PrivateKey, err = m.kek.Open(nil, key.SealedKey[:m.kek.NonceSize()], key.SealedKey[m.kek.NonceSize():], []byte(key.ID))
+			if err != nil {
+				return fmt.Errorf("failed to decrypt signing key %s: %w", id, err)
+			}
+		}
+
+		key.signer, err = certs.ParsePrivateKey(key.PrivateKey)
+		if err != nil {
+			return fmt.Errorf("failed to parse signing key %s: %w", id, err)
+		}
+
+		// Keys stored before the KEK was configured are encrypted now
+		if !sealed && m.kek != nil {
+			if err := m.saveSigningKey(&key); err != nil {
+				return err
+			}
+			logger.L().Info("Token signing key encrypted", zap.String("kid", key.ID))
+		}
+		m.keys = append(m.keys, &key)
+	}
+
+	sortSigningKeys(m.keys)
+	return nil
+}
+
+// currentSigningKey returns the key tokens are signed with, rotating on schedule
+func (m *Manager) currentSigningKey() (*signingKey, error) {
+	now := time.Now()
+
+	m.keysMu.RLock()
+	key := m.activeKey(now)
+	ready := key != nil && !m.needsNextKey(key, now)
+	m.keysMu.RUnlock()
+
+	if ready {
+		return key, nil
+	}
+
+	m.keysMu.Lock()
+	defer m.keysMu.Unlock()
+
+	if err := m.rotateSigningKeys(now); err != nil {
+		return nil, err
+	}
+	return m.activeKey(now), nil
+}
+
+// rotateSigningKeys drops expired keys, activates a key if none is active and
+// publishes the next key ahead of its activation. Callers hold m.keysMu.
+func (m *Manager) rotateSigningKeys(now time.Time) error {
+	kept := m.keys[:0]
+	for _, key := range m.keys {
+		if now.After(key.ExpiresAt) {
+			m.deleteFromStore(signingKeysBucket, key.ID)
+			continue
+		}
+		kept = append(kept, key)
+	}
+	m.keys = kept
+
+	active := m.activeKey(now)
+	if active == nil {
+		key, err := m.newSigningKey(now)
+		if err != nil {
+			return err
+		}
+		active = key
+	}
+
+	if m.needsNextKey(active, now) {
+		if _, err := m.newSigningKey(active.RetireAt); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// activeKey returns the newest key in its signing window. Callers hold m.keysMu.
+func (m *Manager) activeKey(now time.Time) *signingKey {
+	for i := len(m.keys) - 1; i >= 0; i-- {
+		key := m.keys[i]
+		if !now.Before(key.ActivateAt) && now.Before(key.RetireAt) {
+			return key
+		}
+	}
+	return nil
+}
+
+// needsNextKey reports whether the successor of active should be published.
+// Successors are published a tenth of the rotation period ahead so verifiers
+// caching the JWKS learn them before they sign anything.
+func (m *Manager) needsNextKey(active *signingKey, now time.Time) bool {
+	lead := m.config.SigningKeyRotation / 10
+	if now.Before(active.RetireAt.Add(-lead)) {
+		return false
+	}
+
+	for _, key := range m.keys {
+		if !key.ActivateAt.Before(active.RetireAt) {
+			return false
+		}
+	}
+	return true
+}
+
+// newSigningKey generates and persists a key activating at activateAt. Callers hold m.keysMu.
+func (m *Manager) newSigningKey(activateAt time.Time) (*signingKey, error) {
+	keyType := map[string]string{
+		AlgEdDSA: certs.KeyTypeEd25519,
+		AlgES256: certs.KeyTypeECDSA,
+		AlgRS256: certs.KeyTypeRSA,
+	}[m.config.SigningAlgorithm]
+
+	signer, err := certs.GenerateKey(keyType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate signing key: %w", err)
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(signer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal signing key: %w", err)
+	}
+
+	kid := make([]byte, 12)
+	if _, err := rand.Read(kid); err != nil {
+		return nil, fmt.Errorf("failed to generate key ID: %w", err)
+	}
+
+	retireAt := activateAt.Add(m.config.SigningKeyRotation)
+	key := &signingKey{
+		ID:         base64.RawURLEncoding.EncodeToString(kid),
+		Algorithm:  m.config.SigningAlgorithm,
+		PrivateKey: der,
+		ActivateAt: activateAt,
+		RetireAt:   retireAt,
+		ExpiresAt:  retireAt.Add(m.config.TokenDuration),
+		signer:     signer,
+	}
+
+	if err := m.saveSigningKey(key); err != nil {
+		return nil, err
+	}
+
+	m.keys = append(m.keys, key)
+	sortSigningKeys(m.keys)
+
+	logger.L().Info("Token signing key created",
+		zap.String("kid", key.ID),
+		zap.String("alg", key.Algorithm),
+		zap.Time("activate_at", key.ActivateAt),
+		zap.Time("retire_at", key.RetireAt),
+	)
+	return key, nil
+}
+
+// saveSigningKey persists a key, encrypted with the KEK when one is configured
+func (m *Manager) saveSigningKey(key *signingKey) error {
+	stored := *key
+	if m.kek != nil {
+		nonce := make([]byte, m.kek.NonceSize())
+		if _, err := rand.Read(nonce); err != nil {
+			return fmt.Errorf("failed to generate nonce: %w", err)
+		}
+		// The kid is authenticated so a sealed key cannot be swapped with another
+		stored.SealedKey = m.kek.Seal(nonce, nonce, key.PrivateKey, []byte(key.ID))
+		stored.PrivateKey = nil
+	}
+	return m.saveToStore(signingKeysBucket, key.ID, &stored)
+}
+
+// loadKEK reads the base64 encoded AES-256 key encrypting the signing keys
+func loadKEK(path string) (cipher.AEAD, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(data)))
+	if err != nil {
+		return nil, fmt.Errorf("%s: invalid base64: %w", path, err)
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("%s: key is %d bytes, want 32", path, len(key))
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// verificationKey resolves the key a token must be verified with
+func (m *Manager) verificationKey(token *jwt.Token) (interface{}, error) {
+	if _, ok := token.Method.(*jwt.SigningMethodHMAC); ok {
+		return m.legacyVerificationKey(token)
+	}
+
+	kid, _ := token.Header["kid"].(string)
+
+	m.keysMu.RLock()
+	defer m.keysMu.RUnlock()
+
+	for _, key := range m.keys {
+		if key.ID != kid {
+			continue
+		}
+		if key.Algorithm != token.Method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		if time.Now().After(key.ExpiresAt) {
+			return nil, fmt.Errorf("signing key %s expired", kid)
+		}
+		return key.signer.Public(), nil
+	}
+
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// legacyVerificationKey returns the shared secret of HS256 tokens. Under
+// another algorithm they are only accepted when the migration window is
+// explicitly opened, and every such verification is logged.
+func (m *Manager) legacyVerificationKey(token *jwt.Token) (interface{}, error) {
+	if m.config.SigningAlgorithm == AlgHS256 && token.Method.Alg() == AlgHS256 {
+		return []byte(m.config.TokenSecret), nil
+	}
+
+	if token.Method.Alg() != AlgHS256 || !m.config.AcceptLegacyHS256 || !time.Now().Before(m.config.LegacyHS256Until) {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+
+	claims, _ := token.Claims.(jwt.MapClaims)
+	nodeID, _ := claims["node_id"].(string)
+	logger.L().Warn("Verifying legacy HS256 token",
+		zap.String("node_id", nodeID),
+		zap.Time("cut_off", m.config.LegacyHS256Until),
+	)
+	return []byte(m.config.TokenSecret), nil
+}
+
+// signToken signs claims with the current key, or the shared secret in HS256 mode
+func (m *Manager) signToken(claims jwt.MapClaims) (string, error) {
+	if m.config.SigningAlgorithm == AlgHS256 {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(m.config.TokenSecret))
+	}
+
+	key, err := m.currentSigningKey()
+	if err != nil {
+		return "", err
+	}
+
+	token := jwt.NewWithClaims(key.method(), claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.signer)
+}
+
+type jwk struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Algorithm string `json:"alg"`
+	Use       string `json:"use"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+	Y         string `json:"y,omitempty"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+}
+
+// JWKS returns the JSON Web Key Set of every key currently valid for verification
+func (m *Manager) JWKS() ([]byte, error) {
+	if m.config.SigningAlgorithm != AlgHS256 {
+		// Publishes upcoming keys even when no token was issued recently
+		if _, err := m.currentSigningKey(); err != nil {
+			return nil, err
+		}
+	}
+
+	m.keysMu.RLock()
+	defer m.keysMu.RUnlock()
+
+	keys := make([]jwk, 0, len(m.keys))
+	for _, key := range m.keys {
+		if time.Now().After(key.ExpiresAt) {
+			continue
+		}
+		entry, err := publicJWK(key)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, entry)
+	}
+
+	return json.Marshal(map[string][]jwk{"keys": keys})
+}
+
+func publicJWK(key *signingKey) (jwk, error) {
+	entry := jwk{KeyID: key.ID, Algorithm: key.Algorithm, Use: "sig"}
+	b64 := base64.RawURLEncoding.EncodeToString
+
+	switch pub := key.signer.Public().(type) {
+	case ed25519.PublicKey:
+		entry.KeyType = "OKP"
+		entry.Curve = "Ed25519"
+		entry.X = b64(pub)
+	case *ecdsa.PublicKey:
+		point, err := pub.ECDH()
+		if err != nil {
+			return entry, fmt.Errorf("failed to encode signing key %s: %w", key.ID, err)
+		}
+		// Uncompressed point: 0x04 || X || Y
+		raw := point.Bytes()[1:]
+		entry.KeyType = "EC"
+		entry.Curve = pub.Curve.Params().Name
+		entry.X = b64(raw[:len(raw)/2])
+		entry.Y = b64(raw[len(raw)/2:])
+	case *rsa.PublicKey:
+		entry.KeyType = "RSA"
+		entry.N = b64(pub.N.Bytes())
+		entry.E = b64(big.NewInt(int64(pub.E)).Bytes())
+	default:
+		return entry, fmt.Errorf("unsupported signing key type %T", pub)
+	}
+
+	return entry, nil
+}
+
+func sortSigningKeys(keys []*signingKey) {
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].ActivateAt.Before(keys[j].ActivateAt)
+	})
+}
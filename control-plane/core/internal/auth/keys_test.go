@@ -0,0 +1,191 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/init/config"
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/init/logger"
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/internal/store"
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/pkg/certs"
+)
+
+// testCA is the issuing CA of the managers of a test, shared across restarts
+type testCA struct {
+	signer certs.CASigner
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	if logger.L() == nil {
+		logger.NewDevelopmentLogger()
+	}
+
+	key, err := certs.GenerateKey(certs.KeyTypeEd25519)
+	if err != nil {
+		t.Fatalf("failed to generate CA key: %v", err)
+	}
+	cert, err := certs.CreateCA("test CA", key, time.Hour)
+	if err != nil {
+		t.Fatalf("failed to create CA: %v", err)
+	}
+	signer, err := certs.NewCASigner(cert, key)
+	if err != nil {
+		t.Fatalf("failed to create CA signer: %v", err)
+	}
+	return &testCA{signer: signer}
+}
+
+// newTestManager starts an auth manager on dataStore with the auth
+// configuration adjusted by configure
+func (ca *testCA) newTestManager(t *testing.T, dataStore *store.Memory, configure func(*config.AuthConfig)) *Manager {
+	t.Helper()
+
+	cfg := config.DefaultConfig()
+	cfg.Core.Auth.AllowUnboundTokens = true
+	if configure != nil {
+		configure(&cfg.Core.Auth)
+	}
+	config.Set(cfg)
+
+	m, err := NewManager(ca.signer, dataStore)
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	t.Cleanup(m.Close)
+	return m
+}
+
+// writeKEK writes a fresh key encryption key and returns its path
+func writeKEK(t *testing.T) string {
+	t.Helper()
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatalf("failed to generate KEK: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "signing-key.kek")
+	if err := os.WriteFile(path, []byte(base64.StdEncoding.EncodeToString(key)+"\n"), 0o600); err != nil {
+		t.Fatalf("failed to write KEK: %v", err)
+	}
+	return path
+}
+
+func TestSigningKeysEncryptedWithKEK(t *testing.T) {
+	ca := newTestCA(t)
+	dataStore := store.NewMemory()
+	kekFile := writeKEK(t)
+	withKEK := func(cfg *config.AuthConfig) { cfg.SigningKeyKEKFile = kekFile }
+
+	m := ca.newTestManager(t, dataStore, withKEK)
+	token, _, err := m.GenerateAuthToken("node-1", nil)
+	if err != nil {
+		t.Fatalf("GenerateAuthToken() error = %v", err)
+	}
+
+	stored, err := dataStore.List(signingKeysBucket)
+	if err != nil || len(stored) == 0 {
+		t.Fatalf("no signing key stored: %v", err)
+	}
+	for kid, data := range stored {
+		if bytes.Contains(data, []byte(`"private_key"`)) || !bytes.Contains(data, []byte(`"sealed_key"`)) {
+			t.Fatalf("signing key %s stored in clear: %s", kid, data)
+		}
+	}
+
+	// The restarted manager decrypts the key and still verifies the token
+	m = ca.newTestManager(t, dataStore, withKEK)
+	if _, err := m.ValidateAuthToken("node-1", token, nil); err != nil {
+		t.Fatalf("ValidateAuthToken() after restart error = %v", err)
+	}
+
+	// A sealed key cannot be loaded without the KEK
+	cfg := config.DefaultConfig()
+	config.Set(cfg)
+	if _, err := NewManager(ca.signer, dataStore); err == nil {
+		t.Fatalf("NewManager() loaded encrypted signing keys without the KEK")
+	}
+}
+
+func TestClearSigningKeysEncryptedOnceKEKConfigured(t *testing.T) {
+	ca := newTestCA(t)
+	dataStore := store.NewMemory()
+
+	m := ca.newTestManager(t, dataStore, nil)
+	token, _, err := m.GenerateAuthToken("node-1", nil)
+	if err != nil {
+		t.Fatalf("GenerateAuthToken() error = %v", err)
+	}
+
+	kekFile := writeKEK(t)
+	m = ca.newTestManager(t, dataStore, func(cfg *config.AuthConfig) { cfg.SigningKeyKEKFile = kekFile })
+	if _, err := m.ValidateAuthToken("node-1", token, nil); err != nil {
+		t.Fatalf("ValidateAuthToken() error = %v", err)
+	}
+
+	stored, err := dataStore.List(signingKeysBucket)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	for kid, data := range stored {
+		if bytes.Contains(data, []byte(`"private_key"`)) {
+			t.Fatalf("signing key %s still stored in clear", kid)
+		}
+	}
+}
+
+func TestLegacyHS256Tokens(t *testing.T) {
+	ca := newTestCA(t)
+	const secret = "legacy-secret"
+
+	// Issued while the control plane signed with HS256
+	legacy := ca.newTestManager(t, store.NewMemory(), func(cfg *config.AuthConfig) {
+		cfg.SigningAlgorithm = AlgHS256
+		cfg.TokenSecret = secret
+	})
+	claims := jwt.MapClaims{"node_id": "node-1", "exp": time.Now().Add(time.Hour).Unix()}
+	token, err := legacy.signToken(claims)
+	if err != nil {
+		t.Fatalf("signToken() error = %v", err)
+	}
+	hs512, err := jwt.NewWithClaims(jwt.SigningMethodHS512, claims).SignedString([]byte(secret))
+	if err != nil {
+		t.Fatalf("failed to sign HS512 token: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		raw    string
+		accept bool
+		until  time.Time
+		wantOK bool
+	}{
+		{"not accepted", token, false, time.Now().Add(time.Hour), false},
+		{"before cut-off", token, true, time.Now().Add(time.Hour), true},
+		{"after cut-off", token, true, time.Now().Add(-time.Hour), false},
+		{"other HMAC algorithm", hs512, true, time.Now().Add(time.Hour), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := ca.newTestManager(t, store.NewMemory(), func(cfg *config.AuthConfig) {
+				cfg.TokenSecret = secret
+				cfg.AcceptLegacyHS256 = tt.accept
+				cfg.LegacyHS256Until = tt.until
+			})
+			_, err := m.parseToken("node-1", tt.raw)
+			if tt.wantOK && err != nil {
+				t.Fatalf("parseToken() error = %v, want none", err)
+			}
+			if !tt.wantOK && err == nil {
+				t.Fatalf("parseToken() accepted the token")
+			}
+		})
+	}
+}
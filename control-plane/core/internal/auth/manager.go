@@ -1,9 +1,9 @@
 package auth
 
 import (
-	"context"
+	"crypto/cipher"
 	"crypto/rand"
-	"crypto/rsa"
+	"crypto/subtle"
 	"crypto/x509"
 	"encoding/pem"
 	"fmt"
@@ -11,68 +11,134 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt"
+	"github.com/google/uuid"
 	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/init/config"
 	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/pkg/certs"
-	"google.golang.org/grpc/metadata"
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/shared/interfaces"
+	pb "github.com/raphaelCamblong/Luminous-Mesh/control-plane/shared/proto"
 )
 
 // Manager authentication and certificate operations
 type Manager struct {
-	config     *config.AuthConfig
-	caKey      *rsa.PrivateKey
-	caCert     *x509.Certificate
-	tokenCache sync.Map
-	mu         sync.RWMutex
+	config          *config.AuthConfig
+	trust           *certs.TrustBundle
+	store           interfaces.DataStore
+	bootstrapTokens []*bootstrapToken
+	tokens          map[string]*tokenRecord
+	families        map[string]*tokenFamily
+	mu              sync.RWMutex
+	keys            []*signingKey
+	keysMu          sync.RWMutex
+	kek             cipher.AEAD
+	certificates    map[string]*certificateRecord
+	tombstones      map[string]*Tombstone
+	stop            chan struct{}
+	stopOnce        sync.Once
 }
 
-func NewManager() (*Manager, error) {
+// NewManager creates the auth manager around the issuing CA.
+// Token state is persisted in store so revocations survive restarts.
+func NewManager(ca certs.CASigner, store interfaces.DataStore) (*Manager, error) {
 	cfg := config.Get().Core.Auth
 
-	caCert, caKey, err := certs.LoadCA(cfg.CACertPath, cfg.CAKeyPath)
+	trust, err := newTrustBundle(&cfg, ca)
 	if err != nil {
-		return nil, fmt.Errorf("failed to load CA: %w", err)
+		return nil, fmt.Errorf("failed to load CA bundle: %w", err)
 	}
 
-	return &Manager{
-		config: &cfg,
-		caKey:  caKey,
-		caCert: caCert,
-	}, nil
+	m := &Manager{
+		config:          &cfg,
+		trust:           trust,
+		store:           store,
+		bootstrapTokens: newBootstrapTokens(cfg.BootstrapTokens),
+		stop:            make(chan struct{}),
+	}
+
+	if err := m.loadTokenState(); err != nil {
+		return nil, err
+	}
+
+	if err := m.loadSigningKeys(); err != nil {
+		return nil, err
+	}
+
+	if err := m.loadDecommissionState(); err != nil {
+		return nil, err
+	}
+
+	go m.pruneLoop()
+	return m, nil
 }
 
-func (m *Manager) ValidateBootstrapToken(token string) error {
-	// TODO: Implement bootstrap token validation
-	if token == "" {
-		return fmt.Errorf("empty bootstrap token")
+// Close stops the background pruning of expired tokens
+func (m *Manager) Close() {
+	m.stopOnce.Do(func() { close(m.stop) })
+}
+
+// LoadCASigner builds the CA signer selected by the auth configuration
+func LoadCASigner(cfg *config.AuthConfig) (certs.CASigner, error) {
+	switch cfg.CASigner {
+	case "agent":
+		return certs.NewAgentSigner(cfg.CACertPath, cfg.CAAgentSocket)
+	default:
+		return certs.NewFileSigner(cfg.CACertPath, cfg.CAKeyPath, []byte(cfg.CAKeyPassphrase))
 	}
-	return nil
 }
 
-// SignCSR signs a certificate signing request
-func (m *Manager) SignCSR(csrBytes []byte) ([]byte, error) {
-	csr, err := x509.ParseCertificateRequest(csrBytes)
+// newTrustBundle combines the issuing CA with the CAs still trusted during a rotation
+func newTrustBundle(cfg *config.AuthConfig, issuer certs.CASigner) (*certs.TrustBundle, error) {
+	var trusted []*x509.Certificate
+	for _, path := range cfg.CABundlePaths {
+		bundle, err := certs.LoadCertificates(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		trusted = append(trusted, bundle...)
+	}
+
+	return certs.NewTrustBundle(issuer, trusted...)
+}
+
+// SignCSR signs a certificate signing request for a node and returns the
+// certificate with its PEM encoding. The certificate is tracked for revocation.
+func (m *Manager) SignCSR(nodeID string, csrBytes []byte) (*x509.Certificate, []byte, error) {
+	csr, err := certs.ParseCSR(csrBytes)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse CSR: %w", err)
+		return nil, nil, err
 	}
 
-	if err := csr.CheckSignature(); err != nil {
-		return nil, fmt.Errorf("invalid CSR signature: %w", err)
+	keyUsage := x509.KeyUsageDigitalSignature
+	if certs.IsRSAKey(csr.PublicKey) {
+		keyUsage |= x509.KeyUsageKeyEncipherment
 	}
 
+	// The signature algorithm follows the CA key, not the CSR key
 	template := &x509.Certificate{
-		SerialNumber:       certs.GenerateSerialNumber(),
-		Subject:            csr.Subject,
-		PublicKey:          csr.PublicKey,
-		NotBefore:          time.Now(),
-		NotAfter:           time.Now().Add(365 * 24 * time.Hour), // 1 year
-		KeyUsage:           x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
-		ExtKeyUsage:        []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
-		SignatureAlgorithm: csr.SignatureAlgorithm,
+		SerialNumber: certs.GenerateSerialNumber(),
+		Subject:      csr.Subject,
+		PublicKey:    csr.PublicKey,
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(365 * 24 * time.Hour), // 1 year
+		KeyUsage:     keyUsage,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+
+	issuer := m.trust.Issuer()
+	certBytes, err := x509.CreateCertificate(rand.Reader, template, issuer.Certificate(), csr.PublicKey, issuer)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create certificate: %w", err)
+	}
+
+	cert, err := x509.ParseCertificate(certBytes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse certificate: %w", err)
 	}
 
-	certBytes, err := x509.CreateCertificate(rand.Reader, template, m.caCert, csr.PublicKey, m.caKey)
+	m.mu.Lock()
+	err = m.recordCertificate(nodeID, cert)
+	m.mu.Unlock()
 	if err != nil {
-		return nil, fmt.Errorf("failed to create certificate: %w", err)
+		return nil, nil, err
 	}
 
 	certPEM := pem.EncodeToMemory(&pem.Block{
@@ -80,93 +146,218 @@ func (m *Manager) SignCSR(csrBytes []byte) ([]byte, error) {
 		Bytes: certBytes,
 	})
 
-	return certPEM, nil
+	return cert, certPEM, nil
 }
 
-func (m *Manager) GenerateAuthToken(nodeID string) (string, int64, error) {
-	expiry := time.Now().Add(m.config.TokenDuration).Unix()
+// GenerateAuthToken issues the first token of a new token family for a node,
+// bound to the node client certificate
+func (m *Manager) GenerateAuthToken(nodeID string, cert *x509.Certificate) (string, int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+	family, err := m.newTokenFamily(nodeID)
+	if err != nil {
+		return "", 0, err
+	}
+
+	return m.issueToken(nodeID, family.ID, cert)
+}
+
+// issueToken signs a token of the given family bound to cert. Callers hold m.mu.
+func (m *Manager) issueToken(nodeID, familyID string, cert *x509.Certificate) (string, int64, error) {
+	now := time.Now()
+	record := &tokenRecord{
+		ID:        uuid.New().String(),
+		FamilyID:  familyID,
+		NodeID:    nodeID,
+		State:     tokenActive,
+		IssuedAt:  now,
+		ExpiresAt: now.Add(m.config.TokenDuration),
+	}
+
+	claims := jwt.MapClaims{
 		"node_id": nodeID,
-		"exp":     expiry,
-		"iat":     time.Now().Unix(),
-	})
+		"jti":     record.ID,
+		"fam":     familyID,
+		"exp":     record.ExpiresAt.Unix(),
+		"iat":     now.Unix(),
+	}
+	if cert != nil {
+		claims["cnf"] = map[string]string{"x5t#S256": certs.Thumbprint(cert)}
+	}
 
-	tokenString, err := token.SignedString([]byte(m.config.TokenSecret))
+	tokenString, err := m.signToken(claims)
 	if err != nil {
 		return "", 0, fmt.Errorf("failed to sign token: %w", err)
 	}
 
-	m.tokenCache.Store(nodeID, tokenString)
-	return tokenString, expiry, nil
+	if err := m.recordToken(record); err != nil {
+		return "", 0, err
+	}
+
+	return tokenString, record.ExpiresAt.Unix(), nil
 }
 
-func (m *Manager) ValidateAuthToken(nodeID, tokenString string) error {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+// parseToken verifies the token signature and that it belongs to nodeID
+func (m *Manager) parseToken(nodeID, tokenString string) (*TokenClaims, error) {
+	token, err := jwt.Parse(tokenString, m.verificationKey)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse token: %w", err)
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, fmt.Errorf("invalid token")
+	}
+
+	if claims["node_id"] != nodeID {
+		return nil, fmt.Errorf("token node ID mismatch")
+	}
+
+	parsed := &TokenClaims{NodeID: nodeID}
+	parsed.ID, _ = claims["jti"].(string)
+	parsed.FamilyID, _ = claims["fam"].(string)
+	if iat, ok := claims["iat"].(float64); ok {
+		parsed.IssuedAt = time.Unix(int64(iat), 0)
+	}
+	if exp, ok := claims["exp"].(float64); ok {
+		parsed.ExpiresAt = time.Unix(int64(exp), 0)
+	}
+	if cnf, ok := claims["cnf"].(map[string]interface{}); ok {
+		parsed.Thumbprint, _ = cnf["x5t#S256"].(string)
+	}
+
+	return parsed, nil
+}
+
+// checkBinding verifies the token is presented with the certificate it is bound to.
+// It runs before the token state checks so a stolen token cannot trigger reuse detection.
+func (m *Manager) checkBinding(claims *TokenClaims, peer *x509.Certificate) error {
+	if claims.Thumbprint == "" {
+		if m.config.AllowUnboundTokens {
+			return nil
 		}
-		return []byte(m.config.TokenSecret), nil
-	})
+		return ErrTokenUnbound
+	}
+
+	if peer == nil || subtle.ConstantTimeCompare([]byte(claims.Thumbprint), []byte(certs.Thumbprint(peer))) != 1 {
+		return ErrCertificateMismatch
+	}
+	return nil
+}
 
+// ValidateAuthToken checks the token signature, its certificate binding
+// against the TLS peer certificate and its server-side state
+func (m *Manager) ValidateAuthToken(nodeID, tokenString string, peer *x509.Certificate) (*TokenClaims, error) {
+	claims, err := m.parseToken(nodeID, tokenString)
 	if err != nil {
-		return fmt.Errorf("failed to parse token: %w", err)
+		return nil, err
 	}
 
-	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		if claims["node_id"] != nodeID {
-			return fmt.Errorf("token node ID mismatch")
+	if _, ok := m.Tombstone(nodeID); ok {
+		return nil, ErrNodeDecommissioned
+	}
+
+	if err := m.checkBinding(claims, peer); err != nil {
+		return nil, err
+	}
+
+	if peer != nil {
+		if err := m.CheckCertificate(peer); err != nil {
+			return nil, err
 		}
-		return nil
 	}
 
-	return fmt.Errorf("invalid token")
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, err := m.checkToken(claims.ID, nodeID); err != nil {
+		return nil, err
+	}
+	return claims, nil
 }
 
-func (m *Manager) ValidateCertificate(certBytes []byte) error {
+// CheckToken verifies a validated token can still be rotated, so work done
+// before RotateToken is not wasted on a token that was rotated meanwhile
+func (m *Manager) CheckToken(claims *TokenClaims) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, err := m.checkToken(claims.ID, claims.NodeID)
+	return err
+}
+
+// RotateToken replaces a validated token by a new one of the same family,
+// bound to cert. The current token is invalidated immediately.
+func (m *Manager) RotateToken(claims *TokenClaims, cert *x509.Certificate) (string, int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Checked again under the lock so concurrent rotations count as reuse
+	record, err := m.checkToken(claims.ID, claims.NodeID)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid current token: %w", err)
+	}
+
+	record.State = tokenRotated
+	if err := m.saveToken(record); err != nil {
+		return "", 0, err
+	}
+
+	return m.issueToken(claims.NodeID, record.FamilyID, cert)
+}
+
+// ValidateCertificate checks a node certificate against the trust bundle
+func (m *Manager) ValidateCertificate(certBytes []byte) (*x509.Certificate, error) {
 	block, _ := pem.Decode(certBytes)
 	if block == nil {
-		return fmt.Errorf("failed to decode certificate PEM")
+		return nil, fmt.Errorf("failed to decode certificate PEM")
 	}
 
 	cert, err := x509.ParseCertificate(block.Bytes)
 	if err != nil {
-		return fmt.Errorf("failed to parse certificate: %w", err)
+		return nil, fmt.Errorf("failed to parse certificate: %w", err)
 	}
 
-	if err := cert.CheckSignatureFrom(m.caCert); err != nil {
-		return fmt.Errorf("certificate not signed by our CA: %w", err)
+	if _, err := m.trust.Verify(cert, x509.ExtKeyUsageClientAuth); err != nil {
+		return nil, fmt.Errorf("certificate not signed by a trusted CA: %w", err)
 	}
 
-	now := time.Now()
-	if now.Before(cert.NotBefore) || now.After(cert.NotAfter) {
-		return fmt.Errorf("certificate is not valid at this time (not before: %s, not after: %s)", cert.NotBefore, cert.NotAfter)
+	if err := m.CheckCertificate(cert); err != nil {
+		return nil, err
 	}
 
-	return nil
+	return cert, nil
 }
 
-func (m *Manager) RotateToken(nodeID, currentToken string) (string, int64, error) {
-	if err := m.ValidateAuthToken(nodeID, currentToken); err != nil {
-		return "", 0, fmt.Errorf("invalid current token: %w", err)
-	}
-
-	return m.GenerateAuthToken(nodeID)
+// TrustBundle returns the CAs trusted for node certificates
+func (m *Manager) TrustBundle() *certs.TrustBundle {
+	return m.trust
 }
 
-func (m *Manager) GetTokenExpiry() int64 {
-	return time.Now().Add(m.config.TokenDuration).Unix()
-}
+// TrustBundleUpdate builds the trust bundle command for a node holding cert.
+// Nodes whose certificate was issued by a CA being retired are asked to re-key.
+func (m *Manager) TrustBundleUpdate(cert *x509.Certificate) *pb.TrustBundleUpdate {
+	update := &pb.TrustBundleUpdate{
+		CaBundle:          m.trust.PEM(),
+		IssuerFingerprint: certs.Fingerprint(m.trust.Issuer().Certificate()),
+	}
 
-func (m *Manager) NodeIDFromContext(ctx context.Context) (string, error) {
-	md, ok := metadata.FromIncomingContext(ctx)
-	if !ok {
-		return "", fmt.Errorf("no metadata found in context")
+	if cert == nil || m.trust.IsIssuedByCurrentCA(cert) {
+		return update
 	}
 
-	nodeID := md.Get("node_id")
-	if len(nodeID) == 0 {
-		return "", fmt.Errorf("node ID not found in metadata")
+	rekeyBefore := cert.NotAfter
+	if ca, err := m.trust.Verify(cert, x509.ExtKeyUsageClientAuth); err == nil && ca.NotAfter.Before(rekeyBefore) {
+		rekeyBefore = ca.NotAfter
 	}
-	return nodeID[0], nil
+
+	update.RekeyRequired = true
+	update.RekeyBefore = rekeyBefore.Unix()
+	return update
+}
+
+func (m *Manager) GetTokenExpiry() int64 {
+	return time.Now().Add(m.config.TokenDuration).Unix()
 }
@@ -0,0 +1,57 @@
+package auth
+
+import (
+	"context"
+	"crypto/x509"
+	"time"
+
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/internal/access"
+)
+
+// AuthMethod is how the caller of a request was authenticated
+type AuthMethod string
+
+const (
+	// AuthMethodCertificate callers presented a verified client certificate only
+	AuthMethodCertificate AuthMethod = "certificate"
+	// AuthMethodToken callers presented a node token bound to their certificate
+	AuthMethodToken AuthMethod = "token"
+	// AuthMethodAdmin callers presented an operator credential: the admin
+	// token, a login session or an API key
+	AuthMethodAdmin AuthMethod = "admin"
+)
+
+// TokenClaims are the claims of a validated node token
+type TokenClaims struct {
+	ID         string
+	FamilyID   string
+	NodeID     string
+	Thumbprint string
+	IssuedAt   time.Time
+	ExpiresAt  time.Time
+}
+
+// Principal is the authenticated caller of a request
+type Principal struct {
+	Method      AuthMethod
+	NodeID      string
+	SessionID   string
+	Claims      *TokenClaims
+	Certificate *x509.Certificate
+	CertSerial  string
+	// Operator is the identity of admin callers
+	Operator *access.Identity
+}
+
+type principalKey struct{}
+
+// NewContext returns a context carrying the principal
+func NewContext(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFromContext returns the principal attached by the server interceptors
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	return p, ok
+}
@@ -0,0 +1,275 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/init/logger"
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/shared/interfaces"
+	"go.uber.org/zap"
+)
+
+// Buckets holding the server-side token state
+const (
+	tokensBucket   = "auth.tokens"
+	familiesBucket = "auth.token_families"
+)
+
+// pruneInterval is how often expired tokens and families are forgotten
+const pruneInterval = time.Minute
+
+// Token states. A rotated token was replaced by a newer token of its family;
+// presenting it again is treated as theft and revokes the whole family.
+const (
+	tokenActive  = "active"
+	tokenRotated = "rotated"
+	tokenRevoked = "revoked"
+)
+
+var (
+	ErrTokenUnknown = errors.New("token unknown to the control plane")
+	ErrTokenRevoked = errors.New("token revoked")
+	ErrTokenReused  = errors.New("rotated token reused, token family revoked")
+
+	ErrTokenUnbound        = errors.New("token is not bound to a client certificate")
+	ErrCertificateMismatch = errors.New("token is bound to a different client certificate")
+)
+
+// tokenRecord is the server-side state of one issued JWT
+type tokenRecord struct {
+	ID        string    `json:"jti"`
+	FamilyID  string    `json:"family_id"`
+	NodeID    string    `json:"node_id"`
+	State     string    `json:"state"`
+	IssuedAt  time.Time `json:"issued_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// tokenFamily links the tokens derived from one another through rotation
+type tokenFamily struct {
+	ID           string    `json:"id"`
+	NodeID       string    `json:"node_id"`
+	CreatedAt    time.Time `json:"created_at"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	Revoked      bool      `json:"revoked"`
+	RevokedAt    time.Time `json:"revoked_at,omitempty"`
+	RevokeReason string    `json:"revoke_reason,omitempty"`
+}
+
+// loadTokenState restores token records and families from the data store
+func (m *Manager) loadTokenState() error {
+	m.tokens = make(map[string]*tokenRecord)
+	m.families = make(map[string]*tokenFamily)
+
+	if m.store == nil {
+		return nil
+	}
+
+	families, err := m.store.List(familiesBucket)
+	if err != nil {
+		return fmt.Errorf("failed to load token families: %w", err)
+	}
+	for _, data := range families {
+		var family tokenFamily
+		if err := json.Unmarshal(data, &family); err != nil {
+			return fmt.Errorf("failed to decode token family: %w", err)
+		}
+		m.families[family.ID] = &family
+	}
+
+	tokens, err := m.store.List(tokensBucket)
+	if err != nil {
+		return fmt.Errorf("failed to load tokens: %w", err)
+	}
+	for _, data := range tokens {
+		var record tokenRecord
+		if err := json.Unmarshal(data, &record); err != nil {
+			return fmt.Errorf("failed to decode token: %w", err)
+		}
+		m.tokens[record.ID] = &record
+	}
+
+	m.pruneExpiredTokens()
+	return nil
+}
+
+// newTokenFamily starts a family for a freshly credentialed node. Callers hold m.mu.
+func (m *Manager) newTokenFamily(nodeID string) (*tokenFamily, error) {
+	family := &tokenFamily{
+		ID:        uuid.New().String(),
+		NodeID:    nodeID,
+		CreatedAt: time.Now(),
+	}
+	if err := m.saveFamily(family); err != nil {
+		return nil, err
+	}
+	m.families[family.ID] = family
+	return family, nil
+}
+
+// recordToken registers a newly issued token in its family. Callers hold m.mu.
+func (m *Manager) recordToken(record *tokenRecord) error {
+	family := m.families[record.FamilyID]
+	if record.ExpiresAt.After(family.ExpiresAt) {
+		family.ExpiresAt = record.ExpiresAt
+		if err := m.saveFamily(family); err != nil {
+			return err
+		}
+	}
+
+	if err := m.saveToken(record); err != nil {
+		return err
+	}
+	m.tokens[record.ID] = record
+	return nil
+}
+
+// checkToken verifies the server-side state of a token. Callers hold m.mu.
+func (m *Manager) checkToken(tokenID, nodeID string) (*tokenRecord, error) {
+	record, ok := m.tokens[tokenID]
+	if !ok || record.NodeID != nodeID {
+		return nil, ErrTokenUnknown
+	}
+
+	family, ok := m.families[record.FamilyID]
+	if !ok || family.Revoked {
+		return nil, ErrTokenRevoked
+	}
+
+	switch record.State {
+	case tokenActive:
+		return record, nil
+	case tokenRotated:
+		logger.L().Warn("Rotated token reused, revoking token family",
+			zap.String("node_id", nodeID),
+			zap.String("family_id", family.ID),
+			zap.String("jti", tokenID),
+		)
+		if err := m.revokeFamily(family, "rotated token reused"); err != nil {
+			logger.L().Error("Failed to revoke token family", zap.Error(err))
+		}
+		return nil, ErrTokenReused
+	default:
+		return nil, ErrTokenRevoked
+	}
+}
+
+// revokeFamily invalidates every token of a family. Callers hold m.mu.
+func (m *Manager) revokeFamily(family *tokenFamily, reason string) error {
+	family.Revoked = true
+	family.RevokedAt = time.Now()
+	family.RevokeReason = reason
+	if err := m.saveFamily(family); err != nil {
+		return err
+	}
+
+	for _, record := range m.tokens {
+		if record.FamilyID != family.ID || record.State == tokenRevoked {
+			continue
+		}
+		record.State = tokenRevoked
+		if err := m.saveToken(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RevokeNodeTokens revokes every token family of a node and returns how many were revoked
+func (m *Manager) RevokeNodeTokens(nodeID, reason string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	revoked := 0
+	for _, family := range m.families {
+		if family.NodeID != nodeID || family.Revoked {
+			continue
+		}
+		if err := m.revokeFamily(family, reason); err != nil {
+			return revoked, fmt.Errorf("failed to revoke token family %s: %w", family.ID, err)
+		}
+		revoked++
+	}
+
+	logger.L().Info("Node tokens revoked",
+		zap.String("node_id", nodeID),
+		zap.Int("families", revoked),
+		zap.String("reason", reason),
+	)
+	return revoked, nil
+}
+
+// pruneLoop periodically forgets expired token state until the manager is closed
+func (m *Manager) pruneLoop() {
+	ticker := time.NewTicker(pruneInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			m.mu.Lock()
+			m.pruneExpiredTokens()
+			m.mu.Unlock()
+		case <-m.stop:
+			return
+		}
+	}
+}
+
+// pruneExpiredTokens forgets tokens and families past their expiry. Callers hold m.mu.
+func (m *Manager) pruneExpiredTokens() {
+	now := time.Now()
+
+	for id, record := range m.tokens {
+		if now.After(record.ExpiresAt) {
+			delete(m.tokens, id)
+			m.deleteFromStore(tokensBucket, id)
+		}
+	}
+
+	for id, family := range m.families {
+		if !family.ExpiresAt.IsZero() && now.After(family.ExpiresAt) {
+			delete(m.families, id)
+			m.deleteFromStore(familiesBucket, id)
+		}
+	}
+}
+
+func (m *Manager) saveToken(record *tokenRecord) error {
+	return m.saveToStore(tokensBucket, record.ID, record)
+}
+
+func (m *Manager) saveFamily(family *tokenFamily) error {
+	return m.saveToStore(familiesBucket, family.ID, family)
+}
+
+func (m *Manager) saveToStore(bucket, key string, value interface{}) error {
+	if m.store == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", bucket, err)
+	}
+	if err := m.store.Put(bucket, key, data); err != nil {
+		return fmt.Errorf("failed to persist %s: %w", bucket, err)
+	}
+	return nil
+}
+
+func (m *Manager) deleteFromStore(bucket, key string) {
+	if m.store == nil {
+		return
+	}
+	if err := m.store.Delete(bucket, key); err != nil && !errors.Is(err, interfaces.ErrNotFound) {
+		logger.L().Warn("Failed to delete expired token state",
+			zap.String("bucket", bucket),
+			zap.String("key", key),
+			zap.Error(err),
+		)
+	}
+}
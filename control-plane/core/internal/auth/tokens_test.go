@@ -0,0 +1,168 @@
+package auth
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/init/config"
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/internal/store"
+)
+
+// issue returns the first token of a new family and its validated claims
+func issue(t *testing.T, m *Manager, nodeID string) (string, *TokenClaims) {
+	t.Helper()
+
+	token, _, err := m.GenerateAuthToken(nodeID, nil)
+	if err != nil {
+		t.Fatalf("GenerateAuthToken() error = %v", err)
+	}
+	return token, validate(t, m, nodeID, token)
+}
+
+func validate(t *testing.T, m *Manager, nodeID, token string) *TokenClaims {
+	t.Helper()
+
+	claims, err := m.ValidateAuthToken(nodeID, token, nil)
+	if err != nil {
+		t.Fatalf("ValidateAuthToken() error = %v", err)
+	}
+	return claims
+}
+
+func TestRotatedTokenReuseRevokesFamily(t *testing.T) {
+	m := newTestCA(t).newTestManager(t, store.NewMemory(), nil)
+
+	first, claims := issue(t, m, "node-1")
+	second, _, err := m.RotateToken(claims, nil)
+	if err != nil {
+		t.Fatalf("RotateToken() error = %v", err)
+	}
+	third, _, err := m.RotateToken(validate(t, m, "node-1", second), nil)
+	if err != nil {
+		t.Fatalf("RotateToken() error = %v", err)
+	}
+	other, _ := issue(t, m, "node-1")
+
+	// The first token was stolen and replayed
+	if _, err := m.ValidateAuthToken("node-1", first, nil); !errors.Is(err, ErrTokenReused) {
+		t.Fatalf("ValidateAuthToken() of a rotated token error = %v, want %v", err, ErrTokenReused)
+	}
+	for name, token := range map[string]string{"rotated": second, "current": third} {
+		if _, err := m.ValidateAuthToken("node-1", token, nil); !errors.Is(err, ErrTokenRevoked) {
+			t.Fatalf("ValidateAuthToken() of the %s token error = %v, want %v", name, err, ErrTokenRevoked)
+		}
+	}
+
+	// Other families of the node are left alone
+	validate(t, m, "node-1", other)
+}
+
+func TestConcurrentRotation(t *testing.T) {
+	m := newTestCA(t).newTestManager(t, store.NewMemory(), nil)
+	_, claims := issue(t, m, "node-1")
+
+	const rotations = 8
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		issued  []string
+		refused []error
+	)
+	for i := 0; i < rotations; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			token, _, err := m.RotateToken(claims, nil)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				refused = append(refused, err)
+				return
+			}
+			issued = append(issued, token)
+		}()
+	}
+	wg.Wait()
+
+	if len(issued) != 1 {
+		t.Fatalf("%d rotations of the same token succeeded, want 1", len(issued))
+	}
+	reused := 0
+	for _, err := range refused {
+		switch {
+		case errors.Is(err, ErrTokenReused):
+			reused++
+		case !errors.Is(err, ErrTokenRevoked):
+			t.Fatalf("RotateToken() error = %v, want %v or %v", err, ErrTokenReused, ErrTokenRevoked)
+		}
+	}
+	if reused == 0 {
+		t.Fatalf("no concurrent rotation was detected as reuse")
+	}
+
+	// The reuse revoked the family, including the token that won the race
+	if _, err := m.ValidateAuthToken("node-1", issued[0], nil); !errors.Is(err, ErrTokenRevoked) {
+		t.Fatalf("ValidateAuthToken() of the winning token error = %v, want %v", err, ErrTokenRevoked)
+	}
+}
+
+func TestTokenStateSurvivesRestart(t *testing.T) {
+	ca := newTestCA(t)
+	dataStore := store.NewMemory()
+	m := ca.newTestManager(t, dataStore, nil)
+
+	first, claims := issue(t, m, "node-1")
+	current, _, err := m.RotateToken(claims, nil)
+	if err != nil {
+		t.Fatalf("RotateToken() error = %v", err)
+	}
+	revoked, _ := issue(t, m, "node-2")
+	if _, err := m.RevokeNodeTokens("node-2", "compromised"); err != nil {
+		t.Fatalf("RevokeNodeTokens() error = %v", err)
+	}
+
+	m = ca.newTestManager(t, dataStore, nil)
+	if _, err := m.ValidateAuthToken("node-2", revoked, nil); !errors.Is(err, ErrTokenRevoked) {
+		t.Fatalf("ValidateAuthToken() of a revoked token after restart error = %v, want %v", err, ErrTokenRevoked)
+	}
+	validate(t, m, "node-1", current)
+	if _, err := m.ValidateAuthToken("node-1", first, nil); !errors.Is(err, ErrTokenReused) {
+		t.Fatalf("ValidateAuthToken() of a rotated token after restart error = %v, want %v", err, ErrTokenReused)
+	}
+
+	// The revocation following the reuse is persisted too
+	m = ca.newTestManager(t, dataStore, nil)
+	if _, err := m.ValidateAuthToken("node-1", current, nil); !errors.Is(err, ErrTokenRevoked) {
+		t.Fatalf("ValidateAuthToken() after the reuse and a restart error = %v, want %v", err, ErrTokenRevoked)
+	}
+}
+
+func TestExpiredTokensPruned(t *testing.T) {
+	dataStore := store.NewMemory()
+	m := newTestCA(t).newTestManager(t, dataStore, func(cfg *config.AuthConfig) {
+		cfg.TokenDuration = time.Millisecond
+	})
+	issue := func() {
+		if _, _, err := m.GenerateAuthToken("node-1", nil); err != nil {
+			t.Fatalf("GenerateAuthToken() error = %v", err)
+		}
+	}
+	issue()
+	issue()
+	time.Sleep(10 * time.Millisecond)
+
+	m.mu.Lock()
+	m.pruneExpiredTokens()
+	m.mu.Unlock()
+
+	for _, bucket := range []string{tokensBucket, familiesBucket} {
+		if stored, _ := dataStore.List(bucket); len(stored) != 0 {
+			t.Fatalf("%d expired records left in %s", len(stored), bucket)
+		}
+	}
+	if len(m.tokens) != 0 || len(m.families) != 0 {
+		t.Fatalf("%d tokens and %d families left in memory", len(m.tokens), len(m.families))
+	}
+}
@@ -0,0 +1,109 @@
+package dev
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/init/config"
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/pkg/certs"
+)
+
+const validity = 7 * 24 * time.Hour
+
+// Environment is the ephemeral PKI of a development control plane.
+// Keys only live in memory; the CA certificate is written out so nodes can trust it.
+type Environment struct {
+	CA             certs.CASigner
+	TLSCertificate tls.Certificate
+	CACertPath     string
+}
+
+// NewEnvironment generates a CA and a server certificate for local hostnames
+func NewEnvironment() (*Environment, error) {
+	caKey, err := certs.GenerateKey(certs.KeyTypeECDSA)
+	if err != nil {
+		return nil, err
+	}
+
+	caCert, err := certs.CreateCA("Luminous Mesh Development CA", caKey, validity)
+	if err != nil {
+		return nil, err
+	}
+
+	ca, err := certs.NewCASigner(caCert, caKey)
+	if err != nil {
+		return nil, err
+	}
+
+	serverKey, err := certs.GenerateKey(certs.KeyTypeECDSA)
+	if err != nil {
+		return nil, err
+	}
+
+	sans := []string{"localhost", "127.0.0.1", "::1"}
+	if hostname, err := os.Hostname(); err == nil && hostname != "localhost" {
+		sans = append(sans, hostname)
+	}
+
+	serverCert, err := certs.IssueServerCertificate(ca, serverKey.Public(), "localhost", sans, validity)
+	if err != nil {
+		return nil, err
+	}
+
+	// A private directory per instance, so nothing can be planted at the path
+	// and instances running side by side keep their own CA
+	dir, err := os.MkdirTemp("", "luminous-mesh-dev-")
+	if err != nil {
+		return nil, err
+	}
+	caPath := filepath.Join(dir, "ca.crt")
+	if err := certs.WriteFile(caPath, certs.EncodeCertificates([]*x509.Certificate{caCert}), 0o644, false); err != nil {
+		return nil, err
+	}
+
+	return &Environment{
+		CA: ca,
+		TLSCertificate: tls.Certificate{
+			Certificate: [][]byte{serverCert.Raw},
+			PrivateKey:  serverKey,
+			Leaf:        serverCert,
+		},
+		CACertPath: caPath,
+	}, nil
+}
+
+// CACerts returns the CAs a node must trust to reach the server
+func (e *Environment) CACerts() []*x509.Certificate {
+	return []*x509.Certificate{e.CA.Certificate()}
+}
+
+// PrintBanner shows what a developer needs to connect a node
+func (e *Environment) PrintBanner(cfg *config.Config) {
+	fmt.Fprintf(os.Stderr, "\n🧪 Luminous Mesh control plane running in development mode\n")
+	fmt.Fprintf(os.Stderr, "   Endpoint:        %s\n", cfg.Core.APIEndpoint)
+	if cfg.Core.HTTP.ListenAddr != "" {
+		scheme := "https"
+		if cfg.Core.HTTP.Plaintext {
+			scheme = "http"
+		}
+		fmt.Fprintf(os.Stderr, "   JWKS:            %s://localhost%s/.well-known/jwks.json\n", scheme, cfg.Core.HTTP.ListenAddr)
+	}
+	fmt.Fprintf(os.Stderr, "   CA certificate:  %s\n", e.CACertPath)
+	fmt.Fprintf(os.Stderr, "   CA fingerprint:  %s\n", certs.Fingerprint(e.CA.Certificate()))
+	for _, token := range cfg.Core.Auth.BootstrapTokens {
+		if token.Description == "development" {
+			fmt.Fprintf(os.Stderr, "   Bootstrap token: %s\n", token.Token)
+		}
+	}
+	fmt.Fprintf(os.Stderr, "   Admin token:     %s\n", cfg.Core.Admin.Token)
+	auditLog := cfg.Core.Audit.Path
+	if auditLog == "" {
+		auditLog = "disabled"
+	}
+	fmt.Fprintf(os.Stderr, "   Audit log:       %s\n", auditLog)
+	fmt.Fprintf(os.Stderr, "   ⚠️  Keys, secrets and state are kept in memory and lost on exit\n\n")
+}
@@ -0,0 +1,209 @@
+package lmgrpc
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/internal/access"
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/internal/audit"
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/internal/auth"
+	pb "github.com/raphaelCamblong/Luminous-Mesh/control-plane/shared/proto"
+)
+
+// operatorOf returns the operator identity attached by the admin policy
+func operatorOf(ctx context.Context) (*access.Identity, error) {
+	p, ok := auth.PrincipalFromContext(ctx)
+	if !ok || p.Operator == nil {
+		return nil, errCredentialsMissing.Errorf("operator credential required")
+	}
+	return p.Operator, nil
+}
+
+// accessError maps an access manager error to a gRPC status
+func accessError(err error, msg string) error {
+	switch {
+	case errors.Is(err, access.ErrInvalidRequest):
+		return errInvalidRequest.Errorf("%s", err)
+	case errors.Is(err, access.ErrPermissionDenied):
+		return errPermissionDenied.Errorf("%s", err)
+	case errors.Is(err, access.ErrUserExists):
+		return errUserExists.Err()
+	case errors.Is(err, access.ErrUserNotFound):
+		return errUserNotFound.Err()
+	case errors.Is(err, access.ErrAPIKeyNotFound):
+		return errAPIKeyNotFound.Err()
+	}
+
+	return internalError(msg, err)
+}
+
+// Login exchanges an operator password for a session token
+func (a *adminServer) Login(ctx context.Context, req *pb.LoginRequest) (*pb.LoginResponse, error) {
+	token, expiresAt, identity, err := a.server.accessManager.Login(req.Username, req.Password)
+	if err != nil {
+		a.server.audit(ctx, "operator.login", req.Username, audit.OutcomeFailure, nil)
+		if errors.Is(err, access.ErrInvalidCredentials) {
+			return nil, errOperatorCredentialInvalid.Errorf("invalid username or password")
+		}
+		return nil, accessError(err, "Failed to open operator session")
+	}
+
+	a.server.audit(ctx, "operator.login", req.Username, audit.OutcomeSuccess, map[string]string{
+		"expires_at": expiresAt.UTC().Format(time.RFC3339),
+	})
+
+	return &pb.LoginResponse{
+		Token:       token,
+		ExpiresAt:   expiresAt.Unix(),
+		Roles:       identity.Roles,
+		Permissions: identity.PermissionList(),
+	}, nil
+}
+
+// CreateUser adds an operator account
+func (a *adminServer) CreateUser(ctx context.Context, req *pb.CreateUserRequest) (*pb.UserInfo, error) {
+	operator, err := operatorOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := a.server.accessManager.CreateUser(operator, req.Username, req.Password, req.Roles)
+	if err != nil {
+		a.server.audit(ctx, "user.create", req.Username, audit.OutcomeFailure, map[string]string{
+			"roles":  strings.Join(req.Roles, ","),
+			"reason": err.Error(),
+		})
+		return nil, accessError(err, "Failed to create user")
+	}
+
+	a.server.audit(ctx, "user.create", user.Username, audit.OutcomeSuccess, map[string]string{
+		"roles": strings.Join(user.Roles, ","),
+	})
+
+	return userInfo(user), nil
+}
+
+// ListUsers lists the operator accounts
+func (a *adminServer) ListUsers(ctx context.Context, req *pb.ListUsersRequest) (*pb.ListUsersResponse, error) {
+	users := a.server.accessManager.ListUsers()
+
+	resp := &pb.ListUsersResponse{
+		Users: make([]*pb.UserInfo, 0, len(users)),
+	}
+	for _, user := range users {
+		resp.Users = append(resp.Users, userInfo(user))
+	}
+	return resp, nil
+}
+
+// DeleteUser removes an operator account along with its sessions and API keys
+func (a *adminServer) DeleteUser(ctx context.Context, req *pb.DeleteUserRequest) (*pb.DeleteUserResponse, error) {
+	revoked, err := a.server.accessManager.DeleteUser(req.Username)
+	if err != nil {
+		return nil, accessError(err, "Failed to delete user")
+	}
+
+	a.server.audit(ctx, "user.delete", req.Username, audit.OutcomeSuccess, map[string]string{
+		"revoked_api_keys": strconv.Itoa(revoked),
+	})
+
+	return &pb.DeleteUserResponse{
+		RevokedApiKeys: int32(revoked),
+	}, nil
+}
+
+// CreateAPIKey issues an API key scoped to permissions the caller holds
+func (a *adminServer) CreateAPIKey(ctx context.Context, req *pb.CreateAPIKeyRequest) (*pb.CreateAPIKeyResponse, error) {
+	operator, err := operatorOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ttl := time.Duration(req.TtlSeconds) * time.Second
+	key, secret, err := a.server.accessManager.CreateAPIKey(operator, req.Name, req.Permissions, ttl)
+	if err != nil {
+		a.server.audit(ctx, "apikey.create", "", audit.OutcomeFailure, map[string]string{
+			"name":        req.Name,
+			"permissions": strings.Join(req.Permissions, ","),
+			"reason":      err.Error(),
+		})
+		return nil, accessError(err, "Failed to create API key")
+	}
+
+	a.server.audit(ctx, "apikey.create", key.ID, audit.OutcomeSuccess, map[string]string{
+		"name":        key.Name,
+		"permissions": strings.Join(key.Permissions, ","),
+	})
+
+	return &pb.CreateAPIKeyResponse{
+		Key:    apiKeyInfo(key),
+		Secret: secret,
+	}, nil
+}
+
+// ListAPIKeys lists the API keys, without their secrets
+func (a *adminServer) ListAPIKeys(ctx context.Context, req *pb.ListAPIKeysRequest) (*pb.ListAPIKeysResponse, error) {
+	keys := a.server.accessManager.ListAPIKeys()
+
+	resp := &pb.ListAPIKeysResponse{
+		Keys: make([]*pb.APIKeyInfo, 0, len(keys)),
+	}
+	for _, key := range keys {
+		resp.Keys = append(resp.Keys, apiKeyInfo(key))
+	}
+	return resp, nil
+}
+
+// RevokeAPIKey deletes an API key
+func (a *adminServer) RevokeAPIKey(ctx context.Context, req *pb.RevokeAPIKeyRequest) (*pb.RevokeAPIKeyResponse, error) {
+	if err := a.server.accessManager.RevokeAPIKey(req.Id); err != nil {
+		return nil, accessError(err, "Failed to revoke API key")
+	}
+
+	a.server.audit(ctx, "apikey.revoke", req.Id, audit.OutcomeSuccess, nil)
+
+	return &pb.RevokeAPIKeyResponse{}, nil
+}
+
+// ListRoles lists the built-in and configured roles
+func (a *adminServer) ListRoles(ctx context.Context, req *pb.ListRolesRequest) (*pb.ListRolesResponse, error) {
+	roles := a.server.accessManager.Roles()
+
+	resp := &pb.ListRolesResponse{
+		Roles: make([]*pb.RoleInfo, 0, len(roles)),
+	}
+	for _, role := range roles {
+		resp.Roles = append(resp.Roles, &pb.RoleInfo{
+			Name:        role.Name,
+			Permissions: role.Permissions,
+			Builtin:     role.Builtin,
+		})
+	}
+	return resp, nil
+}
+
+func userInfo(user *access.User) *pb.UserInfo {
+	return &pb.UserInfo{
+		Username:  user.Username,
+		Roles:     user.Roles,
+		CreatedAt: user.CreatedAt.Unix(),
+		CreatedBy: user.CreatedBy,
+	}
+}
+
+func apiKeyInfo(key *access.APIKey) *pb.APIKeyInfo {
+	info := &pb.APIKeyInfo{
+		Id:          key.ID,
+		Name:        key.Name,
+		Owner:       key.Owner,
+		Permissions: key.Permissions,
+		CreatedAt:   key.CreatedAt.Unix(),
+	}
+	if !key.ExpiresAt.IsZero() {
+		info.ExpiresAt = key.ExpiresAt.Unix()
+	}
+	return info
+}
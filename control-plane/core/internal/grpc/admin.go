@@ -0,0 +1,193 @@
+package lmgrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/internal/audit"
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/internal/auth"
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/internal/node"
+	pb "github.com/raphaelCamblong/Luminous-Mesh/control-plane/shared/proto"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/metadata"
+)
+
+// adminServer implements the operator-facing AdminService
+type adminServer struct {
+	pb.UnimplementedAdminServiceServer
+	server *Server
+}
+
+// authenticateAdmin resolves the operator credential from the context and
+// checks it holds the permission of method
+func (s *Server) authenticateAdmin(ctx context.Context, method string) (*auth.Principal, error) {
+	permission, ok := methodPermissions[method]
+	if !ok {
+		return nil, errPermissionDenied.Errorf("no permission declared for method")
+	}
+
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return nil, errCredentialsMissing.Errorf("missing metadata")
+	}
+
+	tokens := md.Get("authorization")
+	if len(tokens) == 0 {
+		return nil, errCredentialsMissing.Errorf("missing authorization token")
+	}
+
+	identity, err := s.accessManager.Authenticate(strings.TrimPrefix(tokens[0], "Bearer "))
+	if err != nil {
+		return nil, errOperatorCredentialInvalid.Err()
+	}
+
+	principal := &auth.Principal{
+		Method:   auth.AuthMethodAdmin,
+		Operator: identity,
+	}
+	if !identity.Can(permission) {
+		// The principal is attached so the denial is audited against the operator
+		return principal, errPermissionDenied.Detailed(
+			fmt.Sprintf("%s requires the %s permission", identity.Subject, permission),
+			map[string]string{"permission": permission},
+		)
+	}
+
+	return principal, nil
+}
+
+// RevokeNodeTokens revokes every token family of a node
+func (a *adminServer) RevokeNodeTokens(ctx context.Context, req *pb.RevokeNodeTokensRequest) (*pb.RevokeNodeTokensResponse, error) {
+	reason := req.Reason
+	if reason == "" {
+		reason = "revoked by administrator"
+	}
+
+	revoked, err := a.server.authManager.RevokeNodeTokens(req.NodeId, reason)
+	if err != nil {
+		return nil, internalError("Failed to revoke node tokens", err, zap.String("node_id", req.NodeId))
+	}
+
+	a.server.audit(ctx, "token.revoke", req.NodeId, audit.OutcomeSuccess, map[string]string{
+		"reason":           reason,
+		"revoked_families": strconv.Itoa(revoked),
+	})
+
+	return &pb.RevokeNodeTokensResponse{
+		RevokedFamilies: int32(revoked),
+	}, nil
+}
+
+// ListSessions lists the live sessions of a node or of the whole fleet
+func (a *adminServer) ListSessions(ctx context.Context, req *pb.ListSessionsRequest) (*pb.ListSessionsResponse, error) {
+	sessions := a.server.nodeManager.ListSessions(req.NodeId)
+
+	resp := &pb.ListSessionsResponse{
+		Sessions: make([]*pb.SessionInfo, 0, len(sessions)),
+	}
+	for _, session := range sessions {
+		resp.Sessions = append(resp.Sessions, &pb.SessionInfo{
+			SessionId:       session.ID,
+			NodeId:          session.NodeID,
+			CreatedAt:       session.CreatedAt.Unix(),
+			LastActivity:    session.LastActivity.Unix(),
+			ExpiresAt:       session.ExpiresAt.Unix(),
+			IdleExpiresAt:   session.LastActivity.Add(a.server.config.Sessions.IdleTimeout).Unix(),
+			StreamConnected: session.StreamConnected,
+		})
+	}
+
+	return resp, nil
+}
+
+// RevokeSession revokes a session and closes the stream bound to it
+func (a *adminServer) RevokeSession(ctx context.Context, req *pb.RevokeSessionRequest) (*pb.RevokeSessionResponse, error) {
+	reason := req.Reason
+	if reason == "" {
+		reason = "revoked by administrator"
+	}
+
+	closed, err := a.server.nodeManager.RevokeSession(req.NodeId, req.SessionId, reason)
+	if errors.Is(err, node.ErrSessionNotFound) {
+		return nil, errSessionNotFound.Err()
+	}
+	if err != nil {
+		return nil, internalError("Failed to revoke session", err,
+			zap.String("node_id", req.NodeId),
+			zap.String("session_id", req.SessionId),
+		)
+	}
+
+	a.server.audit(ctx, "session.revoke", req.NodeId, audit.OutcomeSuccess, map[string]string{
+		"session_id": req.SessionId,
+		"reason":     reason,
+	})
+
+	return &pb.RevokeSessionResponse{
+		StreamClosed: closed,
+	}, nil
+}
+
+// DecommissionNode permanently removes a node. Credentials are revoked first
+// so the node cannot act while it is being disconnected.
+func (a *adminServer) DecommissionNode(ctx context.Context, req *pb.DecommissionNodeRequest) (*pb.DecommissionNodeResponse, error) {
+	reason := req.Reason
+	if reason == "" {
+		reason = "decommissioned by administrator"
+	}
+
+	s := a.server
+	if !s.knowsNode(req.NodeId) {
+		return nil, errNodeNotFound.Err()
+	}
+
+	tombstone, err := s.authManager.Decommission(req.NodeId, reason)
+	if err != nil {
+		return nil, internalError("Failed to decommission node", err, zap.String("node_id", req.NodeId))
+	}
+
+	disconnected := s.nodeManager.DisconnectNode(req.NodeId, &pb.Disconnect{
+		Reason:           reason,
+		ReconnectAllowed: false,
+	}, 5*time.Second)
+
+	closed := s.nodeManager.RevokeNodeSessions(req.NodeId, reason)
+	purged := s.outboxManager.Purge(req.NodeId, nil)
+
+	hostname, _ := s.nodeManager.Hostname(req.NodeId)
+	s.metricsManager.RemoveNodeMetrics(req.NodeId, hostname)
+	s.identityManager.Forget(req.NodeId)
+
+	s.audit(ctx, "node.decommission", req.NodeId, audit.OutcomeSuccess, map[string]string{
+		"reason":               reason,
+		"revoked_families":     strconv.Itoa(tombstone.RevokedFamilies),
+		"revoked_certificates": strconv.Itoa(tombstone.RevokedCertificates),
+		"closed_sessions":      strconv.Itoa(closed),
+		"purged_commands":      strconv.Itoa(purged),
+	})
+	s.nodeManager.RemoveNode(req.NodeId)
+
+	return &pb.DecommissionNodeResponse{
+		DecommissionedAt:    tombstone.DecommissionedAt.Unix(),
+		RevokedFamilies:     int32(tombstone.RevokedFamilies),
+		RevokedCertificates: int32(tombstone.RevokedCertificates),
+		ClosedSessions:      int32(closed),
+		Disconnected:        disconnected,
+	}, nil
+}
+
+// knowsNode reports whether a node is registered, holds credentials or has a registration
+func (s *Server) knowsNode(nodeID string) bool {
+	if _, err := s.nodeManager.GetNode(nodeID); err == nil {
+		return true
+	}
+	if s.authManager.KnowsNode(nodeID) {
+		return true
+	}
+	_, err := s.registrationManager.Get(nodeID)
+	return err == nil
+}
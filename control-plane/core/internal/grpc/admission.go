@@ -0,0 +1,71 @@
+package lmgrpc
+
+import (
+	"context"
+	"time"
+
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/init/logger"
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/internal/admission"
+	pb "github.com/raphaelCamblong/Luminous-Mesh/control-plane/shared/proto"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/status"
+)
+
+// admitHandshake takes a handshake slot for a handshake method, waiting briefly
+// for one. release must be called once the caller is authenticated.
+func (s *Server) admitHandshake(ctx context.Context, method string) (release func(), err error) {
+	if !handshakeMethods[method] {
+		return func() {}, nil
+	}
+
+	s.publishAdmission()
+	free, decision := s.admission.AcquireHandshake(ctx)
+	s.publishAdmission()
+	if !decision.Allowed {
+		if ctx.Err() != nil {
+			return nil, status.FromContextError(ctx.Err()).Err()
+		}
+		return nil, s.refuseAdmission(method, decision)
+	}
+
+	return func() {
+		free()
+		s.publishAdmission()
+	}, nil
+}
+
+// admitStream draws a new stream from the stream rate
+func (s *Server) admitStream(method string) error {
+	if method != pb.NodeService_StreamConnection_FullMethodName {
+		return nil
+	}
+
+	decision := s.admission.AllowStream()
+	if !decision.Allowed {
+		return s.refuseAdmission(method, decision)
+	}
+	return nil
+}
+
+func (s *Server) refuseAdmission(method string, decision admission.Decision) error {
+	s.metricsManager.RecordAdmissionRejected(method, decision.Reason)
+	logger.L().Debug("Node call refused by admission control",
+		zap.String("method", method),
+		zap.String("reason", decision.Reason),
+		zap.Duration("retry_after", decision.RetryAfter),
+	)
+	return errOverloaded.RetryAfter(decision.RetryAfter)
+}
+
+// publishAdmission exports the handshakes in flight and waiting as metrics
+func (s *Server) publishAdmission() {
+	inflight, queued := s.admission.Stats()
+	s.metricsManager.UpdateAdmission(inflight, queued)
+}
+
+// reconnectWindow widens the reconnect delays of a drain so the connected
+// nodes come back no faster than admission control lets them in
+func (s *Server) reconnectWindow(minWait, maxWait time.Duration) time.Duration {
+	spread := s.admission.ReconnectSpread(s.nodeManager.ConnectedNodes())
+	return max(maxWait, minWait+spread)
+}
@@ -0,0 +1,114 @@
+package lmgrpc
+
+import (
+	"context"
+	"crypto/x509"
+	"time"
+
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/internal/audit"
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/internal/auth"
+	pb "github.com/raphaelCamblong/Luminous-Mesh/control-plane/shared/proto"
+	"google.golang.org/grpc/status"
+)
+
+// Audit records returned by one query at most
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+)
+
+// audit records an action taken by the caller of ctx
+func (s *Server) audit(ctx context.Context, action, target, outcome string, details map[string]string) {
+	s.auditLogger.Log(audit.Record{
+		Actor:       actorOf(ctx),
+		Action:      action,
+		Target:      target,
+		Outcome:     outcome,
+		PeerAddress: peerAddress(ctx),
+		Details:     details,
+	})
+}
+
+// auditDenied records a call refused before reaching its handler
+func (s *Server) auditDenied(ctx context.Context, method string, err error) {
+	s.audit(ctx, "rpc.authorize", method, audit.OutcomeFailure, map[string]string{
+		"code":   status.Code(err).String(),
+		"reason": status.Convert(err).Message(),
+	})
+}
+
+// auditCertificate records the issuance of a node certificate
+func (s *Server) auditCertificate(ctx context.Context, nodeID string, cert *x509.Certificate) {
+	s.audit(ctx, "certificate.issue", nodeID, audit.OutcomeSuccess, map[string]string{
+		"serial":    cert.SerialNumber.Text(16),
+		"not_after": cert.NotAfter.UTC().Format(time.RFC3339),
+	})
+}
+
+// actorOf names the authenticated caller of ctx
+func actorOf(ctx context.Context) string {
+	p, ok := auth.PrincipalFromContext(ctx)
+	if !ok {
+		return audit.ActorAnonymous
+	}
+
+	switch {
+	case p.Operator != nil:
+		return p.Operator.Subject
+	case p.Method == auth.AuthMethodAdmin:
+		return audit.ActorAdmin
+	case p.NodeID != "":
+		return "node:" + p.NodeID
+	case p.CertSerial != "":
+		return "certificate:" + p.CertSerial
+	}
+	return audit.ActorAnonymous
+}
+
+// ListAuditRecords queries the audit trail
+func (a *adminServer) ListAuditRecords(ctx context.Context, req *pb.ListAuditRecordsRequest) (*pb.ListAuditRecordsResponse, error) {
+	limit := int(req.Limit)
+	switch {
+	case limit <= 0:
+		limit = defaultAuditLimit
+	case limit > maxAuditLimit:
+		limit = maxAuditLimit
+	}
+
+	filter := audit.Filter{
+		Actor:  req.Actor,
+		Action: req.Action,
+		Target: req.Target,
+		Limit:  limit,
+	}
+	if req.Since > 0 {
+		filter.Since = time.Unix(req.Since, 0)
+	}
+	if req.Until > 0 {
+		filter.Until = time.Unix(req.Until, 0)
+	}
+
+	records, err := a.server.auditLogger.Query(filter)
+	if err != nil {
+		return nil, internalError("Failed to query audit records", err)
+	}
+
+	resp := &pb.ListAuditRecordsResponse{
+		Records: make([]*pb.AuditRecord, 0, len(records)),
+	}
+	for _, record := range records {
+		resp.Records = append(resp.Records, &pb.AuditRecord{
+			Seq:         record.Seq,
+			Time:        record.Time.Unix(),
+			Actor:       record.Actor,
+			Action:      record.Action,
+			Target:      record.Target,
+			Outcome:     record.Outcome,
+			PeerAddress: record.PeerAddress,
+			Details:     record.Details,
+			Hash:        record.Hash,
+		})
+	}
+
+	return resp, nil
+}
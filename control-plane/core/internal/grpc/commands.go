@@ -0,0 +1,121 @@
+package lmgrpc
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/internal/audit"
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/internal/outbox"
+	pb "github.com/raphaelCamblong/Luminous-Mesh/control-plane/shared/proto"
+	"go.uber.org/zap"
+)
+
+// SendCommand queues a command in the outbox of a node
+func (a *adminServer) SendCommand(ctx context.Context, req *pb.SendCommandRequest) (*pb.SendCommandResponse, error) {
+	if req.NodeId == "" {
+		return nil, errInvalidRequest.InvalidField("node_id", "is required")
+	}
+	// Disconnects and trust bundle updates only make sense on the stream they
+	// are issued for, the control plane sends them itself
+	switch req.Command.GetCommand().(type) {
+	case *pb.ControlPlaneCommand_ConfigUpdate, *pb.ControlPlaneCommand_HealthCheck:
+	default:
+		return nil, errInvalidRequest.InvalidField("command", "must be a config_update or a health_check")
+	}
+
+	s := a.server
+	if _, decommissioned := s.authManager.Tombstone(req.NodeId); decommissioned {
+		return nil, errTargetDecommissioned.Err()
+	}
+
+	cmd := req.Command
+	if cmd.CommandId == "" {
+		cmd.CommandId = uuid.New().String()
+	}
+
+	receipt, err := s.nodeManager.SendCommand(req.NodeId, cmd, time.Duration(req.TtlSeconds)*time.Second)
+	if err != nil {
+		s.audit(ctx, "command.enqueue", req.NodeId, audit.OutcomeFailure, map[string]string{
+			"command_id": cmd.CommandId,
+			"command":    outbox.CommandKind(cmd),
+			"reason":     err.Error(),
+		})
+		switch {
+		case errors.Is(err, outbox.ErrInvalidTTL):
+			return nil, errInvalidRequest.InvalidField("ttl_seconds", strings.TrimPrefix(err.Error(), outbox.ErrInvalidTTL.Error()+": "))
+		case errors.Is(err, outbox.ErrFull):
+			return nil, errOutboxFull.Err()
+		}
+		return nil, internalError("Failed to queue command", err, zap.String("node_id", req.NodeId))
+	}
+
+	entry := receipt.Entry
+	if !receipt.Duplicate {
+		details := map[string]string{
+			"command_id": entry.CommandID,
+			"command":    entry.Kind,
+			"priority":   entry.Priority.String(),
+			"expires_at": entry.ExpiresAt.UTC().Format(time.RFC3339),
+		}
+		if len(receipt.Superseded) > 0 {
+			details["superseded"] = strings.Join(receipt.Superseded, ",")
+		}
+		s.audit(ctx, "command.enqueue", req.NodeId, audit.OutcomeSuccess, details)
+	}
+
+	return &pb.SendCommandResponse{
+		Command:              pendingCommand(entry),
+		Duplicate:            receipt.Duplicate,
+		SupersededCommandIds: receipt.Superseded,
+	}, nil
+}
+
+// ListPendingCommands lists the commands a node has not acknowledged yet, in delivery order
+func (a *adminServer) ListPendingCommands(ctx context.Context, req *pb.ListPendingCommandsRequest) (*pb.ListPendingCommandsResponse, error) {
+	entries := a.server.outboxManager.Pending(req.NodeId, 0)
+
+	resp := &pb.ListPendingCommandsResponse{
+		Commands: make([]*pb.PendingCommand, 0, len(entries)),
+	}
+	for _, entry := range entries {
+		resp.Commands = append(resp.Commands, pendingCommand(entry))
+	}
+	return resp, nil
+}
+
+// PurgePendingCommands drops commands waiting for a node
+func (a *adminServer) PurgePendingCommands(ctx context.Context, req *pb.PurgePendingCommandsRequest) (*pb.PurgePendingCommandsResponse, error) {
+	purged := a.server.outboxManager.Purge(req.NodeId, req.CommandIds)
+
+	details := map[string]string{
+		"purged": strconv.Itoa(purged),
+	}
+	if len(req.CommandIds) > 0 {
+		details["command_ids"] = strings.Join(req.CommandIds, ",")
+	}
+	a.server.audit(ctx, "command.purge", req.NodeId, audit.OutcomeSuccess, details)
+
+	return &pb.PurgePendingCommandsResponse{
+		Purged: int32(purged),
+	}, nil
+}
+
+func pendingCommand(entry *outbox.Entry) *pb.PendingCommand {
+	info := &pb.PendingCommand{
+		CommandId:  entry.CommandID,
+		Sequence:   entry.Sequence,
+		Kind:       entry.Kind,
+		Priority:   entry.Priority,
+		EnqueuedAt: entry.EnqueuedAt.Unix(),
+		ExpiresAt:  entry.ExpiresAt.Unix(),
+		Attempts:   int32(entry.Attempts),
+	}
+	if !entry.LastAttempt.IsZero() {
+		info.LastAttemptAt = entry.LastAttempt.Unix()
+	}
+	return info
+}
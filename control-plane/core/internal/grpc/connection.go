@@ -0,0 +1,54 @@
+package lmgrpc
+
+import (
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/init/config"
+	pb "github.com/raphaelCamblong/Luminous-Mesh/control-plane/shared/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/keepalive"
+)
+
+// connectionOptions applies the connection settings to the gRPC server
+func connectionOptions(cfg *config.ConnectionConfig) []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.KeepaliveParams(keepalive.ServerParameters{
+			Time:                  cfg.KeepaliveTime,
+			Timeout:               cfg.KeepaliveTimeout,
+			MaxConnectionIdle:     cfg.MaxConnectionIdle,
+			MaxConnectionAge:      cfg.MaxConnectionAge,
+			MaxConnectionAgeGrace: cfg.MaxConnectionAgeGrace,
+		}),
+		grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+			MinTime:             cfg.MinPingInterval,
+			PermitWithoutStream: cfg.PermitWithoutStream,
+		}),
+		grpc.MaxRecvMsgSize(cfg.MaxRecvMessageSize),
+		grpc.MaxSendMsgSize(cfg.MaxSendMessageSize),
+		grpc.MaxConcurrentStreams(cfg.MaxConcurrentStreams),
+	}
+}
+
+// connectionSettings advertises the connection settings to nodes. Nodes
+// ping at the server keepalive time, which the enforcement policy allows.
+func connectionSettings(cfg *config.ConnectionConfig) *pb.ConnectionSettings {
+	return &pb.ConnectionSettings{
+		KeepaliveTimeMs:      cfg.KeepaliveTime.Milliseconds(),
+		KeepaliveTimeoutMs:   cfg.KeepaliveTimeout.Milliseconds(),
+		MinPingIntervalMs:    cfg.MinPingInterval.Milliseconds(),
+		PermitWithoutStream:  cfg.PermitWithoutStream,
+		MaxReconnectDelayMs:  cfg.MaxReconnectDelay.Milliseconds(),
+		MaxConnectionIdleMs:  cfg.MaxConnectionIdle.Milliseconds(),
+		MaxConnectionAgeMs:   cfg.MaxConnectionAge.Milliseconds(),
+		MaxRequestSize:       int32(cfg.MaxRecvMessageSize),
+		MaxResponseSize:      int32(cfg.MaxSendMessageSize),
+		MaxConcurrentStreams: cfg.MaxConcurrentStreams,
+	}
+}
+
+// legacyConnectionParams renders the settings older nodes read from the connection_params map
+func legacyConnectionParams(cfg *config.ConnectionConfig) map[string]string {
+	return map[string]string{
+		"max_reconnect_delay": cfg.MaxReconnectDelay.String(),
+		"keepalive_time":      cfg.KeepaliveTime.String(),
+		"keepalive_timeout":   cfg.KeepaliveTimeout.String(),
+	}
+}
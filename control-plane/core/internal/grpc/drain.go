@@ -0,0 +1,95 @@
+package lmgrpc
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/init/logger"
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/internal/audit"
+	pb "github.com/raphaelCamblong/Luminous-Mesh/control-plane/shared/proto"
+	"go.uber.org/zap"
+)
+
+// Longest drain an operator may ask for, the Drain call blocks meanwhile
+const maxDrainTimeout = 10 * time.Minute
+
+// drainingError refuses node sessions and streams while the control plane
+// drains, telling nodes to retry once the shortest reconnect wait passed
+func (s *Server) drainingError() error {
+	return errDraining.RetryAfter(s.config.Drain.MinReconnectWait)
+}
+
+// Drain refuses new node sessions and streams, tells the connected nodes to
+// reconnect after a random delay and closes their streams, waiting at most
+// timeout or the configured drain timeout when zero. The delays are spread
+// wide enough for the fleet to be admitted back without refusals.
+func (s *Server) Drain(reason string, timeout time.Duration) (notified, forced int) {
+	s.drainMu.Lock()
+	defer s.drainMu.Unlock()
+
+	cfg := &s.config.Drain
+	if timeout <= 0 {
+		timeout = cfg.Timeout
+	}
+
+	s.draining.Store(true)
+	maxWait := s.reconnectWindow(cfg.MinReconnectWait, cfg.MaxReconnectWait)
+	logger.L().Info("Draining node connections",
+		zap.String("reason", reason),
+		zap.Duration("timeout", timeout),
+		zap.Duration("max_reconnect_wait", maxWait),
+	)
+
+	notified, forced = s.nodeManager.Drain(reason, cfg.MinReconnectWait, maxWait, timeout)
+
+	logger.L().Info("Node connections drained",
+		zap.Int("notified", notified),
+		zap.Int("forced", forced),
+	)
+	return notified, forced
+}
+
+// Resume accepts node connections again and reports whether the server was draining
+func (s *Server) Resume() bool {
+	s.drainMu.Lock()
+	defer s.drainMu.Unlock()
+
+	return s.draining.Swap(false)
+}
+
+// Drain tells the connected nodes to reconnect later and refuses node connections until Resume
+func (a *adminServer) Drain(ctx context.Context, req *pb.DrainRequest) (*pb.DrainResponse, error) {
+	timeout := time.Duration(req.TimeoutSeconds) * time.Second
+
+	reason := req.Reason
+	if reason == "" {
+		reason = "control plane draining"
+	}
+
+	notified, forced := a.server.Drain(reason, timeout)
+
+	a.server.audit(ctx, "fleet.drain", "", audit.OutcomeSuccess, map[string]string{
+		"reason":   reason,
+		"notified": strconv.Itoa(notified),
+		"forced":   strconv.Itoa(forced),
+	})
+
+	return &pb.DrainResponse{
+		Notified: int32(notified),
+		Forced:   int32(forced),
+	}, nil
+}
+
+// Resume accepts node connections again after a Drain
+func (a *adminServer) Resume(ctx context.Context, req *pb.ResumeRequest) (*pb.ResumeResponse, error) {
+	wasDraining := a.server.Resume()
+
+	a.server.audit(ctx, "fleet.resume", "", audit.OutcomeSuccess, map[string]string{
+		"was_draining": strconv.FormatBool(wasDraining),
+	})
+
+	return &pb.ResumeResponse{
+		WasDraining: wasDraining,
+	}, nil
+}
@@ -0,0 +1,145 @@
+package lmgrpc
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/init/logger"
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/internal/validation"
+	pb "github.com/raphaelCamblong/Luminous-Mesh/control-plane/shared/proto"
+	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// errorDomain is the ErrorInfo domain of the control plane errors
+const errorDomain = "luminousmesh.io"
+
+// apiError is an entry of the error catalog: the status code and public
+// message returned for an error reason. Internal causes are logged, never sent.
+type apiError struct {
+	code    codes.Code
+	reason  pb.ErrorReason
+	message string
+}
+
+// The error catalog
+var (
+	errInternal = apiError{codes.Internal, pb.ErrorReason_INTERNAL, "internal error"}
+
+	errCredentialsMissing        = apiError{codes.Unauthenticated, pb.ErrorReason_CREDENTIALS_MISSING, "missing credentials"}
+	errBootstrapTokenInvalid     = apiError{codes.Unauthenticated, pb.ErrorReason_BOOTSTRAP_TOKEN_INVALID, "invalid bootstrap token"}
+	errAuthTokenInvalid          = apiError{codes.Unauthenticated, pb.ErrorReason_AUTH_TOKEN_INVALID, "invalid authorization token"}
+	errAuthTokenUnbound          = apiError{codes.Unauthenticated, pb.ErrorReason_AUTH_TOKEN_UNBOUND, "authorization token is not bound to a client certificate"}
+	errAuthTokenCertMismatch     = apiError{codes.Unauthenticated, pb.ErrorReason_AUTH_TOKEN_CERTIFICATE_MISMATCH, "authorization token is bound to a different client certificate"}
+	errTokenReused               = apiError{codes.Unauthenticated, pb.ErrorReason_TOKEN_REUSED, "rotated token reused, token family revoked"}
+	errCertificateRequired       = apiError{codes.Unauthenticated, pb.ErrorReason_CERTIFICATE_REQUIRED, "client certificate required"}
+	errCertificateInvalid        = apiError{codes.Unauthenticated, pb.ErrorReason_CERTIFICATE_INVALID, "invalid certificate"}
+	errCertificateRevoked        = apiError{codes.Unauthenticated, pb.ErrorReason_CERTIFICATE_REVOKED, "client certificate revoked"}
+	errCertificateMismatch       = apiError{codes.Unauthenticated, pb.ErrorReason_CERTIFICATE_MISMATCH, "certificate does not match the TLS client certificate"}
+	errCSRInvalid                = apiError{codes.InvalidArgument, pb.ErrorReason_CSR_INVALID, "invalid certificate signing request"}
+	errNodeDecommissioned        = apiError{codes.Unauthenticated, pb.ErrorReason_NODE_DECOMMISSIONED, "node decommissioned"}
+	errNodeNotFound              = apiError{codes.NotFound, pb.ErrorReason_NODE_NOT_FOUND, "unknown node"}
+	errTargetDecommissioned      = apiError{codes.FailedPrecondition, pb.ErrorReason_NODE_DECOMMISSIONED, "node is decommissioned"}
+	errNodeMismatch              = apiError{codes.PermissionDenied, pb.ErrorReason_NODE_MISMATCH, "node_id does not match the authenticated node"}
+	errSessionRequired           = apiError{codes.Unauthenticated, pb.ErrorReason_SESSION_REQUIRED, "session required"}
+	errSessionInvalid            = apiError{codes.Unauthenticated, pb.ErrorReason_SESSION_INVALID, "invalid session"}
+	errSessionExpired            = apiError{codes.Unauthenticated, pb.ErrorReason_SESSION_EXPIRED, "session expired"}
+	errSessionRevoked            = apiError{codes.Unauthenticated, pb.ErrorReason_SESSION_REVOKED, "session revoked"}
+	errSessionMismatch           = apiError{codes.PermissionDenied, pb.ErrorReason_SESSION_MISMATCH, "status update for another session"}
+	errSessionNotFound           = apiError{codes.NotFound, pb.ErrorReason_SESSION_NOT_FOUND, "session not found"}
+	errRegistrationNotFound      = apiError{codes.NotFound, pb.ErrorReason_REGISTRATION_NOT_FOUND, "unknown registration"}
+	errRegistrationInvalid       = apiError{codes.FailedPrecondition, pb.ErrorReason_REGISTRATION_INVALID, "registration is no longer valid"}
+	errRegistrationNotPending    = apiError{codes.FailedPrecondition, pb.ErrorReason_REGISTRATION_NOT_PENDING, "registration already decided"}
+	errRegistrationDeciding      = apiError{codes.Aborted, pb.ErrorReason_REGISTRATION_DECIDING, "registration is being decided, retry shortly"}
+	errRateLimited               = apiError{codes.ResourceExhausted, pb.ErrorReason_RATE_LIMITED, "too many requests"}
+	errLockedOut                 = apiError{codes.ResourceExhausted, pb.ErrorReason_LOCKED_OUT, "too many failed attempts"}
+	errOverloaded                = apiError{codes.Unavailable, pb.ErrorReason_OVERLOADED, "control plane is busy"}
+	errDraining                  = apiError{codes.Unavailable, pb.ErrorReason_DRAINING, "control plane is draining"}
+	errPermissionDenied          = apiError{codes.PermissionDenied, pb.ErrorReason_PERMISSION_DENIED, "permission denied"}
+	errOperatorCredentialInvalid = apiError{codes.Unauthenticated, pb.ErrorReason_OPERATOR_CREDENTIAL_INVALID, "invalid operator credential"}
+	errInvalidRequest            = apiError{codes.InvalidArgument, pb.ErrorReason_INVALID_REQUEST, "invalid request"}
+	errUserExists                = apiError{codes.AlreadyExists, pb.ErrorReason_USER_EXISTS, "user already exists"}
+	errUserNotFound              = apiError{codes.NotFound, pb.ErrorReason_USER_NOT_FOUND, "user not found"}
+	errAPIKeyNotFound            = apiError{codes.NotFound, pb.ErrorReason_API_KEY_NOT_FOUND, "api key not found"}
+	errOutboxFull                = apiError{codes.ResourceExhausted, pb.ErrorReason_OUTBOX_FULL, "too many commands pending for the node"}
+)
+
+// Err returns the status error of e
+func (e apiError) Err() error {
+	return e.status(e.message, nil).Err()
+}
+
+// Errorf returns the status error of e with a message of its own
+func (e apiError) Errorf(format string, args ...interface{}) error {
+	return e.status(fmt.Sprintf(format, args...), nil).Err()
+}
+
+// With returns the status error of e carrying metadata in its ErrorInfo and
+// the given details
+func (e apiError) With(metadata map[string]string, details ...protoadapt.MessageV1) error {
+	return e.status(e.message, metadata, details...).Err()
+}
+
+// Detailed returns the status error of e with a message of its own, metadata
+// and details
+func (e apiError) Detailed(message string, metadata map[string]string, details ...protoadapt.MessageV1) error {
+	return e.status(message, metadata, details...).Err()
+}
+
+// RetryAfter returns the status error of e telling the caller when to retry,
+// rounded up to whole seconds
+func (e apiError) RetryAfter(retry time.Duration) error {
+	retry = time.Duration(math.Ceil(retry.Seconds())) * time.Second
+	return e.status(fmt.Sprintf("%s, retry in %s", e.message, retry), nil,
+		&errdetails.RetryInfo{RetryDelay: durationpb.New(retry)},
+	).Err()
+}
+
+// InvalidField returns the status error of e with a violation of field
+func (e apiError) InvalidField(field, description string) error {
+	return e.Violations([]validation.Violation{{Field: field, Description: description}})
+}
+
+// Violations returns the status error of e listing the violations of the
+// request fields, the first one in the message
+func (e apiError) Violations(violations []validation.Violation) error {
+	request := &errdetails.BadRequest{}
+	for _, v := range violations {
+		request.FieldViolations = append(request.FieldViolations, &errdetails.BadRequest_FieldViolation{
+			Field:       v.Field,
+			Description: v.Description,
+		})
+	}
+
+	message := e.message
+	if len(violations) > 0 {
+		message = fmt.Sprintf("%s: %s %s", e.message, violations[0].Field, violations[0].Description)
+	}
+	return e.status(message, nil, request).Err()
+}
+
+func (e apiError) status(message string, metadata map[string]string, details ...protoadapt.MessageV1) *status.Status {
+	st := status.New(e.code, message)
+
+	info := &errdetails.ErrorInfo{
+		Reason:   e.reason.String(),
+		Domain:   errorDomain,
+		Metadata: metadata,
+	}
+	detailed, err := st.WithDetails(append([]protoadapt.MessageV1{info}, details...)...)
+	if err != nil {
+		return st
+	}
+	return detailed
+}
+
+// internalError logs the cause of a failure and returns the opaque INTERNAL status
+func internalError(msg string, err error, fields ...zap.Field) error {
+	logger.L().Error(msg, append(fields, zap.Error(err))...)
+	return errInternal.Err()
+}
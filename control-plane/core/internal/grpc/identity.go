@@ -0,0 +1,76 @@
+package lmgrpc
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/init/logger"
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/internal/audit"
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/internal/identity"
+	pb "github.com/raphaelCamblong/Luminous-Mesh/control-plane/shared/proto"
+	"go.uber.org/zap"
+)
+
+// resolveIdentity returns the node ID a registration matches, or a new one.
+// Decommissioned nodes are never re-attached.
+func (s *Server) resolveIdentity(keys []identity.Key) (string, string, bool) {
+	nodeID, strategy, ok := s.identityManager.Resolve(keys)
+	if ok {
+		if _, decommissioned := s.authManager.Tombstone(nodeID); !decommissioned {
+			return nodeID, strategy, true
+		}
+		s.identityManager.Forget(nodeID)
+	}
+	return s.nodeManager.GenerateNodeID(), "", false
+}
+
+// reattachNode revokes the credentials of the previous incarnation of a
+// re-registering node, but the certificate just issued to it, and closes its
+// sessions
+func (s *Server) reattachNode(ctx context.Context, nodeID, strategy string, issued *x509.Certificate) error {
+	const reason = "node re-registered"
+
+	tokens, err := s.authManager.RevokeNodeTokens(nodeID, reason)
+	if err != nil {
+		return fmt.Errorf("failed to revoke node tokens: %w", err)
+	}
+
+	certificates, err := s.authManager.RevokeNodeCertificates(nodeID, reason, issued)
+	if err != nil {
+		return fmt.Errorf("failed to revoke node certificates: %w", err)
+	}
+
+	s.nodeManager.DisconnectNode(nodeID, &pb.Disconnect{
+		Reason:           reason,
+		ReconnectAllowed: false,
+	}, 5*time.Second)
+	sessions := s.nodeManager.RevokeNodeSessions(nodeID, reason)
+
+	logger.L().Info("Node re-attached to its previous identity",
+		zap.String("node_id", nodeID),
+		zap.String("matched_by", strategy),
+		zap.Int("revoked_families", tokens),
+		zap.Int("revoked_certificates", certificates),
+		zap.Int("closed_sessions", sessions),
+	)
+	s.audit(ctx, "node.reattach", nodeID, audit.OutcomeSuccess, map[string]string{
+		"matched_by":           strategy,
+		"revoked_families":     strconv.Itoa(tokens),
+		"revoked_certificates": strconv.Itoa(certificates),
+	})
+	return nil
+}
+
+// recordIdentity remembers the identity keys of an admitted node. Failing to
+// do so only costs identity continuity on the next re-registration.
+func (s *Server) recordIdentity(nodeID string, keys []identity.Key) {
+	if err := s.identityManager.Record(nodeID, keys); err != nil {
+		logger.L().Warn("Failed to record node identity",
+			zap.String("node_id", nodeID),
+			zap.Error(err),
+		)
+	}
+}
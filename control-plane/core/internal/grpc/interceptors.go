@@ -2,26 +2,44 @@ package lmgrpc
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/init/logger"
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/internal/auth"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
-	"google.golang.org/grpc/status"
 )
 
 func (s *Server) unaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
 	start := time.Now()
 
-	if info.FullMethod != "/luminousmesh.NodeService/RegisterNode" {
-		if err := s.authenticate(ctx); err != nil {
-			return nil, err
-		}
+	limits, err := s.checkRateLimits(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+
+	release, err := s.admitHandshake(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	authorized, err := s.authorize(ctx, info.FullMethod)
+	if err != nil {
+		s.auditDenied(authorized, info.FullMethod, err)
+		s.recordCallOutcome(authorized, info.FullMethod, limits, err)
+		return nil, err
+	}
+
+	if err := s.validate(req); err != nil {
+		s.recordCallOutcome(authorized, info.FullMethod, limits, err)
+		return nil, err
 	}
 
-	resp, err := handler(ctx, req)
+	resp, err := handler(authorized, req)
+	s.recordCallOutcome(authorized, info.FullMethod, limits, err)
 
 	logger.L().Info("Unary RPC",
 		zap.String("method", info.FullMethod),
@@ -32,20 +50,61 @@ func (s *Server) unaryInterceptor(ctx context.Context, req interface{}, info *gr
 	return resp, err
 }
 
+// recoveryUnaryInterceptor turns a panic of the call into an INTERNAL error
+func recoveryUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = recovered(info.FullMethod, r)
+		}
+	}()
+	return handler(ctx, req)
+}
+
+// recoveryStreamInterceptor turns a panic of the stream handler into an INTERNAL error
+func recoveryStreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = recovered(info.FullMethod, r)
+		}
+	}()
+	return handler(srv, ss)
+}
+
+// recovered logs a recovered panic along with its stack
+func recovered(method string, r interface{}) error {
+	logger.L().Error("RPC handler panicked",
+		zap.String("method", method),
+		zap.Any("panic", r),
+		zap.Stack("stack"),
+	)
+	return errInternal.Err()
+}
+
 // streamInterceptor handles authentication and logging for streaming RPC calls
 func (s *Server) streamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
 	start := time.Now()
 
+	if err := s.admitStream(info.FullMethod); err != nil {
+		return err
+	}
+	release, err := s.admitHandshake(ss.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+
 	// Authenticate stream
-	if err := s.authenticate(ss.Context()); err != nil {
+	ctx, err := s.authorize(ss.Context(), info.FullMethod)
+	release()
+	if err != nil {
+		s.auditDenied(ctx, info.FullMethod, err)
 		return err
 	}
 
 	// Wrap stream to intercept messages
-	wrapped := newWrappedStream(ss, info.FullMethod)
+	wrapped := newWrappedStream(ss, ctx, info.FullMethod, s.validate)
 
 	// Handle stream
-	err := handler(srv, wrapped)
+	err = handler(srv, wrapped)
 
 	// Log stream completion
 	logger.L().Info("Stream RPC completed",
@@ -57,49 +116,107 @@ func (s *Server) streamInterceptor(srv interface{}, ss grpc.ServerStream, info *
 	return err
 }
 
-// authenticate validates the authentication token from the context
-func (s *Server) authenticate(ctx context.Context) error {
+// authenticate validates the node token from the context and builds the principal
+func (s *Server) authenticate(ctx context.Context) (*auth.Principal, error) {
 	md, ok := metadata.FromIncomingContext(ctx)
 	if !ok {
-		return status.Error(codes.Unauthenticated, "missing metadata")
+		return nil, errCredentialsMissing.Errorf("missing metadata")
 	}
 
 	tokens := md.Get("authorization")
 	if len(tokens) == 0 {
-		return status.Error(codes.Unauthenticated, "missing authorization token")
+		return nil, errCredentialsMissing.Errorf("missing authorization token")
 	}
 
 	nodeID := md.Get("node-id")
 	if len(nodeID) == 0 {
-		return status.Error(codes.Unauthenticated, "missing node ID")
+		return nil, errCredentialsMissing.Errorf("missing node ID")
 	}
 
-	if err := s.authManager.ValidateAuthToken(nodeID[0], tokens[0]); err != nil {
-		return status.Error(codes.Unauthenticated, "invalid authorization token")
+	peer := peerCertificate(ctx)
+	claims, err := s.authManager.ValidateAuthToken(nodeID[0], tokens[0], peer)
+	switch {
+	case err == nil:
+	case errors.Is(err, auth.ErrCertificateMismatch):
+		logger.L().Warn("Token presented with a foreign certificate", zap.String("node_id", nodeID[0]))
+		return nil, errAuthTokenCertMismatch.Err()
+	case errors.Is(err, auth.ErrNodeDecommissioned):
+		return nil, s.decommissionedError(nodeID[0])
+	case errors.Is(err, auth.ErrCertificateRevoked):
+		return nil, errCertificateRevoked.Err()
+	case errors.Is(err, auth.ErrTokenUnbound):
+		return nil, errAuthTokenUnbound.Err()
+	case errors.Is(err, auth.ErrTokenReused):
+		return nil, errTokenReused.Err()
+	default:
+		return nil, errAuthTokenInvalid.Err()
 	}
 
-	return nil
+	principal := &auth.Principal{
+		Method:      auth.AuthMethodToken,
+		NodeID:      nodeID[0],
+		Claims:      claims,
+		Certificate: peer,
+	}
+	if peer != nil {
+		principal.CertSerial = peer.SerialNumber.Text(16)
+	}
+
+	if sessionID := md.Get("session-id"); len(sessionID) > 0 {
+		if err := s.nodeManager.ValidateSession(principal.NodeID, sessionID[0]); err != nil {
+			return nil, errSessionInvalid.Err()
+		}
+		principal.SessionID = sessionID[0]
+	}
+
+	return principal, nil
+}
+
+// authenticateCertificate builds the principal of a caller presenting only its
+// client certificate. Revocation is left to the handler, which can tell
+// decommissioned nodes why they are rejected.
+func (s *Server) authenticateCertificate(ctx context.Context) (*auth.Principal, error) {
+	peer := peerCertificate(ctx)
+	if peer == nil {
+		return nil, errCertificateRequired.Err()
+	}
+
+	return &auth.Principal{
+		Method:      auth.AuthMethodCertificate,
+		Certificate: peer,
+		CertSerial:  peer.SerialNumber.Text(16),
+	}, nil
 }
 
 // wrappedStream wraps grpc.ServerStream to provide message interception
+// and the authenticated context
 type wrappedStream struct {
 	grpc.ServerStream
+	ctx    context.Context
 	method string
+	// validate checks every received message
+	validate func(interface{}) error
 }
 
-func newWrappedStream(s grpc.ServerStream, method string) *wrappedStream {
+func newWrappedStream(s grpc.ServerStream, ctx context.Context, method string, validate func(interface{}) error) *wrappedStream {
 	return &wrappedStream{
 		ServerStream: s,
+		ctx:          ctx,
 		method:       method,
+		validate:     validate,
 	}
 }
 
+func (w *wrappedStream) Context() context.Context {
+	return w.ctx
+}
+
 func (w *wrappedStream) RecvMsg(m interface{}) error {
 	err := w.ServerStream.RecvMsg(m)
 	if err != nil {
 		return err
 	}
-	return nil
+	return w.validate(m)
 }
 
 func (w *wrappedStream) SendMsg(m interface{}) error {
@@ -0,0 +1,141 @@
+package lmgrpc
+
+import (
+	"context"
+
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/internal/access"
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/internal/auth"
+	pb "github.com/raphaelCamblong/Luminous-Mesh/control-plane/shared/proto"
+)
+
+// authPolicy is the authentication an RPC requires before its handler runs
+type authPolicy int
+
+const (
+	// policyDeny rejects the call; methods missing from the table get it
+	policyDeny authPolicy = iota
+	// policyBootstrap methods carry their credential in the request: a
+	// bootstrap token, a registration secret or an operator password
+	policyBootstrap
+	// policyCertificate methods require a verified client certificate, the
+	// handler checks the token carried in the request
+	policyCertificate
+	// policyNodeToken methods require a certificate-bound node token
+	policyNodeToken
+	// policyAdmin methods require an operator credential holding the
+	// permission declared in methodPermissions
+	policyAdmin
+)
+
+// methodPolicies declares the authentication of every RPC
+var methodPolicies = map[string]authPolicy{
+	pb.NodeService_RegisterNode_FullMethodName:          policyBootstrap,
+	pb.NodeService_GetRegistrationStatus_FullMethodName: policyBootstrap,
+	pb.NodeService_Authenticate_FullMethodName:          policyCertificate,
+	pb.NodeService_StreamConnection_FullMethodName:      policyNodeToken,
+	pb.NodeService_RotateToken_FullMethodName:           policyNodeToken,
+	pb.NodeService_RenewCertificate_FullMethodName:      policyNodeToken,
+	pb.NodeService_GetTrustBundle_FullMethodName:        policyNodeToken,
+
+	pb.AdminService_RevokeNodeTokens_FullMethodName:     policyAdmin,
+	pb.AdminService_ListSessions_FullMethodName:         policyAdmin,
+	pb.AdminService_RevokeSession_FullMethodName:        policyAdmin,
+	pb.AdminService_DecommissionNode_FullMethodName:     policyAdmin,
+	pb.AdminService_SendCommand_FullMethodName:          policyAdmin,
+	pb.AdminService_ListPendingCommands_FullMethodName:  policyAdmin,
+	pb.AdminService_PurgePendingCommands_FullMethodName: policyAdmin,
+	pb.AdminService_Drain_FullMethodName:                policyAdmin,
+	pb.AdminService_Resume_FullMethodName:               policyAdmin,
+	pb.AdminService_ListRegistrations_FullMethodName:    policyAdmin,
+	pb.AdminService_ApproveRegistration_FullMethodName:  policyAdmin,
+	pb.AdminService_RejectRegistration_FullMethodName:   policyAdmin,
+	pb.AdminService_ListAuditRecords_FullMethodName:     policyAdmin,
+	pb.AdminService_Login_FullMethodName:                policyBootstrap,
+	pb.AdminService_CreateUser_FullMethodName:           policyAdmin,
+	pb.AdminService_ListUsers_FullMethodName:            policyAdmin,
+	pb.AdminService_DeleteUser_FullMethodName:           policyAdmin,
+	pb.AdminService_CreateAPIKey_FullMethodName:         policyAdmin,
+	pb.AdminService_ListAPIKeys_FullMethodName:          policyAdmin,
+	pb.AdminService_RevokeAPIKey_FullMethodName:         policyAdmin,
+	pb.AdminService_ListRoles_FullMethodName:            policyAdmin,
+}
+
+// methodPermissions declares the permission every admin RPC requires
+var methodPermissions = map[string]string{
+	pb.AdminService_RevokeNodeTokens_FullMethodName:     access.PermTokensRevoke,
+	pb.AdminService_ListSessions_FullMethodName:         access.PermNodesRead,
+	pb.AdminService_RevokeSession_FullMethodName:        access.PermSessionsRevoke,
+	pb.AdminService_DecommissionNode_FullMethodName:     access.PermNodesDecommission,
+	pb.AdminService_SendCommand_FullMethodName:          access.PermCommandsSend,
+	pb.AdminService_ListPendingCommands_FullMethodName:  access.PermNodesRead,
+	pb.AdminService_PurgePendingCommands_FullMethodName: access.PermCommandsSend,
+	pb.AdminService_Drain_FullMethodName:                access.PermFleetDrain,
+	pb.AdminService_Resume_FullMethodName:               access.PermFleetDrain,
+	pb.AdminService_ListRegistrations_FullMethodName:    access.PermNodesRead,
+	pb.AdminService_ApproveRegistration_FullMethodName:  access.PermNodesApprove,
+	pb.AdminService_RejectRegistration_FullMethodName:   access.PermNodesApprove,
+	pb.AdminService_ListAuditRecords_FullMethodName:     access.PermAuditRead,
+	pb.AdminService_CreateUser_FullMethodName:           access.PermUsersManage,
+	pb.AdminService_ListUsers_FullMethodName:            access.PermUsersManage,
+	pb.AdminService_DeleteUser_FullMethodName:           access.PermUsersManage,
+	pb.AdminService_CreateAPIKey_FullMethodName:         access.PermTokensCreate,
+	pb.AdminService_ListAPIKeys_FullMethodName:          access.PermTokensRead,
+	pb.AdminService_RevokeAPIKey_FullMethodName:         access.PermTokensRevoke,
+	pb.AdminService_ListRoles_FullMethodName:            access.PermNodesRead,
+}
+
+// rateLimitedMethods are throttled per peer address and client certificate, with
+// lockouts after repeated failures, since they run before a node is authenticated
+var rateLimitedMethods = map[string]bool{
+	pb.NodeService_RegisterNode_FullMethodName:          true,
+	pb.NodeService_GetRegistrationStatus_FullMethodName: true,
+	pb.NodeService_Authenticate_FullMethodName:          true,
+	pb.AdminService_Login_FullMethodName:                true,
+}
+
+// handshakeMethods authenticate node credentials, which costs token and
+// certificate validation; they are admitted at a bounded concurrency
+var handshakeMethods = map[string]bool{
+	pb.NodeService_Authenticate_FullMethodName:     true,
+	pb.NodeService_StreamConnection_FullMethodName: true,
+}
+
+// authorize applies the policy of method and returns the context carrying the
+// principal. A refused caller that was identified is still attached, so the
+// denial is audited against it.
+func (s *Server) authorize(ctx context.Context, method string) (context.Context, error) {
+	var principal *auth.Principal
+	var err error
+
+	switch methodPolicies[method] {
+	case policyBootstrap:
+		return ctx, nil
+	case policyCertificate:
+		principal, err = s.authenticateCertificate(ctx)
+	case policyNodeToken:
+		principal, err = s.authenticate(ctx)
+	case policyAdmin:
+		principal, err = s.authenticateAdmin(ctx, method)
+	default:
+		return ctx, errPermissionDenied.Errorf("no authentication policy for method")
+	}
+	if principal != nil {
+		ctx = auth.NewContext(ctx, principal)
+	}
+	return ctx, err
+}
+
+// principalFor returns the principal of the call, checking that a node ID
+// given in the request, if any, is the authenticated one
+func principalFor(ctx context.Context, nodeID string) (*auth.Principal, error) {
+	p, ok := auth.PrincipalFromContext(ctx)
+	if !ok {
+		return nil, errCredentialsMissing.Errorf("unauthenticated call")
+	}
+
+	if nodeID != "" && nodeID != p.NodeID {
+		return nil, errNodeMismatch.Err()
+	}
+
+	return p, nil
+}
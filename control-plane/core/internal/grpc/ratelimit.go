@@ -0,0 +1,141 @@
+package lmgrpc
+
+import (
+	"context"
+	"net"
+
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/init/logger"
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/internal/audit"
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/internal/ratelimit"
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/pkg/certs"
+	pb "github.com/raphaelCamblong/Luminous-Mesh/control-plane/shared/proto"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// limitKey is a bucket a rate limited call draws from
+type limitKey struct {
+	scope string
+	key   string
+}
+
+// checkRateLimits draws a call from the buckets of its peer address and of
+// its verified client certificate. The node ID named in the request is never
+// used, so nobody can lock a node out by failing calls on its behalf. It
+// returns the buckets drawn from, to record the outcome.
+func (s *Server) checkRateLimits(ctx context.Context, method string) ([]limitKey, error) {
+	if !rateLimitedMethods[method] {
+		return nil, nil
+	}
+
+	keys := []limitKey{{ratelimit.ScopePeer, peerAddress(ctx)}}
+	if cert := peerCertificate(ctx); cert != nil {
+		keys = append(keys, limitKey{ratelimit.ScopeNode, certs.Thumbprint(cert)})
+	}
+
+	for _, k := range keys {
+		decision := s.limiter.Allow(k.scope, k.key)
+		if decision.Allowed {
+			continue
+		}
+
+		s.metricsManager.RecordRateLimited(method, k.scope, decision.Reason)
+		logger.L().Warn("Call rate limited",
+			zap.String("method", method),
+			zap.String("scope", k.scope),
+			zap.String("key", k.key),
+			zap.String("reason", decision.Reason),
+			zap.Duration("retry_after", decision.RetryAfter),
+		)
+		return nil, rateLimitError(decision)
+	}
+
+	return keys, nil
+}
+
+// recordCallOutcome feeds the result of a rate limited call to the lockout tracking
+func (s *Server) recordCallOutcome(ctx context.Context, method string, keys []limitKey, err error) {
+	if len(keys) == 0 {
+		return
+	}
+
+	if !callFailed(method, err) {
+		for _, k := range keys {
+			s.limiter.Success(k.scope, k.key)
+		}
+		return
+	}
+
+	s.metricsManager.RecordAuthFailure(method)
+	for _, k := range keys {
+		if lockout := s.limiter.Failure(k.scope, k.key); lockout > 0 {
+			s.metricsManager.RecordLockout(k.scope)
+			logger.L().Warn("Locked out after repeated failures",
+				zap.String("method", method),
+				zap.String("scope", k.scope),
+				zap.String("key", k.key),
+				zap.Duration("lockout", lockout),
+			)
+			s.audit(ctx, "ratelimit.lockout", k.key, audit.OutcomeSuccess, map[string]string{
+				"scope":    k.scope,
+				"method":   method,
+				"duration": lockout.String(),
+			})
+		}
+	}
+}
+
+// callFailed reports whether a call was refused for its credentials, or
+// polled a registration that does not exist. Malformed requests and pending,
+// rejected and expired registrations are answers, not failures.
+func callFailed(method string, err error) bool {
+	switch status.Code(err) {
+	case codes.Unauthenticated, codes.PermissionDenied:
+		return true
+	case codes.NotFound:
+		return method == pb.NodeService_GetRegistrationStatus_FullMethodName
+	}
+	return false
+}
+
+// rateLimitError builds the RESOURCE_EXHAUSTED status telling the client when to retry
+func rateLimitError(decision ratelimit.Decision) error {
+	if decision.Reason == ratelimit.ReasonLockout {
+		return errLockedOut.RetryAfter(decision.RetryAfter)
+	}
+	return errRateLimited.RetryAfter(decision.RetryAfter)
+}
+
+// peerAddress returns the IP address of the caller
+func peerAddress(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+// publishRateLimits exports the configured limits as metrics
+func (s *Server) publishRateLimits() {
+	cfg := s.config.RateLimit
+	if !cfg.Enabled {
+		return
+	}
+
+	s.metricsManager.UpdateRateLimitSetting(ratelimit.ScopePeer, "rate", cfg.PeerRate)
+	s.metricsManager.UpdateRateLimitSetting(ratelimit.ScopePeer, "burst", float64(cfg.PeerBurst))
+	s.metricsManager.UpdateRateLimitSetting(ratelimit.ScopeNode, "rate", cfg.NodeRate)
+	s.metricsManager.UpdateRateLimitSetting(ratelimit.ScopeNode, "burst", float64(cfg.NodeBurst))
+	for _, scope := range []string{ratelimit.ScopePeer, ratelimit.ScopeNode} {
+		s.metricsManager.UpdateRateLimitSetting(scope, "lockout_threshold", float64(cfg.LockoutThreshold))
+		s.metricsManager.UpdateRateLimitSetting(scope, "lockout_seconds", cfg.LockoutDuration.Seconds())
+		s.metricsManager.UpdateRateLimitSetting(scope, "lockout_max_seconds", cfg.LockoutMax.Seconds())
+	}
+}
@@ -0,0 +1,205 @@
+package lmgrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/internal/audit"
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/internal/registration"
+	pb "github.com/raphaelCamblong/Luminous-Mesh/control-plane/shared/proto"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// registrationPollInterval is advertised to nodes waiting for approval
+	registrationPollInterval = 10 * time.Second
+	// maxRegistrationWait caps how long GetRegistrationStatus holds a call
+	maxRegistrationWait = 60 * time.Second
+)
+
+var registrationStates = map[string]pb.RegisterNodeResponse_RegistrationState{
+	registration.StatePending:  pb.RegisterNodeResponse_PENDING_APPROVAL,
+	registration.StateApproved: pb.RegisterNodeResponse_ADMITTED,
+	registration.StateRejected: pb.RegisterNodeResponse_REJECTED,
+	registration.StateExpired:  pb.RegisterNodeResponse_EXPIRED,
+}
+
+// GetRegistrationStatus answers nodes polling for the decision on their registration.
+// Approved nodes receive their certificate and initial auth token on the first
+// poll after approval; later polls only learn the node was admitted.
+func (s *Server) GetRegistrationStatus(ctx context.Context, req *pb.RegistrationStatusRequest) (*pb.RegisterNodeResponse, error) {
+	wait := time.Duration(req.WaitSeconds) * time.Second
+	if wait > maxRegistrationWait {
+		wait = maxRegistrationWait
+	}
+
+	reg, err := s.registrationManager.Wait(ctx, req.RegistrationId, wait)
+	if err != nil {
+		s.audit(ctx, "registration.collect", "", audit.OutcomeFailure, map[string]string{
+			"reason": "unknown registration",
+		})
+		return nil, errRegistrationNotFound.Err()
+	}
+
+	if reg.State != registration.StateApproved || !reg.CollectedAt.IsZero() {
+		return s.registrationResponse(reg, req.RegistrationId)
+	}
+
+	// Credentials are delivered once; concurrent polls get the response above
+	collected, err := s.registrationManager.Collect(req.RegistrationId)
+	switch {
+	case errors.Is(err, registration.ErrCollected):
+		return s.registrationResponse(reg, req.RegistrationId)
+	case errors.Is(err, registration.ErrNotFound):
+		return nil, errRegistrationNotFound.Err()
+	case err != nil:
+		return nil, internalError("Failed to collect registration", err)
+	}
+
+	cert, err := s.authManager.ValidateCertificate(collected.Certificate)
+	if err != nil {
+		return nil, errRegistrationInvalid.Err()
+	}
+
+	s.audit(ctx, "registration.collect", collected.NodeID, audit.OutcomeSuccess, nil)
+	return s.admittedResponse(collected.NodeID, cert, collected.Certificate)
+}
+
+// registrationResponse tells a node its registration is not admitted (yet),
+// or that it was and its credentials were collected
+func (s *Server) registrationResponse(reg *registration.Registration, secret string) (*pb.RegisterNodeResponse, error) {
+	resp := &pb.RegisterNodeResponse{
+		Success:           false,
+		NodeId:            reg.NodeID,
+		RegistrationState: registrationStates[reg.State],
+	}
+
+	switch reg.State {
+	case registration.StatePending:
+		resp.Message = "Registration pending operator approval"
+		resp.RegistrationId = secret
+		resp.PollIntervalSeconds = int32(registrationPollInterval.Seconds())
+	case registration.StateApproved:
+		resp.Message = "Registration admitted, its credentials were already collected"
+	case registration.StateRejected:
+		resp.Message = fmt.Sprintf("Registration rejected: %s", reg.Reason)
+	case registration.StateExpired:
+		resp.Message = "Registration expired without a decision"
+	}
+
+	return resp, nil
+}
+
+// admitRegistration issues the certificate of an approved registration and registers the node
+func (s *Server) admitRegistration(ctx context.Context, reg *registration.Registration) ([]byte, error) {
+	info := &pb.NodeBasicInfo{}
+	if err := proto.Unmarshal(reg.BasicInfo, info); err != nil {
+		return nil, fmt.Errorf("failed to decode node info: %w", err)
+	}
+
+	cert, certPEM, err := s.authManager.SignCSR(reg.NodeID, reg.CSR)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign CSR: %w", err)
+	}
+
+	keys := s.identityManager.Keys(info, reg.CSR)
+	if nodeID, strategy, ok := s.resolveIdentity(keys); ok && nodeID == reg.NodeID {
+		if err := s.reattachNode(ctx, nodeID, strategy, cert); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.nodeManager.RegisterNode(reg.NodeID, info); err != nil {
+		return nil, fmt.Errorf("failed to register node: %w", err)
+	}
+	s.recordIdentity(reg.NodeID, keys)
+	s.auditCertificate(ctx, reg.NodeID, cert)
+
+	return certPEM, nil
+}
+
+func registrationInfo(reg *registration.Registration) *pb.RegistrationInfo {
+	info := &pb.RegistrationInfo{
+		NodeId:         reg.NodeID,
+		State:          registrationStates[reg.State],
+		Hostname:       reg.Hostname,
+		BootstrapToken: reg.BootstrapToken,
+		PublicKeyPin:   reg.PublicKeyPin,
+		CreatedAt:      reg.CreatedAt.Unix(),
+		ExpiresAt:      reg.ExpiresAt.Unix(),
+		Reason:         reg.Reason,
+	}
+	if !reg.DecidedAt.IsZero() {
+		info.DecidedAt = reg.DecidedAt.Unix()
+	}
+	return info
+}
+
+// ListRegistrations lists registrations awaiting approval or decided recently
+func (a *adminServer) ListRegistrations(ctx context.Context, req *pb.ListRegistrationsRequest) (*pb.ListRegistrationsResponse, error) {
+	state := ""
+	for name, value := range registrationStates {
+		if value == req.State {
+			state = name
+		}
+	}
+
+	regs := a.server.registrationManager.List(state)
+	resp := &pb.ListRegistrationsResponse{
+		Registrations: make([]*pb.RegistrationInfo, 0, len(regs)),
+	}
+	for _, reg := range regs {
+		resp.Registrations = append(resp.Registrations, registrationInfo(reg))
+	}
+
+	return resp, nil
+}
+
+// ApproveRegistration admits a pending node
+func (a *adminServer) ApproveRegistration(ctx context.Context, req *pb.DecideRegistrationRequest) (*pb.DecideRegistrationResponse, error) {
+	return a.decideRegistration(ctx, req, true)
+}
+
+// RejectRegistration refuses a pending node
+func (a *adminServer) RejectRegistration(ctx context.Context, req *pb.DecideRegistrationRequest) (*pb.DecideRegistrationResponse, error) {
+	return a.decideRegistration(ctx, req, false)
+}
+
+func (a *adminServer) decideRegistration(ctx context.Context, req *pb.DecideRegistrationRequest, approve bool) (*pb.DecideRegistrationResponse, error) {
+	issue := func(reg *registration.Registration) ([]byte, error) {
+		return a.server.admitRegistration(ctx, reg)
+	}
+
+	action := "registration.reject"
+	if approve {
+		action = "registration.approve"
+	}
+
+	reg, err := a.server.registrationManager.Decide(req.NodeId, approve, req.Reason, issue)
+	switch {
+	case errors.Is(err, registration.ErrNotFound):
+		return nil, errRegistrationNotFound.Err()
+	case errors.Is(err, registration.ErrNotPending):
+		return nil, errRegistrationNotPending.Detailed(fmt.Sprintf("registration already %s", reg.State),
+			map[string]string{"state": reg.State},
+		)
+	case errors.Is(err, registration.ErrDeciding):
+		return nil, errRegistrationDeciding.Err()
+	case err != nil:
+		a.server.audit(ctx, action, req.NodeId, audit.OutcomeFailure, map[string]string{
+			"reason": err.Error(),
+		})
+		return nil, internalError("Failed to decide registration", err, zap.String("node_id", req.NodeId))
+	}
+
+	a.server.audit(ctx, action, req.NodeId, audit.OutcomeSuccess, map[string]string{
+		"reason": req.Reason,
+	})
+
+	return &pb.DecideRegistrationResponse{
+		Registration: registrationInfo(reg),
+	}, nil
+}
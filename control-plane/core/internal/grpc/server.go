@@ -2,75 +2,183 @@ package lmgrpc
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
 	"net"
+	"strconv"
 	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/init/config"
 	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/init/logger"
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/internal/access"
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/internal/admission"
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/internal/audit"
 	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/internal/auth"
+	lmhttp "github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/internal/http"
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/internal/identity"
 	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/internal/metrics"
 	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/internal/node"
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/internal/oidc"
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/internal/outbox"
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/internal/ratelimit"
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/internal/registration"
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/internal/validation"
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/pkg/certs"
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/shared/interfaces"
 	pb "github.com/raphaelCamblong/Luminous-Mesh/control-plane/shared/proto"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 )
 
 // Server represents the gRPC server for the Luminous Mesh control plane
 type Server struct {
 	pb.UnimplementedNodeServiceServer
-	config         *config.CoreConfig
-	nodeManager    *node.Manager
-	authManager    *auth.Manager
-	metricsManager *metrics.Manager
-	mu             sync.RWMutex
-	grpcServer     *grpc.Server
+	config              *config.CoreConfig
+	nodeManager         *node.Manager
+	outboxManager       *outbox.Manager
+	authManager         *auth.Manager
+	registrationManager *registration.Manager
+	identityManager     *identity.Manager
+	accessManager       *access.Manager
+	oidcHandler         *oidc.Handler
+	auditLogger         *audit.Logger
+	limiter             *ratelimit.Limiter
+	admission           *admission.Controller
+	validator           *validation.Validator
+	metricsManager      *metrics.Manager
+	store               interfaces.DataStore
+	tls                 *serverTLS
+	mu                  sync.RWMutex
+	grpcServer          *grpc.Server
+	// draining refuses node sessions and streams; drainMu serializes drains
+	draining atomic.Bool
+	drainMu  sync.Mutex
+}
+
+// Options carry the runtime dependencies of the server.
+// PKI material left empty is loaded from the configuration files.
+type Options struct {
+	Store          interfaces.DataStore
+	CA             certs.CASigner
+	TLSCertificate *tls.Certificate
+	TLSCACerts     []*x509.Certificate
 }
 
 // NewServer creates a new instance of the control plane server
-func NewServer() (*Server, error) {
+func NewServer(opts Options) (*Server, error) {
 	cfg := config.Get()
-	nodeManager, err := node.NewManager()
+
+	var serverTLS *serverTLS
+	var err error
+	if opts.TLSCertificate != nil {
+		serverTLS, err = newServerTLS(*opts.TLSCertificate, opts.TLSCACerts)
+	} else {
+		serverTLS, err = loadServerTLS(&cfg.Core.TLS)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS material: %w", err)
+	}
+
+	ca := opts.CA
+	if ca == nil {
+		ca, err = auth.LoadCASigner(&cfg.Core.Auth)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load CA: %w", err)
+		}
+	}
+
+	auditLogger, err := audit.NewLogger(opts.Store)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create audit logger: %w", err)
+	}
+
+	outboxManager, err := outbox.NewManager(opts.Store, auditLogger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create outbox manager: %w", err)
+	}
+
+	nodeManager, err := node.NewManager(opts.Store, auditLogger, outboxManager)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create node manager: %w", err)
 	}
 
-	authManager, err := auth.NewManager()
+	authManager, err := auth.NewManager(ca, opts.Store)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create auth manager: %w", err)
 	}
 
+	registrationManager, err := registration.NewManager(opts.Store)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create registration manager: %w", err)
+	}
+
+	identityManager, err := identity.NewManager(opts.Store)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create identity manager: %w", err)
+	}
+
+	accessManager, err := access.NewManager(opts.Store)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create access manager: %w", err)
+	}
+
+	oidcHandler, err := oidc.NewHandler(accessManager, auditLogger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OIDC handler: %w", err)
+	}
+
 	metricsManager, err := metrics.NewManager()
 	if err != nil {
 		return nil, fmt.Errorf("failed to create metrics manager: %w", err)
 	}
 
-	return &Server{
-		config:         &cfg.Core,
-		nodeManager:    nodeManager,
-		authManager:    authManager,
-		metricsManager: metricsManager,
-	}, nil
+	validator, err := validation.NewValidator(messageRules)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request validator: %w", err)
+	}
+
+	s := &Server{
+		config:              &cfg.Core,
+		nodeManager:         nodeManager,
+		outboxManager:       outboxManager,
+		authManager:         authManager,
+		registrationManager: registrationManager,
+		identityManager:     identityManager,
+		accessManager:       accessManager,
+		oidcHandler:         oidcHandler,
+		auditLogger:         auditLogger,
+		limiter:             ratelimit.NewLimiter(),
+		admission:           admission.NewController(),
+		validator:           validator,
+		metricsManager:      metricsManager,
+		store:               opts.Store,
+		tls:                 serverTLS,
+	}
+	s.publishRateLimits()
+
+	return s, nil
 }
 
 // Start initializes and starts the gRPC server
 func (s *Server) Start(ctx context.Context) error {
-	// Load TLS credentials
-	creds, err := credentials.NewServerTLSFromFile(
-		s.config.TLS.CertFile,
-		s.config.TLS.KeyFile,
-	)
-	if err != nil {
-		return fmt.Errorf("failed to load TLS credentials: %w", err)
-	}
+	creds := credentials.NewTLS(s.TLSConfig())
 
-	// Create gRPC server with interceptors
-	s.grpcServer = grpc.NewServer(
+	// Create gRPC server with interceptors and the transport limits
+	opts := append([]grpc.ServerOption{
 		grpc.Creds(creds),
-		grpc.UnaryInterceptor(s.unaryInterceptor),
-		grpc.StreamInterceptor(s.streamInterceptor),
-	)
+		grpc.ChainUnaryInterceptor(recoveryUnaryInterceptor, s.unaryInterceptor),
+		grpc.ChainStreamInterceptor(recoveryStreamInterceptor, s.streamInterceptor),
+	}, connectionOptions(&s.config.Connection)...)
+	s.grpcServer = grpc.NewServer(opts...)
 
 	// Register services
 	s.registerGrpcServices()
@@ -94,145 +202,464 @@ func (s *Server) Start(ctx context.Context) error {
 	return nil
 }
 
+// Stop drains the node connections, then stops the server. RPCs still
+// running once the drain timeout passed again are cancelled.
 func (s *Server) Stop() {
 	logger.L().Info("Stopping gRPC server")
-	s.grpcServer.GracefulStop()
+
+	// Draining and the RPCs still running share a single timeout
+	deadline := time.Now().Add(s.config.Drain.Timeout)
+
+	reason := "control plane shutting down"
+	notified, forced := s.Drain(reason, s.config.Drain.Timeout)
+	s.auditLogger.Log(audit.Record{
+		Actor:   audit.ActorControlPlane,
+		Action:  "fleet.drain",
+		Outcome: audit.OutcomeSuccess,
+		Details: map[string]string{
+			"reason":   reason,
+			"notified": strconv.Itoa(notified),
+			"forced":   strconv.Itoa(forced),
+		},
+	})
+
+	stopped := make(chan struct{})
+	go func() {
+		s.grpcServer.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(time.Until(deadline)):
+		logger.L().Warn("RPCs still running after the drain timeout, closing them")
+		s.grpcServer.Stop()
+	}
+
+	s.authManager.Close()
+	if err := s.auditLogger.Close(); err != nil {
+		logger.L().Error("Failed to close audit log", zap.Error(err))
+	}
+}
+
+// TLSConfig returns the TLS configuration of the server certificate, shared with the HTTP server
+func (s *Server) TLSConfig() *tls.Config {
+	return s.tls.config(s.authManager.TrustBundle().Pool())
+}
+
+// RegisterHTTPHandlers exposes the token verification keys and the single sign-on endpoints over HTTP
+func (s *Server) RegisterHTTPHandlers(h *lmhttp.Server) {
+	h.Handle("/.well-known/jwks.json", lmhttp.JSONDocument(s.authManager.JWKS, 5*time.Minute))
+	if s.oidcHandler != nil {
+		s.oidcHandler.Register(h)
+	}
 }
 
 func (s *Server) registerGrpcServices() {
 	pb.RegisterNodeServiceServer(s.grpcServer, s)
+	pb.RegisterAdminServiceServer(s.grpcServer, &adminServer{server: s})
 }
 
 // RegisterNode handles node registration requests
 func (s *Server) RegisterNode(ctx context.Context, req *pb.RegisterNodeRequest) (*pb.RegisterNodeResponse, error) {
 	// Validate bootstrap token
-	if err := s.authManager.ValidateBootstrapToken(req.BootstrapToken); err != nil {
-		return &pb.RegisterNodeResponse{
-			Success: false,
-			Message: "Invalid bootstrap token",
-		}, nil
+	grant, err := s.authManager.ValidateBootstrapToken(req.BootstrapToken)
+	if err != nil {
+		s.audit(ctx, "node.register", "", audit.OutcomeFailure, map[string]string{
+			"reason":   "invalid bootstrap token",
+			"hostname": req.BasicInfo.GetHostname(),
+		})
+		return nil, errBootstrapTokenInvalid.Err()
 	}
 
-	// Process CSR and generate certificate
-	cert, err := s.authManager.SignCSR(req.Csr)
-	if err != nil {
-		return &pb.RegisterNodeResponse{
-			Success: false,
-			Message: fmt.Sprintf("Failed to sign CSR: %v", err),
-		}, nil
+	// Re-enrolling nodes get their previous identity back. Matches on data the
+	// node declares itself are only trusted once an operator approves them.
+	keys := s.identityManager.Keys(req.BasicInfo, req.Csr)
+	nodeID, strategy, reattach := s.resolveIdentity(keys)
+	requireApproval := grant.RequireApproval
+	if reattach && !identity.Proven(strategy) {
+		logger.L().Info("Re-registration matched by declared identity, holding it for approval",
+			zap.String("node_id", nodeID),
+			zap.String("matched_by", strategy),
+		)
+		requireApproval = true
+	}
+
+	// Hold the node until an operator approves it
+	if requireApproval {
+		basicInfo, err := proto.Marshal(req.BasicInfo)
+		if err != nil {
+			return nil, internalError("Failed to encode node info", err)
+		}
+
+		reg, secret, err := s.registrationManager.Submit(nodeID, grant.Description, req.BasicInfo.GetHostname(), basicInfo, req.Csr)
+		if errors.Is(err, registration.ErrDeciding) {
+			return nil, errRegistrationDeciding.Err()
+		}
+		if err != nil {
+			return nil, s.csrError(ctx, "node.register", nodeID, "Failed to queue registration", err)
+		}
+		s.audit(ctx, "node.register", nodeID, audit.OutcomePending, map[string]string{
+			"hostname":        req.BasicInfo.GetHostname(),
+			"bootstrap_token": grant.Description,
+			"public_key_pin":  reg.PublicKeyPin,
+		})
+		return s.registrationResponse(reg, secret)
 	}
 
-	// Generate node ID and initial auth token
-	nodeID := s.nodeManager.GenerateNodeID()
-	authToken, _, err := s.authManager.GenerateAuthToken(nodeID)
+	// Process CSR and generate certificate, before anything of a previous
+	// incarnation is touched
+	cert, certPEM, err := s.authManager.SignCSR(nodeID, req.Csr)
 	if err != nil {
-		return &pb.RegisterNodeResponse{
-			Success: false,
-			Message: "Failed to generate auth token",
-		}, nil
+		return nil, s.csrError(ctx, "node.register", nodeID, "Failed to sign CSR", err)
+	}
+
+	if reattach {
+		if err := s.reattachNode(ctx, nodeID, strategy, cert); err != nil {
+			return nil, internalError("Failed to revoke previous credentials", err, zap.String("node_id", nodeID))
+		}
 	}
 
 	// Register node
 	if err := s.nodeManager.RegisterNode(nodeID, req.BasicInfo); err != nil {
-		return &pb.RegisterNodeResponse{
-			Success: false,
-			Message: fmt.Sprintf("Failed to register node: %v", err),
-		}, nil
+		return nil, internalError("Failed to register node", err, zap.String("node_id", nodeID))
+	}
+
+	s.recordIdentity(nodeID, keys)
+	s.auditCertificate(ctx, nodeID, cert)
+	s.audit(ctx, "node.register", nodeID, audit.OutcomeSuccess, map[string]string{
+		"hostname":        req.BasicInfo.GetHostname(),
+		"bootstrap_token": grant.Description,
+	})
+
+	resp, err := s.admittedResponse(nodeID, cert, certPEM)
+	if err != nil {
+		return nil, err
+	}
+	if reattach {
+		resp.Message = "Node re-registered with its previous identity"
+	}
+	return resp, nil
+}
+
+// csrError audits a registration or renewal refused for its CSR. Invalid
+// requests are reported on the csr field, other failures stay internal.
+func (s *Server) csrError(ctx context.Context, action, nodeID, msg string, err error) error {
+	s.audit(ctx, action, nodeID, audit.OutcomeFailure, map[string]string{
+		"reason": err.Error(),
+	})
+	if errors.Is(err, certs.ErrInvalidCSR) {
+		return errCSRInvalid.InvalidField("csr", "must be a DER encoded PKCS#10 request signed with the node key")
+	}
+	return internalError(msg, err, zap.String("node_id", nodeID))
+}
+
+// rotationError reports a token that can no longer be rotated the way
+// authentication reports it; other failures are store failures and stay internal
+func rotationError(msg, nodeID string, err error) error {
+	switch {
+	case errors.Is(err, auth.ErrTokenReused):
+		return errTokenReused.Err()
+	case errors.Is(err, auth.ErrTokenRevoked), errors.Is(err, auth.ErrTokenUnknown):
+		return errAuthTokenInvalid.Err()
+	default:
+		return internalError(msg, err, zap.String("node_id", nodeID))
+	}
+}
+
+// decommissionedError tells a node it was decommissioned, when and why
+func (s *Server) decommissionedError(nodeID string) error {
+	tombstone, ok := s.authManager.Tombstone(nodeID)
+	if !ok {
+		return errNodeDecommissioned.Err()
+	}
+	return errNodeDecommissioned.Detailed(
+		fmt.Sprintf("node was decommissioned on %s: %s", tombstone.DecommissionedAt.Format(time.RFC3339), tombstone.Reason),
+		map[string]string{
+			"decommissioned_at": tombstone.DecommissionedAt.UTC().Format(time.RFC3339),
+			"reason":            tombstone.Reason,
+		},
+	)
+}
+
+// admittedResponse issues the initial auth token of an admitted node, bound to its certificate
+func (s *Server) admittedResponse(nodeID string, cert *x509.Certificate, certPEM []byte) (*pb.RegisterNodeResponse, error) {
+	authToken, _, err := s.authManager.GenerateAuthToken(nodeID, cert)
+	if err != nil {
+		return nil, internalError("Failed to generate auth token", err, zap.String("node_id", nodeID))
 	}
 
 	return &pb.RegisterNodeResponse{
 		Success:           true,
 		Message:           "Node registered successfully",
-		SignedCertificate: cert,
+		SignedCertificate: certPEM,
 		NodeId:            nodeID,
 		InitialAuthToken:  authToken,
 		ControlPlaneInfo: &pb.ControlPlaneInfo{
-			ApiEndpoint:      s.config.APIEndpoint,
-			CaCertificate:    []byte(s.config.TLS.CACert), // TODO: Convert to []byte
-			ConnectionParams: s.config.ConnectionParams,
+			ApiEndpoint:        s.config.APIEndpoint,
+			CaCertificate:      s.tls.caPEM,
+			ConnectionParams:   legacyConnectionParams(&s.config.Connection),
+			CaBundle:           s.authManager.TrustBundle().PEM(),
+			CaSpkiPins:         s.tls.caPins,
+			ConnectionSettings: connectionSettings(&s.config.Connection),
 		},
+		RegistrationState: pb.RegisterNodeResponse_ADMITTED,
 	}, nil
 }
 
 // Authenticate handles node authentication requests
 func (s *Server) Authenticate(ctx context.Context, req *pb.AuthenticationRequest) (*pb.AuthenticationResponse, error) {
+	p, err := principalFor(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	peer := p.Certificate
+
+	if s.draining.Load() {
+		return nil, s.drainingError()
+	}
+
+	failed := func(reason string, err error) (*pb.AuthenticationResponse, error) {
+		s.audit(ctx, "node.authenticate", req.NodeId, audit.OutcomeFailure, map[string]string{
+			"reason": reason,
+		})
+		return nil, err
+	}
+
+	if _, ok := s.authManager.Tombstone(req.NodeId); ok {
+		return failed("node decommissioned", s.decommissionedError(req.NodeId))
+	}
+
 	// Validate auth token
-	if err := s.authManager.ValidateAuthToken(req.NodeId, req.AuthToken); err != nil {
-		return &pb.AuthenticationResponse{
-			Success: false,
-			Message: "Invalid auth token",
-		}, nil
+	if _, err := s.authManager.ValidateAuthToken(req.NodeId, req.AuthToken, peer); err != nil {
+		if errors.Is(err, auth.ErrTokenReused) {
+			return failed("rotated token reused", errTokenReused.Err())
+		}
+		return failed("invalid auth token", errAuthTokenInvalid.Err())
 	}
 
-	// Validate certificate
-	if err := s.authManager.ValidateCertificate(req.Certificate); err != nil {
-		return &pb.AuthenticationResponse{
-			Success: false,
-			Message: "Invalid certificate",
-		}, nil
+	// Validate certificate, which must be the one the connection is made with
+	cert, err := s.authManager.ValidateCertificate(req.Certificate)
+	if err != nil {
+		if errors.Is(err, auth.ErrCertificateRevoked) {
+			return failed("certificate revoked", errCertificateRevoked.Err())
+		}
+		return failed("invalid certificate", errCertificateInvalid.InvalidField("certificate", "must be a PEM certificate issued by the control plane"))
+	}
+	if !cert.Equal(peer) {
+		return failed("certificate does not match the TLS client certificate", errCertificateMismatch.Err())
 	}
 
-	// Create session
+	// Create session. Nodes admitted before their records were kept, or
+	// whose record was lost, must register again.
 	sessionID, err := s.nodeManager.CreateSession(req.NodeId)
+	if errors.Is(err, node.ErrNodeNotFound) {
+		return failed("unknown node", errNodeNotFound.Errorf("node is not registered, register again"))
+	}
 	if err != nil {
-		return &pb.AuthenticationResponse{
-			Success: false,
-			Message: fmt.Sprintf("Failed to create session: %v", err),
-		}, nil
+		return failed("failed to create session", internalError("Failed to create session", err, zap.String("node_id", req.NodeId)))
 	}
 
 	// Update node info
 	if err := s.nodeManager.UpdateNodeInfo(req.NodeId, req.BasicInfo, req.Capabilities); err != nil {
-		return &pb.AuthenticationResponse{
-			Success: false,
-			Message: fmt.Sprintf("Failed to update node info: %v", err),
-		}, nil
+		return failed("failed to update node info", internalError("Failed to update node info", err, zap.String("node_id", req.NodeId)))
+	}
+
+	if err := s.nodeManager.SetNodeCertificate(req.NodeId, cert); err != nil {
+		return failed("failed to update node info", internalError("Failed to update node certificate", err, zap.String("node_id", req.NodeId)))
 	}
 
 	// Get initial configuration
 	config := s.nodeManager.GetNodeConfiguration(req.NodeId)
 
+	s.audit(ctx, "node.authenticate", req.NodeId, audit.OutcomeSuccess, map[string]string{
+		"session_id": sessionID,
+	})
+
 	return &pb.AuthenticationResponse{
-		Success:       true,
-		Message:       "Authentication successful",
-		SessionId:     sessionID,
-		TokenExpiry:   s.authManager.GetTokenExpiry(),
-		InitialConfig: config,
+		Success:            true,
+		Message:            "Authentication successful",
+		SessionId:          sessionID,
+		TokenExpiry:        s.authManager.GetTokenExpiry(),
+		InitialConfig:      config,
+		ConnectionSettings: connectionSettings(&s.config.Connection),
 	}, nil
 }
 
 // StreamConnection handles bidirectional streaming with nodes
 func (s *Server) StreamConnection(stream pb.NodeService_StreamConnectionServer) error {
-	ctx := stream.Context()
-	nodeID, err := s.authManager.NodeIDFromContext(ctx)
+	p, err := principalFor(stream.Context(), "")
+	if err != nil {
+		return err
+	}
+	nodeID := p.NodeID
+
+	if p.SessionID == "" {
+		return errSessionRequired.Err()
+	}
+
+	if s.draining.Load() {
+		return s.drainingError()
+	}
+
+	// Create stream handler, bound to the session of the call
+	handler := node.NewStreamHandler(stream.Context(), nodeID, p.SessionID, s.nodeManager, s.metricsManager)
+	if err := s.nodeManager.BindStream(nodeID, p.SessionID, handler); err != nil {
+		return sessionError(err)
+	}
+	defer s.nodeManager.UnbindStream(nodeID, p.SessionID, handler)
+
+	s.nodeManager.AttachStream(nodeID, handler)
+	defer s.nodeManager.DetachStream(nodeID, handler)
+
+	s.sendTrustBundleUpdate(nodeID, handler)
+
+	return sessionError(handler.HandleStream(stream))
+}
+
+// sessionError maps session failures ending a stream to gRPC statuses
+func sessionError(err error) error {
+	switch {
+	case err == nil, errors.Is(err, node.ErrDisconnected):
+		return nil
+	case errors.Is(err, node.ErrSessionRevoked):
+		return errSessionRevoked.Err()
+	case errors.Is(err, node.ErrSessionExpired), errors.Is(err, node.ErrSessionNotFound):
+		return errSessionExpired.Err()
+	case errors.Is(err, node.ErrSessionMismatch):
+		return errSessionMismatch.Err()
+	case status.Code(err) != codes.Unknown:
+		// Transport errors, e.g. the node cancelling its stream
+		return err
+	default:
+		return internalError("Node stream failed", err)
+	}
+}
+
+// sendTrustBundleUpdate pushes the trust bundle to nodes that have not seen its
+// current version, and to nodes holding a certificate from a retiring CA, which
+// are asked to re-key
+func (s *Server) sendTrustBundleUpdate(nodeID string, handler *node.StreamHandler) {
+	cert, err := s.nodeManager.NodeCertificate(nodeID)
 	if err != nil {
-		return fmt.Errorf("node ID not found in context: %w", err)
+		return
+	}
+
+	update := s.authManager.TrustBundleUpdate(cert)
+	version := s.authManager.TrustBundle().Version()
+	if s.nodeManager.HasTrustBundle(nodeID, version) && !update.RekeyRequired {
+		return
+	}
+
+	if update.RekeyRequired {
+		logger.L().Info("Node certificate issued by a retiring CA, requesting re-key",
+			zap.String("node_id", nodeID),
+			zap.Time("rekey_before", time.Unix(update.RekeyBefore, 0)),
+		)
+	} else {
+		logger.L().Info("Pushing updated trust bundle to node",
+			zap.String("node_id", nodeID),
+			zap.String("version", version),
+		)
 	}
 
-	// Create stream handler
-	handler := node.NewStreamHandler(nodeID, s.nodeManager, s.metricsManager)
-	return handler.HandleStream(stream)
+	cmd := &pb.ControlPlaneCommand{
+		CommandId: uuid.New().String(),
+		Command:   &pb.ControlPlaneCommand_TrustBundleUpdate{TrustBundleUpdate: update},
+		Priority:  pb.ControlPlaneCommand_CRITICAL,
+	}
+	if err := handler.SendCommand(cmd); err != nil {
+		logger.L().Error("Failed to send trust bundle update",
+			zap.String("node_id", nodeID),
+			zap.Error(err),
+		)
+		return
+	}
+	s.nodeManager.SetTrustBundle(nodeID, version)
 }
 
 // RotateToken handles token rotation requests
 func (s *Server) RotateToken(ctx context.Context, req *pb.TokenRotationRequest) (*pb.TokenRotationResponse, error) {
-	// Validate current token and session
-	if err := s.authManager.ValidateAuthToken(req.NodeId, req.CurrentToken); err != nil {
-		return nil, fmt.Errorf("invalid auth token")
+	// The token rotated is the one the call is authenticated with
+	p, err := principalFor(ctx, req.NodeId)
+	if err != nil {
+		return nil, err
 	}
 
-	if err := s.nodeManager.ValidateSession(req.NodeId, req.SessionId); err != nil {
-		return nil, fmt.Errorf("invalid session")
+	if p.SessionID == "" {
+		return nil, errSessionRequired.Err()
 	}
 
 	// Generate new token
-	newToken, expiry, err := s.authManager.RotateToken(req.NodeId, req.CurrentToken)
+	newToken, expiry, err := s.authManager.RotateToken(p.Claims, p.Certificate)
 	if err != nil {
-		return nil, fmt.Errorf("failed to rotate token: %w", err)
+		s.audit(ctx, "token.rotate", p.NodeID, audit.OutcomeFailure, map[string]string{
+			"reason": err.Error(),
+		})
+		return nil, rotationError("Failed to rotate token", p.NodeID, err)
 	}
 
+	s.audit(ctx, "token.rotate", p.NodeID, audit.OutcomeSuccess, map[string]string{
+		"family_id": p.Claims.FamilyID,
+	})
+
 	return &pb.TokenRotationResponse{
 		NewToken: newToken,
 		Expiry:   expiry,
 	}, nil
 }
+
+// RenewCertificate signs a new node certificate with the current issuing CA
+func (s *Server) RenewCertificate(ctx context.Context, req *pb.CertificateRenewalRequest) (*pb.CertificateRenewalResponse, error) {
+	p, err := principalFor(ctx, req.NodeId)
+	if err != nil {
+		return nil, err
+	}
+
+	// The current token is rebound to the new certificate, checked before
+	// signing so a rotated or revoked token does not get a certificate issued
+	if err := s.authManager.CheckToken(p.Claims); err != nil {
+		s.audit(ctx, "certificate.renew", p.NodeID, audit.OutcomeFailure, map[string]string{
+			"reason": err.Error(),
+		})
+		return nil, rotationError("Failed to check auth token", p.NodeID, err)
+	}
+
+	cert, certPEM, err := s.authManager.SignCSR(p.NodeID, req.Csr)
+	if err != nil {
+		return nil, s.csrError(ctx, "certificate.renew", p.NodeID, "Failed to sign CSR", err)
+	}
+
+	authToken, expiry, err := s.authManager.RotateToken(p.Claims, cert)
+	if err != nil {
+		return nil, rotationError("Failed to rebind auth token", p.NodeID, err)
+	}
+
+	logger.L().Info("Node certificate renewed", zap.String("node_id", p.NodeID))
+	s.auditCertificate(ctx, p.NodeID, cert)
+	s.audit(ctx, "certificate.renew", p.NodeID, audit.OutcomeSuccess, nil)
+
+	return &pb.CertificateRenewalResponse{
+		Success:           true,
+		Message:           "Certificate renewed successfully",
+		SignedCertificate: certPEM,
+		CaBundle:          s.authManager.TrustBundle().PEM(),
+		AuthToken:         authToken,
+		TokenExpiry:       expiry,
+	}, nil
+}
+
+// GetTrustBundle returns the trust material a node needs to pin the control plane
+func (s *Server) GetTrustBundle(ctx context.Context, req *pb.TrustBundleRequest) (*pb.TrustBundleResponse, error) {
+	if _, err := principalFor(ctx, req.NodeId); err != nil {
+		return nil, err
+	}
+
+	trust := s.authManager.TrustBundle()
+
+	return &pb.TrustBundleResponse{
+		CaCertificate:     s.tls.caPEM,
+		CaSpkiPins:        s.tls.caPins,
+		CaBundle:          trust.PEM(),
+		IssuerFingerprint: certs.Fingerprint(trust.Issuer().Certificate()),
+	}, nil
+}
@@ -0,0 +1,102 @@
+package lmgrpc
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/init/config"
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/pkg/certs"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+// serverTLS holds the control-plane server certificate and the CAs nodes pin
+type serverTLS struct {
+	certificate tls.Certificate
+	caCerts     []*x509.Certificate
+	caPEM       []byte
+	caPins      []string
+}
+
+// loadServerTLS loads and cross-checks the server certificate and its CA bundle
+func loadServerTLS(cfg *config.TLSConfig) (*serverTLS, error) {
+	certificate, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load server certificate: %w", err)
+	}
+
+	caCerts, err := certs.LoadCertificates(cfg.CACert)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load CA bundle: %w", err)
+	}
+
+	return newServerTLS(certificate, caCerts)
+}
+
+func newServerTLS(certificate tls.Certificate, caCerts []*x509.Certificate) (*serverTLS, error) {
+	leaf, err := x509.ParseCertificate(certificate.Certificate[0])
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse server certificate: %w", err)
+	}
+
+	roots := x509.NewCertPool()
+	pins := make([]string, 0, len(caCerts))
+	for _, ca := range caCerts {
+		if !ca.IsCA {
+			return nil, fmt.Errorf("CA bundle entry %q is not a CA", ca.Subject.CommonName)
+		}
+		roots.AddCert(ca)
+		pins = append(pins, certs.SPKIPin(ca))
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, der := range certificate.Certificate[1:] {
+		if cert, err := x509.ParseCertificate(der); err == nil {
+			intermediates.AddCert(cert)
+		}
+	}
+
+	if _, err := leaf.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}); err != nil {
+		return nil, fmt.Errorf("server certificate does not chain to the CA bundle: %w", err)
+	}
+
+	return &serverTLS{
+		certificate: certificate,
+		caCerts:     caCerts,
+		caPEM:       certs.EncodeCertificates(caCerts),
+		caPins:      pins,
+	}, nil
+}
+
+// config returns the TLS configuration of the gRPC listener. Client
+// certificates are optional at the handshake since registering nodes have
+// none yet; certificate-bound tokens require them on authenticated calls.
+func (t *serverTLS) config(clientCAs *x509.CertPool) *tls.Config {
+	return &tls.Config{
+		Certificates: []tls.Certificate{t.certificate},
+		ClientAuth:   tls.VerifyClientCertIfGiven,
+		ClientCAs:    clientCAs,
+		MinVersion:   tls.VersionTLS12,
+	}
+}
+
+// peerCertificate returns the verified TLS client certificate of the caller, if any
+func peerCertificate(ctx context.Context) *x509.Certificate {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil
+	}
+
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(info.State.VerifiedChains) == 0 {
+		return nil
+	}
+
+	return info.State.VerifiedChains[0][0]
+}
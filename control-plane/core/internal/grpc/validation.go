@@ -0,0 +1,196 @@
+package lmgrpc
+
+import (
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/internal/validation"
+	pb "github.com/raphaelCamblong/Luminous-Mesh/control-plane/shared/proto"
+	"google.golang.org/protobuf/proto"
+)
+
+// Field limits shared by the validation rules
+const (
+	maxIDLen          = 128
+	maxTokenLen       = 8 << 10
+	maxCSRSize        = 16 << 10
+	maxCertificateLen = 16 << 10
+	maxNameLen        = 256
+	maxReasonLen      = 512
+	maxLabels         = 64
+	maxModelTypes     = 64
+	maxResources      = 64
+	maxMetrics        = 256
+	maxMetricLabels   = 32
+	maxCommandIDs     = 1000
+)
+
+var (
+	id        = validation.Field{MaxLen: maxIDLen}
+	requireID = validation.Field{Required: true, MaxLen: maxIDLen}
+	reason    = validation.Field{MaxLen: maxReasonLen}
+	notBefore = validation.Field{Bounds: validation.AtLeast(0)}
+)
+
+// messageRules declares the constraints of the request messages and of the
+// messages they hold. Fields without a rule are still bounded by the
+// validation defaults.
+var messageRules = validation.Rules{
+	// Node service
+	validation.Message(&pb.RegisterNodeRequest{}): {
+		"bootstrap_token": {Required: true, MaxLen: maxTokenLen},
+		"basic_info":      {Required: true},
+		"csr":             {Required: true, MaxLen: maxCSRSize},
+	},
+	validation.Message(&pb.NodeBasicInfo{}): {
+		"hostname":              {Required: true, MaxLen: 253},
+		"ip_address":            {MaxLen: 64},
+		"version":               {MaxLen: maxNameLen},
+		"supported_model_types": {MaxItems: maxModelTypes, MaxLen: maxNameLen},
+		"architecture":          {MaxLen: maxNameLen},
+		"labels":                {MaxItems: maxLabels, MaxLen: maxNameLen},
+		"machine_id":            {MaxLen: maxNameLen},
+	},
+	validation.Message(&pb.RegistrationStatusRequest{}): {
+		"registration_id": requireID,
+		"wait_seconds":    notBefore,
+	},
+	validation.Message(&pb.AuthenticationRequest{}): {
+		"node_id":     requireID,
+		"auth_token":  {Required: true, MaxLen: maxTokenLen},
+		"certificate": {Required: true, MaxLen: maxCertificateLen},
+	},
+	validation.Message(&pb.NodeCapabilities{}): {
+		"supported_model_types": {MaxItems: maxModelTypes, MaxLen: maxNameLen},
+		"architecture":          {MaxLen: maxNameLen},
+		"labels":                {MaxItems: maxLabels, MaxLen: maxNameLen},
+	},
+	validation.Message(&pb.NodeStatusUpdate{}): {
+		"node_id":           id,
+		"session_id":        id,
+		"metrics":           {MaxItems: maxMetrics},
+		"timestamp":         notBefore,
+		"acked_command_ids": {MaxItems: maxCommandIDs, MaxLen: maxIDLen},
+	},
+	validation.Message(&pb.NodeStatus{}): {
+		"resources": {MaxItems: maxResources, MaxLen: maxNameLen},
+	},
+	validation.Message(&pb.ResourceStatus{}): {
+		"name":             {MaxLen: maxNameLen},
+		"usage_percentage": {Bounds: validation.Between(0, 100)},
+		"status":           {MaxLen: maxNameLen},
+	},
+	validation.Message(&pb.MetricsReport{}): {
+		"metric_name": {Required: true, MaxLen: maxNameLen},
+		"labels":      {MaxItems: maxMetricLabels, MaxLen: maxNameLen},
+	},
+	validation.Message(&pb.TokenRotationRequest{}): {
+		"node_id":       id,
+		"current_token": {MaxLen: maxTokenLen},
+		"session_id":    id,
+	},
+	validation.Message(&pb.CertificateRenewalRequest{}): {
+		"node_id":    id,
+		"auth_token": {MaxLen: maxTokenLen},
+		"csr":        {Required: true, MaxLen: maxCSRSize},
+	},
+	validation.Message(&pb.TrustBundleRequest{}): {
+		"node_id": id,
+	},
+
+	// Admin service
+	validation.Message(&pb.RevokeNodeTokensRequest{}): {
+		"node_id": requireID,
+		"reason":  reason,
+	},
+	validation.Message(&pb.ListSessionsRequest{}): {
+		"node_id": id,
+	},
+	validation.Message(&pb.RevokeSessionRequest{}): {
+		"session_id": requireID,
+		"node_id":    id,
+		"reason":     reason,
+	},
+	validation.Message(&pb.DecommissionNodeRequest{}): {
+		"node_id": requireID,
+		"reason":  reason,
+	},
+	validation.Message(&pb.SendCommandRequest{}): {
+		"node_id":     requireID,
+		"command":     {Required: true},
+		"ttl_seconds": notBefore,
+	},
+	validation.Message(&pb.ControlPlaneCommand{}): {
+		"command_id": id,
+		// The lane is picked by the control plane, which must know it
+		"priority": {KnownEnum: true},
+		"deadline": notBefore,
+	},
+	validation.Message(&pb.ConfigurationUpdate{}): {
+		"config_id": id,
+	},
+	validation.Message(&pb.NodeConfiguration{}): {
+		"enabled_features": {MaxItems: maxLabels, MaxLen: maxNameLen},
+	},
+	validation.Message(&pb.ResourceLimits{}): {
+		"max_concurrent_tasks": notBefore,
+		"max_memory_mb":        notBefore,
+		"max_cpu_usage":        {Bounds: validation.Between(0, 100)},
+	},
+	validation.Message(&pb.HealthCheck{}): {
+		"check_id":    id,
+		"check_items": {MaxItems: maxLabels, MaxLen: maxNameLen},
+	},
+	validation.Message(&pb.ListPendingCommandsRequest{}): {
+		"node_id": requireID,
+	},
+	validation.Message(&pb.PurgePendingCommandsRequest{}): {
+		"node_id":     requireID,
+		"command_ids": {MaxItems: maxCommandIDs, MaxLen: maxIDLen},
+	},
+	validation.Message(&pb.DrainRequest{}): {
+		"reason":          reason,
+		"timeout_seconds": {Bounds: validation.Between(0, maxDrainTimeout.Seconds())},
+	},
+	validation.Message(&pb.DecideRegistrationRequest{}): {
+		"node_id": requireID,
+		"reason":  reason,
+	},
+	validation.Message(&pb.ListAuditRecordsRequest{}): {
+		"actor":  {MaxLen: maxNameLen},
+		"action": {MaxLen: maxNameLen},
+		"target": {MaxLen: maxNameLen},
+		"since":  notBefore,
+		"until":  notBefore,
+		"limit":  notBefore,
+	},
+	validation.Message(&pb.LoginRequest{}): {
+		"username": {Required: true, MaxLen: maxNameLen},
+		"password": {Required: true, MaxLen: maxNameLen},
+	},
+	validation.Message(&pb.CreateUserRequest{}): {
+		"username": {Required: true, MaxLen: maxNameLen},
+		"password": {Required: true, MaxLen: maxNameLen},
+		"roles":    {MaxItems: maxLabels, MaxLen: maxNameLen},
+	},
+	validation.Message(&pb.DeleteUserRequest{}): {
+		"username": {Required: true, MaxLen: maxNameLen},
+	},
+	validation.Message(&pb.CreateAPIKeyRequest{}): {
+		"name":        {Required: true, MaxLen: maxNameLen},
+		"permissions": {MaxItems: maxLabels, MaxLen: maxNameLen},
+		"ttl_seconds": notBefore,
+	},
+	validation.Message(&pb.RevokeAPIKeyRequest{}): {
+		"id": requireID,
+	},
+}
+
+// validate checks a received message against messageRules
+func (s *Server) validate(msg interface{}) error {
+	m, ok := msg.(proto.Message)
+	if !ok {
+		return nil
+	}
+	if violations := s.validator.Validate(m); len(violations) > 0 {
+		return errInvalidRequest.Violations(violations)
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package lmhttp
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/init/config"
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/init/logger"
+	"go.uber.org/zap"
+)
+
+// Server serves the public HTTP endpoints of the control plane: the JWKS
+// verifiers fetch token signing keys from, the Prometheus metrics and the
+// single sign-on endpoints of operators
+type Server struct {
+	config     *config.HTTPConfig
+	tlsConfig  *tls.Config
+	mux        *http.ServeMux
+	httpServer *http.Server
+}
+
+// NewServer creates the HTTP server. tlsConfig is unused in plaintext mode.
+func NewServer(tlsConfig *tls.Config) *Server {
+	cfg := config.Get().Core.HTTP
+
+	s := &Server{
+		config:    &cfg,
+		tlsConfig: tlsConfig,
+		mux:       http.NewServeMux(),
+	}
+	s.mux.Handle("/metrics", promhttp.Handler())
+	s.mux.HandleFunc("/healthz", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	return s
+}
+
+// Handle registers a handler on the server
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+}
+
+// Start listens and serves in the background until Stop is called
+func (s *Server) Start() error {
+	if s.config.ListenAddr == "" {
+		logger.L().Info("HTTP server disabled")
+		return nil
+	}
+
+	lis, err := net.Listen("tcp", s.config.ListenAddr)
+	if err != nil {
+		return fmt.Errorf("failed to listen: %w", err)
+	}
+
+	s.httpServer = &http.Server{
+		Handler:           s.mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	if !s.config.Plaintext {
+		s.httpServer.TLSConfig = s.tlsConfig
+		lis = tls.NewListener(lis, s.tlsConfig)
+	}
+
+	logger.L().Info("Starting HTTP server",
+		zap.String("address", s.config.ListenAddr),
+		zap.Bool("tls", !s.config.Plaintext),
+	)
+
+	go func() {
+		if err := s.httpServer.Serve(lis); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.L().Error("HTTP server stopped", zap.Error(err))
+		}
+	}()
+
+	return nil
+}
+
+// Stop gracefully shuts the server down
+func (s *Server) Stop() {
+	if s.httpServer == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := s.httpServer.Shutdown(ctx); err != nil {
+		logger.L().Warn("HTTP server shutdown failed", zap.Error(err))
+	}
+}
+
+// JSONDocument serves the document returned by load, regenerated on each request
+func JSONDocument(load func() ([]byte, error), maxAge time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		body, err := load()
+		if err != nil {
+			logger.L().Error("Failed to render document",
+				zap.String("path", r.URL.Path),
+				zap.Error(err),
+			)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(maxAge.Seconds())))
+		w.Write(body)
+	})
+}
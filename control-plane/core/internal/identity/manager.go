@@ -0,0 +1,249 @@
+package identity
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/init/config"
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/init/logger"
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/pkg/certs"
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/shared/interfaces"
+	pb "github.com/raphaelCamblong/Luminous-Mesh/control-plane/shared/proto"
+	"go.uber.org/zap"
+)
+
+const identitiesBucket = "identities"
+
+// Match strategies
+const (
+	MatchPublicKey = "public_key"
+	MatchMachineID = "machine_id"
+	MatchHostname  = "hostname"
+)
+
+// Proven reports whether a match by strategy proves the node is the one it
+// matched: a public key match is backed by the CSR signature, while machine
+// IDs and hostnames are declared by the node and can be spoofed
+func Proven(strategy string) bool {
+	return strategy == MatchPublicKey
+}
+
+// Key identifies a node across re-registrations
+type Key struct {
+	Strategy string
+	Value    string
+}
+
+func (k Key) String() string {
+	return k.Strategy + ":" + k.Value
+}
+
+// binding maps an identity key to the node holding it
+type binding struct {
+	Key        string    `json:"key"`
+	Strategy   string    `json:"strategy"`
+	NodeID     string    `json:"node_id"`
+	RecordedAt time.Time `json:"recorded_at"`
+}
+
+// Manager remembers the identity keys of registered nodes so a node that
+// re-enrolls gets its previous node ID back
+type Manager struct {
+	config   *config.IdentityConfig
+	store    interfaces.DataStore
+	bindings map[string]*binding
+	byNode   map[string][]string
+	mu       sync.RWMutex
+}
+
+// NewManager restores identity bindings from store
+func NewManager(store interfaces.DataStore) (*Manager, error) {
+	cfg := config.Get().Core.Identity
+
+	m := &Manager{
+		config:   &cfg,
+		store:    store,
+		bindings: make(map[string]*binding),
+		byNode:   make(map[string][]string),
+	}
+
+	if store == nil {
+		return m, nil
+	}
+
+	stored, err := store.List(identitiesBucket)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load identities: %w", err)
+	}
+	for _, data := range stored {
+		var b binding
+		if err := json.Unmarshal(data, &b); err != nil {
+			return nil, fmt.Errorf("failed to decode identity: %w", err)
+		}
+		m.bindings[b.Key] = &b
+		m.byNode[b.NodeID] = append(m.byNode[b.NodeID], b.Key)
+	}
+
+	return m, nil
+}
+
+// Keys derives the identity keys of a registering node, in configured match order.
+// Strategies the node provides no data for are skipped.
+func (m *Manager) Keys(info *pb.NodeBasicInfo, csrBytes []byte) []Key {
+	var keys []Key
+	for _, strategy := range m.config.Match {
+		switch strategy {
+		case MatchPublicKey:
+			csr, err := certs.ParseCSR(csrBytes)
+			if err != nil {
+				continue
+			}
+			pin := sha256.Sum256(csr.RawSubjectPublicKeyInfo)
+			keys = append(keys, Key{strategy, base64.StdEncoding.EncodeToString(pin[:])})
+		case MatchMachineID:
+			if info.GetMachineId() != "" {
+				keys = append(keys, Key{strategy, info.GetMachineId()})
+			}
+		case MatchHostname:
+			if value, ok := m.hostnameKey(info); ok {
+				keys = append(keys, Key{strategy, value})
+			}
+		}
+	}
+	return keys
+}
+
+// hostnameKey combines the hostname with the configured labels; every label must be present
+func (m *Manager) hostnameKey(info *pb.NodeBasicInfo) (string, bool) {
+	if info.GetHostname() == "" {
+		return "", false
+	}
+
+	labels := append([]string(nil), m.config.Labels...)
+	sort.Strings(labels)
+
+	parts := []string{info.GetHostname()}
+	for _, label := range labels {
+		value, ok := info.GetLabels()[label]
+		if !ok {
+			return "", false
+		}
+		parts = append(parts, label+"="+value)
+	}
+	return strings.Join(parts, ","), true
+}
+
+// Resolve returns the node holding the first matching key and the strategy
+// that matched, a proven one when another key also matches that node
+func (m *Manager) Resolve(keys []Key) (string, string, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for i, key := range keys {
+		b, ok := m.bindings[key.String()]
+		if !ok {
+			continue
+		}
+		for _, other := range keys[i+1:] {
+			if proof, ok := m.bindings[other.String()]; ok && Proven(proof.Strategy) && proof.NodeID == b.NodeID {
+				return b.NodeID, proof.Strategy, true
+			}
+		}
+		return b.NodeID, b.Strategy, true
+	}
+	return "", "", false
+}
+
+// Record binds keys to a node, replacing its previous keys. A key held by
+// another node moves to this one.
+func (m *Manager) Record(nodeID string, keys []Key) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.forget(nodeID)
+
+	now := time.Now()
+	for _, key := range keys {
+		b := &binding{
+			Key:        key.String(),
+			Strategy:   key.Strategy,
+			NodeID:     nodeID,
+			RecordedAt: now,
+		}
+		if previous, ok := m.bindings[b.Key]; ok {
+			m.unbind(previous.NodeID, b.Key)
+		}
+		if err := m.save(b); err != nil {
+			return err
+		}
+		m.bindings[b.Key] = b
+		m.byNode[nodeID] = append(m.byNode[nodeID], b.Key)
+	}
+	return nil
+}
+
+// Forget drops the identity keys of a node, so its next registration gets a new ID
+func (m *Manager) Forget(nodeID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.forget(nodeID)
+}
+
+// forget drops the keys of a node. Callers hold m.mu.
+func (m *Manager) forget(nodeID string) {
+	for _, key := range m.byNode[nodeID] {
+		delete(m.bindings, key)
+		m.delete(key)
+	}
+	delete(m.byNode, nodeID)
+}
+
+// unbind removes key from the keys of a node. Callers hold m.mu.
+func (m *Manager) unbind(nodeID, key string) {
+	keys := m.byNode[nodeID]
+	for i, k := range keys {
+		if k == key {
+			m.byNode[nodeID] = append(keys[:i], keys[i+1:]...)
+			break
+		}
+	}
+}
+
+func (m *Manager) save(b *binding) error {
+	if m.store == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(b)
+	if err != nil {
+		return fmt.Errorf("failed to encode identity: %w", err)
+	}
+	if err := m.store.Put(identitiesBucket, b.Key, data); err != nil {
+		return fmt.Errorf("failed to persist identity: %w", err)
+	}
+	return nil
+}
+
+func (m *Manager) delete(key string) {
+	if m.store == nil {
+		return
+	}
+	if err := m.store.Delete(identitiesBucket, key); err != nil && !errors.Is(err, interfaces.ErrNotFound) {
+		logger.L().Warn("Failed to delete identity",
+			zap.String("key", key),
+			zap.Error(err),
+		)
+	}
+}
@@ -7,16 +7,21 @@ import (
 
 	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/init/config"
 	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/init/logger"
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/internal/dev"
 	lmgrpc "github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/internal/grpc"
+	lmhttp "github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/internal/http"
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/internal/store"
 	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/pkg/plugins"
 	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/shared/interfaces"
 	"go.uber.org/zap"
 )
 
 type Infra struct {
-	Plugins pluginRegistry
-	Server  *lmgrpc.Server
-	Ctx     context.Context
+	Plugins    pluginRegistry
+	Server     *lmgrpc.Server
+	HttpServer *lmhttp.Server
+	Dev        *dev.Environment
+	Ctx        context.Context
 }
 
 type pluginRegistry struct {
@@ -31,7 +36,7 @@ func NewInfra() *Infra {
 }
 
 func (i *Infra) IntegrityCheck() {
-	if i.Plugins.ApiGateway == nil {
+	if i.Plugins.ApiGateway == nil && i.Dev == nil {
 		panic("ApiGateway is not initialized")
 	}
 	if i.Plugins.DataStore == nil {
@@ -42,6 +47,12 @@ func (i *Infra) IntegrityCheck() {
 func (i *Infra) LoadPlugins() {
 	cfg := config.Get()
 
+	// Development mode keeps everything in process
+	if cfg.Dev {
+		i.Plugins.DataStore = store.NewMemory()
+		return
+	}
+
 	pluginDefs := []plugins.Definition{
 		{
 			Name:        "api-gateway",
@@ -71,8 +82,76 @@ func (i *Infra) LoadPlugins() {
 	}
 }
 
+// StartDataStore configures and starts the data store, which the server
+// restores its state from
+func (i *Infra) StartDataStore() {
+	if i.Plugins.DataStore != nil {
+		startPlugin(i.Plugins.DataStore, config.Get().Plugins.Settings, i.Plugins.DataStore.Start)
+	}
+}
+
+// StartApiGateway configures and starts the api gateway
+func (i *Infra) StartApiGateway() {
+	if i.Plugins.ApiGateway == nil {
+		return
+	}
+
+	startPlugin(i.Plugins.ApiGateway, config.Get().Plugins.Settings, i.Plugins.ApiGateway.Start)
+}
+
+func startPlugin(p interfaces.Plugin, settings map[string]map[string]string, start func() error) {
+	if err := p.Init(settings[p.GetName()]); err != nil {
+		panic(fmt.Errorf("❌ Failed to initialize plugin %s: %w", p.GetName(), err))
+	}
+	if err := start(); err != nil {
+		panic(fmt.Errorf("❌ Failed to start plugin %s: %w", p.GetName(), err))
+	}
+	logger.L().Info("Plugin started",
+		zap.String("name", p.GetName()),
+		zap.String("version", p.GetVersion()),
+	)
+}
+
+// StopPlugins stops the plugins in reverse start order
+func (i *Infra) StopPlugins() {
+	if i.Plugins.ApiGateway != nil {
+		if err := i.Plugins.ApiGateway.Stop(); err != nil {
+			logger.L().Error("Failed to stop api gateway", zap.Error(err))
+		}
+	}
+	if i.Plugins.DataStore != nil {
+		if err := i.Plugins.DataStore.Stop(); err != nil {
+			logger.L().Error("Failed to stop data store", zap.Error(err))
+		}
+	}
+}
+
+// LoadDevEnvironment generates the ephemeral PKI used by --dev
+func (i *Infra) LoadDevEnvironment() {
+	if !config.Get().Dev {
+		return
+	}
+
+	env, err := dev.NewEnvironment()
+	if err != nil {
+		logger.L().Fatal("Failed to create development environment",
+			zap.Error(err),
+		)
+	}
+	i.Dev = env
+}
+
 func (i *Infra) LoadGrpcServer() {
-	server, err := lmgrpc.NewServer()
+	opts := lmgrpc.Options{
+		Store: i.Plugins.DataStore,
+	}
+	if i.Dev != nil {
+		opts.CA = i.Dev.CA
+		opts.TLSCertificate = &i.Dev.TLSCertificate
+		opts.TLSCACerts = i.Dev.CACerts()
+	}
+
+	server, err := lmgrpc.NewServer(opts)
 	if err != nil {
 		logger.L().Fatal("Failed to create server",
 			zap.Error(err),
@@ -80,3 +159,9 @@ func (i *Infra) LoadGrpcServer() {
 	}
 	i.Server = server
 }
+
+// LoadHttpServer creates the HTTP server publishing the JWKS and metrics
+func (i *Infra) LoadHttpServer() {
+	i.HttpServer = lmhttp.NewServer(i.Server.TLSConfig())
+	i.Server.RegisterHTTPHandlers(i.HttpServer)
+}
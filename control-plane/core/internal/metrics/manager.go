@@ -23,6 +23,17 @@ type Manager struct {
 	activeTasks    *prometheus.GaugeVec
 	completedTasks *prometheus.CounterVec
 	failedTasks    *prometheus.CounterVec
+
+	// Abuse protection metrics
+	rateLimited       *prometheus.CounterVec
+	authFailures      *prometheus.CounterVec
+	lockouts          *prometheus.CounterVec
+	rateLimitSettings *prometheus.GaugeVec
+
+	// Admission control metrics
+	admissionInflight prometheus.Gauge
+	admissionQueued   prometheus.Gauge
+	admissionRejected *prometheus.CounterVec
 }
 
 // NewManager creates a new metrics manager
@@ -41,6 +52,13 @@ func NewManager() (*Manager, error) {
 		m.activeTasks,
 		m.completedTasks,
 		m.failedTasks,
+		m.rateLimited,
+		m.authFailures,
+		m.lockouts,
+		m.rateLimitSettings,
+		m.admissionInflight,
+		m.admissionQueued,
+		m.admissionRejected,
 	)
 
 	return m, nil
@@ -110,6 +128,60 @@ func (m *Manager) initMetrics() {
 		},
 		[]string{"node_id", "hostname"},
 	)
+
+	m.rateLimited = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "luminous_mesh_rate_limited_total",
+			Help: "Total number of calls refused by rate limiting or lockouts",
+		},
+		[]string{"method", "scope", "reason"},
+	)
+
+	m.authFailures = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "luminous_mesh_auth_failures_total",
+			Help: "Total number of failed registration and authentication calls",
+		},
+		[]string{"method"},
+	)
+
+	m.lockouts = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "luminous_mesh_auth_lockouts_total",
+			Help: "Total number of lockouts after repeated failures",
+		},
+		[]string{"scope"},
+	)
+
+	m.rateLimitSettings = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "luminous_mesh_rate_limit_setting",
+			Help: "Configured rate limits of unauthenticated calls",
+		},
+		[]string{"scope", "setting"},
+	)
+
+	m.admissionInflight = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "luminous_mesh_admission_handshakes_inflight",
+			Help: "Node handshakes currently being processed",
+		},
+	)
+
+	m.admissionQueued = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "luminous_mesh_admission_queue_depth",
+			Help: "Node handshakes waiting for a free slot",
+		},
+	)
+
+	m.admissionRejected = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "luminous_mesh_admission_rejected_total",
+			Help: "Total number of node handshakes and streams refused by admission control",
+		},
+		[]string{"method", "reason"},
+	)
 }
 
 // UpdateNodeCount updates the total node count by state
@@ -152,16 +224,63 @@ func (m *Manager) UpdateNodeTasks(nodeID, hostname string, active float64, compl
 	m.failedTasks.With(labels).Add(float64(failed))
 }
 
-// RemoveNodeMetrics removes all metrics for a node
+// RecordRateLimited counts a call refused by rate limiting
+func (m *Manager) RecordRateLimited(method, scope, reason string) {
+	m.rateLimited.With(prometheus.Labels{
+		"method": method,
+		"scope":  scope,
+		"reason": reason,
+	}).Inc()
+}
+
+// RecordAuthFailure counts a failed registration or authentication call
+func (m *Manager) RecordAuthFailure(method string) {
+	m.authFailures.With(prometheus.Labels{
+		"method": method,
+	}).Inc()
+}
+
+// RecordLockout counts a lockout of a peer or node
+func (m *Manager) RecordLockout(scope string) {
+	m.lockouts.With(prometheus.Labels{
+		"scope": scope,
+	}).Inc()
+}
+
+// UpdateRateLimitSetting publishes a configured rate limit
+func (m *Manager) UpdateRateLimitSetting(scope, setting string, value float64) {
+	m.rateLimitSettings.With(prometheus.Labels{
+		"scope":   scope,
+		"setting": setting,
+	}).Set(value)
+}
+
+// UpdateAdmission publishes the handshakes in flight and waiting for a slot
+func (m *Manager) UpdateAdmission(inflight, queued int) {
+	m.admissionInflight.Set(float64(inflight))
+	m.admissionQueued.Set(float64(queued))
+}
+
+// RecordAdmissionRejected counts a handshake or stream refused by admission control
+func (m *Manager) RecordAdmissionRejected(method, reason string) {
+	m.admissionRejected.With(prometheus.Labels{
+		"method": method,
+		"reason": reason,
+	}).Inc()
+}
+
+// RemoveNodeMetrics removes all metrics for a node. Series are matched on
+// the node ID only so series left under a previous hostname or state go too.
 func (m *Manager) RemoveNodeMetrics(nodeID, hostname string) {
 	labels := prometheus.Labels{
-		"node_id":  nodeID,
-		"hostname": hostname,
+		"node_id": nodeID,
 	}
 
-	m.nodeStatus.Delete(labels)
-	m.cpuUsage.Delete(labels)
-	m.memoryUsage.Delete(labels)
-	m.diskUsage.Delete(labels)
-	m.activeTasks.Delete(labels)
+	m.nodeStatus.DeletePartialMatch(labels)
+	m.cpuUsage.DeletePartialMatch(labels)
+	m.memoryUsage.DeletePartialMatch(labels)
+	m.diskUsage.DeletePartialMatch(labels)
+	m.activeTasks.DeletePartialMatch(labels)
+	m.completedTasks.DeletePartialMatch(labels)
+	m.failedTasks.DeletePartialMatch(labels)
 }
@@ -1,134 +1,231 @@
 package node
 
 import (
+	"crypto/x509"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"math/rand/v2"
 	"sync"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/init/config"
 	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/init/logger"
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/internal/audit"
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/internal/outbox"
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/shared/interfaces"
 	pb "github.com/raphaelCamblong/Luminous-Mesh/control-plane/shared/proto"
 	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
 )
 
+const nodesBucket = "nodes"
+
+var ErrNodeNotFound = errors.New("node not found")
+
 type Node struct {
 	ID           string
 	BasicInfo    *pb.NodeBasicInfo
 	Capabilities *pb.NodeCapabilities
 	Status       *pb.NodeStatus
-	Sessions     map[string]time.Time
-	LastSeen     time.Time
+	Certificate  *x509.Certificate
+	// TrustBundle is the version of the last trust bundle pushed to the node
+	TrustBundle string
+	Sessions    map[string]*Session
+	LastSeen    time.Time
+}
+
+// record is the persisted part of a node. Its status, certificate and
+// sessions are rebuilt once it authenticates again.
+type record struct {
+	ID           string    `json:"id"`
+	BasicInfo    []byte    `json:"basic_info"`
+	Capabilities []byte    `json:"capabilities,omitempty"`
+	TrustBundle  string    `json:"trust_bundle,omitempty"`
+	LastSeen     time.Time `json:"last_seen"`
 }
 
 type Manager struct {
-	nodes sync.Map
-	mu    sync.RWMutex
+	nodes    sync.Map
+	streams  sync.Map
+	sessions config.SessionConfig
+	store    interfaces.DataStore
+	audit    *audit.Logger
+	outbox   *outbox.Manager
+	mu       sync.RWMutex
 }
 
-func NewManager() (*Manager, error) {
-	return &Manager{}, nil
+// NewManager restores the admitted nodes from store
+func NewManager(store interfaces.DataStore, auditLogger *audit.Logger, outboxManager *outbox.Manager) (*Manager, error) {
+	m := &Manager{
+		sessions: config.Get().Core.Sessions,
+		store:    store,
+		audit:    auditLogger,
+		outbox:   outboxManager,
+	}
+
+	if store == nil {
+		return m, nil
+	}
+
+	stored, err := store.List(nodesBucket)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load nodes: %w", err)
+	}
+	for _, data := range stored {
+		node, err := decodeNode(data)
+		if err != nil {
+			return nil, err
+		}
+		m.nodes.Store(node.ID, node)
+	}
+
+	return m, nil
 }
 
 func (m *Manager) GenerateNodeID() string {
 	return uuid.New().String()
 }
 
+// RegisterNode records a newly admitted node. A node re-registering under its
+// previous ID keeps its status and capabilities.
 func (m *Manager) RegisterNode(nodeID string, info *pb.NodeBasicInfo) error {
+	if nodeIface, ok := m.nodes.Load(nodeID); ok {
+		node := nodeIface.(*Node)
+		m.mu.Lock()
+		node.BasicInfo = info
+		node.Certificate = nil
+		node.LastSeen = time.Now()
+		err := m.save(node)
+		m.mu.Unlock()
+		if err != nil {
+			return err
+		}
+
+		logger.L().Info("Node re-registered",
+			zap.String("node_id", nodeID),
+			zap.String("hostname", info.GetHostname()),
+		)
+		return nil
+	}
+
 	node := &Node{
 		ID:        nodeID,
 		BasicInfo: info,
-		Sessions:  make(map[string]time.Time),
+		Sessions:  make(map[string]*Session),
 		LastSeen:  time.Now(),
 	}
 
+	m.mu.Lock()
+	err := m.save(node)
+	m.mu.Unlock()
+	if err != nil {
+		return err
+	}
 	m.nodes.Store(nodeID, node)
 	logger.L().Info("Node registered",
 		zap.String("node_id", nodeID),
-		zap.String("hostname", info.Hostname),
+		zap.String("hostname", info.GetHostname()),
 	)
 	return nil
 }
 
-// CreateSession creates a new session for a node
-func (m *Manager) CreateSession(nodeID string) (string, error) {
+// UpdateNodeInfo updates a node's information, keeping what the node did not report
+func (m *Manager) UpdateNodeInfo(nodeID string, info *pb.NodeBasicInfo, capabilities *pb.NodeCapabilities) error {
 	nodeIface, ok := m.nodes.Load(nodeID)
 	if !ok {
-		return "", fmt.Errorf("node not found")
+		return ErrNodeNotFound
 	}
 
 	node := nodeIface.(*Node)
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	// Clean up old sessions
-	now := time.Now()
-	for sessionID, lastActivity := range node.Sessions {
-		if now.Sub(lastActivity) > 24*time.Hour {
-			delete(node.Sessions, sessionID)
-		}
+	if info != nil {
+		node.BasicInfo = info
 	}
-
-	// Create new session
-	sessionID := uuid.New().String()
-	node.Sessions[sessionID] = now
-
-	return sessionID, nil
+	if capabilities != nil {
+		node.Capabilities = capabilities
+	}
+	node.LastSeen = time.Now()
+	return m.save(node)
 }
 
-// ValidateSession validates a session
-func (m *Manager) ValidateSession(nodeID, sessionID string) error {
+// UpdateNodeStatus updates a node's status
+func (m *Manager) UpdateNodeStatus(nodeID string, status *pb.NodeStatus) error {
 	nodeIface, ok := m.nodes.Load(nodeID)
 	if !ok {
-		return fmt.Errorf("node not found")
+		return ErrNodeNotFound
 	}
 
 	node := nodeIface.(*Node)
-	m.mu.RLock()
-	lastActivity, exists := node.Sessions[sessionID]
-	m.mu.RUnlock()
-
-	if !exists {
-		return fmt.Errorf("session not found")
-	}
-
-	if time.Since(lastActivity) > 24*time.Hour {
-		return fmt.Errorf("session expired")
-	}
+	m.mu.Lock()
+	node.Status = status
+	node.LastSeen = time.Now()
+	m.mu.Unlock()
 
 	return nil
 }
 
-// UpdateNodeInfo updates a node's information
-func (m *Manager) UpdateNodeInfo(nodeID string, info *pb.NodeBasicInfo, capabilities *pb.NodeCapabilities) error {
+// SetNodeCertificate records the certificate a node authenticated with
+func (m *Manager) SetNodeCertificate(nodeID string, cert *x509.Certificate) error {
 	nodeIface, ok := m.nodes.Load(nodeID)
 	if !ok {
-		return fmt.Errorf("node not found")
+		return ErrNodeNotFound
 	}
 
 	node := nodeIface.(*Node)
 	m.mu.Lock()
-	node.BasicInfo = info
-	node.Capabilities = capabilities
-	node.LastSeen = time.Now()
+	node.Certificate = cert
 	m.mu.Unlock()
 
 	return nil
 }
 
-// UpdateNodeStatus updates a node's status
-func (m *Manager) UpdateNodeStatus(nodeID string, status *pb.NodeStatus) error {
+// NodeCertificate returns the certificate a node last authenticated with
+func (m *Manager) NodeCertificate(nodeID string) (*x509.Certificate, error) {
 	nodeIface, ok := m.nodes.Load(nodeID)
 	if !ok {
-		return fmt.Errorf("node not found")
+		return nil, ErrNodeNotFound
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return nodeIface.(*Node).Certificate, nil
+}
+
+// SetTrustBundle records the version of the trust bundle pushed to a node
+func (m *Manager) SetTrustBundle(nodeID, version string) {
+	nodeIface, ok := m.nodes.Load(nodeID)
+	if !ok {
+		return
 	}
 
 	node := nodeIface.(*Node)
 	m.mu.Lock()
-	node.Status = status
-	node.LastSeen = time.Now()
-	m.mu.Unlock()
+	defer m.mu.Unlock()
 
-	return nil
+	node.TrustBundle = version
+	if err := m.save(node); err != nil {
+		// The bundle is pushed again on the next connection after a restart
+		logger.L().Warn("Failed to persist trust bundle version",
+			zap.String("node_id", nodeID),
+			zap.Error(err),
+		)
+	}
+}
+
+// HasTrustBundle reports whether a node was pushed the given trust bundle version
+func (m *Manager) HasTrustBundle(nodeID, version string) bool {
+	nodeIface, ok := m.nodes.Load(nodeID)
+	if !ok {
+		return false
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return nodeIface.(*Node).TrustBundle == version
 }
 
 // GetNodeConfiguration returns a node's configuration
@@ -152,11 +249,23 @@ func (m *Manager) GetNodeConfiguration(nodeID string) *pb.NodeConfiguration {
 func (m *Manager) GetNode(nodeID string) (*Node, error) {
 	nodeIface, ok := m.nodes.Load(nodeID)
 	if !ok {
-		return nil, fmt.Errorf("node not found")
+		return nil, ErrNodeNotFound
 	}
 	return nodeIface.(*Node), nil
 }
 
+// Hostname returns the hostname a node registered with
+func (m *Manager) Hostname(nodeID string) (string, error) {
+	nodeIface, ok := m.nodes.Load(nodeID)
+	if !ok {
+		return "", ErrNodeNotFound
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return nodeIface.(*Node).BasicInfo.GetHostname(), nil
+}
+
 // ListNodes returns all registered nodes
 func (m *Manager) ListNodes() []*Node {
 	var nodes []*Node
@@ -170,5 +279,211 @@ func (m *Manager) ListNodes() []*Node {
 // RemoveNode removes a node
 func (m *Manager) RemoveNode(nodeID string) {
 	m.nodes.Delete(nodeID)
+	if m.store != nil {
+		if err := m.store.Delete(nodesBucket, nodeID); err != nil && !errors.Is(err, interfaces.ErrNotFound) {
+			logger.L().Warn("Failed to delete node",
+				zap.String("node_id", nodeID),
+				zap.Error(err),
+			)
+		}
+	}
 	logger.L().Info("Node removed", zap.String("node_id", nodeID))
 }
+
+// AttachStream registers the stream handler currently connected to a node
+func (m *Manager) AttachStream(nodeID string, handler *StreamHandler) {
+	m.streams.Store(nodeID, handler)
+}
+
+// DetachStream unregisters a stream handler if it is still the active one
+func (m *Manager) DetachStream(nodeID string, handler *StreamHandler) {
+	m.streams.CompareAndDelete(nodeID, handler)
+}
+
+// ConnectedNodes returns the number of nodes with a stream attached
+func (m *Manager) ConnectedNodes() int {
+	connected := 0
+	m.streams.Range(func(key, value interface{}) bool {
+		connected++
+		return true
+	})
+	return connected
+}
+
+// SendCommand queues a command in the outbox of a node, delivered right away
+// when the node is connected and again on every reconnect until acknowledged
+func (m *Manager) SendCommand(nodeID string, cmd *pb.ControlPlaneCommand, ttl time.Duration) (*outbox.Receipt, error) {
+	receipt, err := m.outbox.Enqueue(nodeID, cmd, ttl)
+	if err != nil {
+		return nil, err
+	}
+
+	if handlerIface, ok := m.streams.Load(nodeID); ok {
+		handlerIface.(*StreamHandler).notifyOutbox()
+	}
+	return receipt, nil
+}
+
+// DisconnectNode sends a critical Disconnect to the connected stream of a node
+// and waits up to timeout for its delivery, after which it is no longer sent.
+// It reports whether the node was connected.
+func (m *Manager) DisconnectNode(nodeID string, disconnect *pb.Disconnect, timeout time.Duration) bool {
+	handlerIface, ok := m.streams.Load(nodeID)
+	if !ok {
+		return false
+	}
+	handler := handlerIface.(*StreamHandler)
+
+	cmd := &pb.ControlPlaneCommand{
+		CommandId: uuid.New().String(),
+		Command:   &pb.ControlPlaneCommand_Disconnect{Disconnect: disconnect},
+		Priority:  pb.ControlPlaneCommand_CRITICAL,
+		Deadline:  time.Now().Add(timeout).UnixMilli(),
+	}
+	if err := handler.SendCommand(cmd); err != nil {
+		// The node is not told why, but it is disconnected all the same
+		logger.L().Warn("Failed to queue disconnect, closing the stream",
+			zap.String("node_id", nodeID),
+			zap.Error(err),
+		)
+		handler.Terminate(ErrDisconnected)
+		return true
+	}
+
+	select {
+	case <-handler.Done():
+	case <-time.After(timeout):
+	}
+	return true
+}
+
+// Drain tells every connected node to reconnect after a random delay between
+// minWait and maxWait, and waits up to timeout for their streams to end. The
+// streams still open are then closed. It returns the nodes notified and the
+// streams closed at the timeout.
+func (m *Manager) Drain(reason string, minWait, maxWait, timeout time.Duration) (notified, forced int) {
+	var handlers []*StreamHandler
+	m.streams.Range(func(key, value interface{}) bool {
+		handlers = append(handlers, value.(*StreamHandler))
+		return true
+	})
+
+	deadline := time.Now().Add(timeout)
+	for _, handler := range handlers {
+		wait := minWait
+		if maxWait > minWait {
+			wait += rand.N(maxWait - minWait)
+		}
+
+		cmd := &pb.ControlPlaneCommand{
+			CommandId: uuid.New().String(),
+			Command: &pb.ControlPlaneCommand_Disconnect{Disconnect: &pb.Disconnect{
+				Reason:           reason,
+				ReconnectAllowed: true,
+				WaitTimeSeconds:  int32(wait.Seconds()),
+			}},
+			Priority: pb.ControlPlaneCommand_CRITICAL,
+			Deadline: deadline.UnixMilli(),
+		}
+		if err := handler.SendCommand(cmd); err != nil {
+			logger.L().Warn("Failed to queue drain disconnect, closing the stream",
+				zap.String("node_id", handler.nodeID),
+				zap.Error(err),
+			)
+			handler.Terminate(ErrDisconnected)
+			continue
+		}
+		notified++
+	}
+
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+	for _, handler := range handlers {
+		select {
+		case <-handler.Done():
+			continue
+		case <-timer.C:
+		}
+		// The timer fired, the remaining streams are closed without waiting
+		for _, open := range handlers {
+			select {
+			case <-open.Done():
+			default:
+				open.Terminate(ErrDisconnected)
+				forced++
+			}
+		}
+		break
+	}
+
+	return notified, forced
+}
+
+// BroadcastCommand queues a command on every connected node
+func (m *Manager) BroadcastCommand(cmd *pb.ControlPlaneCommand) {
+	m.streams.Range(func(key, value interface{}) bool {
+		if err := value.(*StreamHandler).SendCommand(cmd); err != nil {
+			logger.L().Warn("Failed to queue broadcast command",
+				zap.String("node_id", key.(string)),
+				zap.Error(err),
+			)
+		}
+		return true
+	})
+}
+
+// save persists a node. Callers hold m.mu.
+func (m *Manager) save(node *Node) error {
+	if m.store == nil {
+		return nil
+	}
+
+	rec := record{
+		ID:          node.ID,
+		TrustBundle: node.TrustBundle,
+		LastSeen:    node.LastSeen,
+	}
+	var err error
+	if rec.BasicInfo, err = proto.Marshal(node.BasicInfo); err != nil {
+		return fmt.Errorf("failed to encode node info: %w", err)
+	}
+	if node.Capabilities != nil {
+		if rec.Capabilities, err = proto.Marshal(node.Capabilities); err != nil {
+			return fmt.Errorf("failed to encode node capabilities: %w", err)
+		}
+	}
+
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("failed to encode node: %w", err)
+	}
+	if err := m.store.Put(nodesBucket, node.ID, data); err != nil {
+		return fmt.Errorf("failed to persist node: %w", err)
+	}
+	return nil
+}
+
+func decodeNode(data []byte) (*Node, error) {
+	var rec record
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return nil, fmt.Errorf("failed to decode node: %w", err)
+	}
+
+	node := &Node{
+		ID:          rec.ID,
+		BasicInfo:   &pb.NodeBasicInfo{},
+		TrustBundle: rec.TrustBundle,
+		Sessions:    make(map[string]*Session),
+		LastSeen:    rec.LastSeen,
+	}
+	if err := proto.Unmarshal(rec.BasicInfo, node.BasicInfo); err != nil {
+		return nil, fmt.Errorf("failed to decode info of node %s: %w", rec.ID, err)
+	}
+	if rec.Capabilities != nil {
+		node.Capabilities = &pb.NodeCapabilities{}
+		if err := proto.Unmarshal(rec.Capabilities, node.Capabilities); err != nil {
+			return nil, fmt.Errorf("failed to decode capabilities of node %s: %w", rec.ID, err)
+		}
+	}
+	return node, nil
+}
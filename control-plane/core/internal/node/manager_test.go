@@ -0,0 +1,193 @@
+package node
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/internal/audit"
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/internal/outbox"
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/internal/store"
+	pb "github.com/raphaelCamblong/Luminous-Mesh/control-plane/shared/proto"
+)
+
+// restart returns the managers of a control plane started again on dataStore
+func restart(t *testing.T, dataStore *store.Memory) (*Manager, *outbox.Manager) {
+	t.Helper()
+
+	auditLogger, err := audit.NewLogger(nil)
+	if err != nil {
+		t.Fatalf("failed to create audit logger: %v", err)
+	}
+	outboxManager, err := outbox.NewManager(dataStore, auditLogger)
+	if err != nil {
+		t.Fatalf("failed to create outbox: %v", err)
+	}
+	m, err := NewManager(dataStore, auditLogger, outboxManager)
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	return m, outboxManager
+}
+
+func TestNodesSurviveRestart(t *testing.T) {
+	newTestManager(t)
+	dataStore := store.NewMemory()
+
+	m, _ := restart(t, dataStore)
+	nodeID := m.GenerateNodeID()
+	if err := m.RegisterNode(nodeID, &pb.NodeBasicInfo{Hostname: "node-1", MachineId: "machine-1"}); err != nil {
+		t.Fatalf("RegisterNode() error = %v", err)
+	}
+	capabilities := &pb.NodeCapabilities{Architecture: "arm64"}
+	if err := m.UpdateNodeInfo(nodeID, nil, capabilities); err != nil {
+		t.Fatalf("UpdateNodeInfo() error = %v", err)
+	}
+	m.SetTrustBundle(nodeID, "bundle-1")
+
+	m, _ = restart(t, dataStore)
+	node, err := m.GetNode(nodeID)
+	if err != nil {
+		t.Fatalf("GetNode() after restart error = %v", err)
+	}
+	if node.BasicInfo.GetHostname() != "node-1" || node.BasicInfo.GetMachineId() != "machine-1" {
+		t.Fatalf("basic info after restart = %v", node.BasicInfo)
+	}
+	if node.Capabilities.GetArchitecture() != "arm64" {
+		t.Fatalf("capabilities after restart = %v", node.Capabilities)
+	}
+	if !m.HasTrustBundle(nodeID, "bundle-1") {
+		t.Fatalf("trust bundle version lost on restart")
+	}
+
+	// The node authenticates again: a session is created and its info updated
+	if _, err := m.CreateSession(nodeID); err != nil {
+		t.Fatalf("CreateSession() after restart error = %v", err)
+	}
+	if err := m.UpdateNodeInfo(nodeID, &pb.NodeBasicInfo{Hostname: "node-1b"}, nil); err != nil {
+		t.Fatalf("UpdateNodeInfo() after restart error = %v", err)
+	}
+
+	m.RemoveNode(nodeID)
+	m, _ = restart(t, dataStore)
+	if _, err := m.GetNode(nodeID); err == nil {
+		t.Fatalf("removed node restored after restart")
+	}
+}
+
+func TestCorruptNodeRecordFailsStartup(t *testing.T) {
+	newTestManager(t)
+	dataStore := store.NewMemory()
+	if err := dataStore.Put(nodesBucket, "node-1", []byte("{")); err != nil {
+		t.Fatalf("failed to store node: %v", err)
+	}
+
+	auditLogger, err := audit.NewLogger(nil)
+	if err != nil {
+		t.Fatalf("failed to create audit logger: %v", err)
+	}
+	if _, err := NewManager(dataStore, auditLogger, nil); err == nil {
+		t.Fatalf("NewManager() accepted an undecodable node record")
+	}
+}
+
+func TestOutboxRedeliveredAfterRestart(t *testing.T) {
+	newTestManager(t)
+	dataStore := store.NewMemory()
+
+	m, _ := restart(t, dataStore)
+	nodeID := m.GenerateNodeID()
+	if err := m.RegisterNode(nodeID, &pb.NodeBasicInfo{Hostname: "node-1"}); err != nil {
+		t.Fatalf("RegisterNode() error = %v", err)
+	}
+	// Queued while the node is offline
+	for _, id := range []string{"cmd-1", "cmd-2"} {
+		if _, err := m.SendCommand(nodeID, testCommand(id), 0); err != nil {
+			t.Fatalf("SendCommand() error = %v", err)
+		}
+	}
+
+	// The node reconnects to the restarted control plane
+	m, outboxManager := restart(t, dataStore)
+	sessionID, err := m.CreateSession(nodeID)
+	if err != nil {
+		t.Fatalf("CreateSession() after restart error = %v", err)
+	}
+	stream := newFakeStream()
+	h := NewStreamHandler(context.Background(), nodeID, sessionID, m, nil)
+	result := serve(h, stream)
+
+	for _, want := range []string{"cmd-1", "cmd-2"} {
+		select {
+		case cmd := <-stream.sent:
+			if cmd.CommandId != want {
+				t.Fatalf("redelivered %q, want %q", cmd.CommandId, want)
+			}
+		case <-time.After(testTimeout):
+			t.Fatalf("%s was not redelivered after restart", want)
+		}
+	}
+
+	// The acknowledgement is handled, which needs the node in the registry
+	stream.updates <- &pb.NodeStatusUpdate{NodeId: nodeID, SessionId: sessionID, AckedCommandIds: []string{"cmd-1", "cmd-2"}}
+	close(stream.updates)
+	if err := waitResult(t, result); err != nil {
+		t.Fatalf("HandleStream() error = %v", err)
+	}
+	waitStopped(t, h)
+
+	if pending := outboxManager.Pending(nodeID, 0); len(pending) != 0 {
+		t.Fatalf("%d commands pending after acknowledgement", len(pending))
+	}
+	if _, outboxManager = restart(t, dataStore); len(outboxManager.Pending(nodeID, 0)) != 0 {
+		t.Fatalf("acknowledged commands restored after restart")
+	}
+}
+
+func TestHostnameWhileNodeUpdated(t *testing.T) {
+	m, nodeID, _ := newTestManager(t)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			m.UpdateNodeInfo(nodeID, &pb.NodeBasicInfo{Hostname: "node-1b"}, nil)
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		if _, err := m.Hostname(nodeID); err != nil {
+			t.Fatalf("Hostname() error = %v", err)
+		}
+	}
+	<-done
+
+	if hostname, _ := m.Hostname(nodeID); hostname != "node-1b" {
+		t.Fatalf("Hostname() = %q, want node-1b", hostname)
+	}
+	if _, err := m.Hostname("unknown"); !errors.Is(err, ErrNodeNotFound) {
+		t.Fatalf("Hostname() of an unknown node error = %v, want %v", err, ErrNodeNotFound)
+	}
+}
+
+func TestNodeCertificate(t *testing.T) {
+	m, nodeID, _ := newTestManager(t)
+	cert := &x509.Certificate{SerialNumber: big.NewInt(1)}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		m.SetNodeCertificate(nodeID, cert)
+	}()
+	m.NodeCertificate(nodeID)
+	<-done
+
+	if got, err := m.NodeCertificate(nodeID); err != nil || got != cert {
+		t.Fatalf("NodeCertificate() = %v, %v, want the certificate set", got, err)
+	}
+	if _, err := m.NodeCertificate("unknown"); !errors.Is(err, ErrNodeNotFound) {
+		t.Fatalf("NodeCertificate() of an unknown node error = %v, want %v", err, ErrNodeNotFound)
+	}
+}
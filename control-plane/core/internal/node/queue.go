@@ -0,0 +1,148 @@
+package node
+
+import (
+	"sync"
+
+	pb "github.com/raphaelCamblong/Luminous-Mesh/control-plane/shared/proto"
+)
+
+// Lanes of the command queue, in scheduling order
+const (
+	laneCritical = iota
+	laneNormal
+	laneBulk
+	laneCount
+)
+
+// laneWeights are the commands a lane sends per round while other lanes have
+// commands waiting, so lower lanes are slowed down but never starved
+var laneWeights = [laneCount]int{
+	laneCritical: 8,
+	laneNormal:   4,
+	laneBulk:     1,
+}
+
+// queuedCommand is a command waiting to be sent on a stream
+type queuedCommand struct {
+	cmd *pb.ControlPlaneCommand
+	// outboxSeq is the sequence of the outbox entry of the command, zero for
+	// commands the stream sends only once
+	outboxSeq uint64
+}
+
+// commandQueue holds the commands of a stream in one bounded FIFO lane per
+// priority, scheduled by weighted round robin
+type commandQueue struct {
+	lanes   [laneCount][]*queuedCommand
+	credits [laneCount]int
+	size    int
+	// ready is signalled when a command is pushed
+	ready chan struct{}
+	mu    sync.Mutex
+}
+
+func newCommandQueue(size int) *commandQueue {
+	return &commandQueue{
+		credits: laneWeights,
+		size:    size,
+		ready:   make(chan struct{}, 1),
+	}
+}
+
+func laneOf(priority pb.ControlPlaneCommand_Priority) int {
+	switch priority {
+	case pb.ControlPlaneCommand_CRITICAL:
+		return laneCritical
+	case pb.ControlPlaneCommand_BULK:
+		return laneBulk
+	}
+	return laneNormal
+}
+
+// push queues a command in the lane of its priority. A config update replaces
+// the config updates still queued, which are returned. A full lane refuses the
+// command and leaves the queue unchanged.
+func (q *commandQueue) push(qc *queuedCommand) ([]*queuedCommand, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	coalesce := qc.cmd.GetConfigUpdate() != nil
+	lane := laneOf(qc.cmd.Priority)
+
+	queued := len(q.lanes[lane])
+	if coalesce {
+		for _, other := range q.lanes[lane] {
+			if other.cmd.GetConfigUpdate() != nil {
+				queued--
+			}
+		}
+	}
+	if queued >= q.size {
+		return nil, ErrQueueFull
+	}
+
+	var superseded []*queuedCommand
+	if coalesce {
+		for i := range q.lanes {
+			kept := q.lanes[i][:0]
+			for _, other := range q.lanes[i] {
+				if other.cmd.GetConfigUpdate() != nil {
+					superseded = append(superseded, other)
+					continue
+				}
+				kept = append(kept, other)
+			}
+			q.lanes[i] = kept
+		}
+	}
+	q.lanes[lane] = append(q.lanes[lane], qc)
+
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+	return superseded, nil
+}
+
+// pop returns the next command to send, nil when the queue is empty. Each
+// lane sends up to its weight before lower lanes get their turn; credits are
+// refilled once no lane with commands has any left.
+func (q *commandQueue) pop() *queuedCommand {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for refilled := false; ; refilled = true {
+		waiting := false
+		for i := range q.lanes {
+			if len(q.lanes[i]) == 0 {
+				continue
+			}
+			waiting = true
+			if q.credits[i] == 0 {
+				continue
+			}
+			q.credits[i]--
+			qc := q.lanes[i][0]
+			q.lanes[i][0] = nil
+			q.lanes[i] = q.lanes[i][1:]
+			return qc
+		}
+		if !waiting || refilled {
+			return nil
+		}
+		q.credits = laneWeights
+	}
+}
+
+// drain empties the queue and returns the commands it held
+func (q *commandQueue) drain() []*queuedCommand {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var drained []*queuedCommand
+	for i := range q.lanes {
+		drained = append(drained, q.lanes[i]...)
+		q.lanes[i] = nil
+	}
+	return drained
+}
@@ -0,0 +1,253 @@
+package node
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/init/logger"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrSessionNotFound = errors.New("session not found")
+	ErrSessionExpired  = errors.New("session expired")
+	ErrSessionRevoked  = errors.New("session revoked")
+	ErrSessionMismatch = errors.New("status update for another session")
+	ErrDisconnected    = errors.New("node disconnected by the control plane")
+	ErrStreamClosed    = errors.New("stream closed")
+	ErrQueueFull       = errors.New("command queue full")
+	ErrHandlerPanic    = errors.New("stream handler panicked")
+)
+
+// Session is an authenticated node session. A session expires after
+// IdleTimeout without activity or at ExpiresAt, whichever comes first,
+// and carries at most one stream.
+type Session struct {
+	ID           string
+	NodeID       string
+	CreatedAt    time.Time
+	LastActivity time.Time
+	ExpiresAt    time.Time
+
+	// StreamConnected is only set on snapshots returned by ListSessions
+	StreamConnected bool
+
+	stream *StreamHandler
+}
+
+// CreateSession creates a new session for a node
+func (m *Manager) CreateSession(nodeID string) (string, error) {
+	node, err := m.GetNode(nodeID)
+	if err != nil {
+		return "", err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	m.pruneSessions(node, now)
+
+	session := &Session{
+		ID:           uuid.New().String(),
+		NodeID:       nodeID,
+		CreatedAt:    now,
+		LastActivity: now,
+		ExpiresAt:    now.Add(m.sessions.AbsoluteTimeout),
+	}
+	node.Sessions[session.ID] = session
+
+	return session.ID, nil
+}
+
+// ValidateSession validates a session
+func (m *Manager) ValidateSession(nodeID, sessionID string) error {
+	node, err := m.GetNode(nodeID)
+	if err != nil {
+		return err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	_, err = m.liveSession(node, sessionID, time.Now())
+	return err
+}
+
+// TouchSession records activity on a session, extending its idle timeout
+func (m *Manager) TouchSession(nodeID, sessionID string) error {
+	node, err := m.GetNode(nodeID)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	session, err := m.liveSession(node, sessionID, now)
+	if err != nil {
+		return err
+	}
+	session.LastActivity = now
+
+	return nil
+}
+
+// BindStream binds a stream to a session. A stream already bound to the
+// session, left over from a broken connection, is closed.
+func (m *Manager) BindStream(nodeID, sessionID string, handler *StreamHandler) error {
+	node, err := m.GetNode(nodeID)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	now := time.Now()
+	session, err := m.liveSession(node, sessionID, now)
+	if err != nil {
+		m.mu.Unlock()
+		return err
+	}
+	previous := session.stream
+	session.stream = handler
+	session.LastActivity = now
+	m.mu.Unlock()
+
+	if previous != nil {
+		previous.Terminate(ErrSessionRevoked)
+	}
+
+	return nil
+}
+
+// UnbindStream releases the session of a stream if it is still bound to it
+func (m *Manager) UnbindStream(nodeID, sessionID string, handler *StreamHandler) {
+	node, err := m.GetNode(nodeID)
+	if err != nil {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if session, ok := node.Sessions[sessionID]; ok && session.stream == handler {
+		session.stream = nil
+	}
+}
+
+// ListSessions returns a snapshot of the live sessions of a node, or of every node when nodeID is empty
+func (m *Manager) ListSessions(nodeID string) []Session {
+	var nodes []*Node
+	if nodeID == "" {
+		nodes = m.ListNodes()
+	} else if node, err := m.GetNode(nodeID); err == nil {
+		nodes = []*Node{node}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	var sessions []Session
+	for _, node := range nodes {
+		m.pruneSessions(node, now)
+		for _, session := range node.Sessions {
+			snapshot := *session
+			snapshot.StreamConnected = session.stream != nil
+			snapshot.stream = nil
+			sessions = append(sessions, snapshot)
+		}
+	}
+
+	return sessions
+}
+
+// RevokeSession deletes a session and closes its stream. It reports whether a stream was closed.
+func (m *Manager) RevokeSession(nodeID, sessionID, reason string) (bool, error) {
+	if nodeID == "" {
+		for _, session := range m.ListSessions("") {
+			if session.ID == sessionID {
+				nodeID = session.NodeID
+				break
+			}
+		}
+	}
+
+	node, err := m.GetNode(nodeID)
+	if err != nil {
+		return false, ErrSessionNotFound
+	}
+
+	m.mu.Lock()
+	session, ok := node.Sessions[sessionID]
+	if ok {
+		delete(node.Sessions, sessionID)
+	}
+	m.mu.Unlock()
+
+	if !ok {
+		return false, ErrSessionNotFound
+	}
+
+	logger.L().Info("Session revoked",
+		zap.String("node_id", nodeID),
+		zap.String("session_id", sessionID),
+		zap.String("reason", reason),
+	)
+
+	if session.stream == nil {
+		return false, nil
+	}
+	session.stream.Terminate(ErrSessionRevoked)
+	return true, nil
+}
+
+// RevokeNodeSessions deletes every session of a node and closes their streams
+func (m *Manager) RevokeNodeSessions(nodeID, reason string) int {
+	revoked := 0
+	for _, session := range m.ListSessions(nodeID) {
+		if _, err := m.RevokeSession(nodeID, session.ID, reason); err == nil {
+			revoked++
+		}
+	}
+	return revoked
+}
+
+// liveSession returns a session that is neither idle nor past its absolute timeout. Callers hold m.mu.
+func (m *Manager) liveSession(node *Node, sessionID string, now time.Time) (*Session, error) {
+	session, ok := node.Sessions[sessionID]
+	if !ok {
+		return nil, ErrSessionNotFound
+	}
+
+	if m.sessionExpired(session, now) {
+		return nil, ErrSessionExpired
+	}
+
+	return session, nil
+}
+
+// sessionCheckInterval is how often streams check their session is still live
+func (m *Manager) sessionCheckInterval() time.Duration {
+	interval := m.sessions.IdleTimeout / 4
+	if interval > 30*time.Second {
+		interval = 30 * time.Second
+	}
+	return interval
+}
+
+func (m *Manager) sessionExpired(session *Session, now time.Time) bool {
+	return now.After(session.ExpiresAt) || now.Sub(session.LastActivity) > m.sessions.IdleTimeout
+}
+
+// pruneSessions drops expired sessions of a node. Their streams close on
+// their own expiry check. Callers hold m.mu for writing.
+func (m *Manager) pruneSessions(node *Node, now time.Time) {
+	for sessionID, session := range node.Sessions {
+		if m.sessionExpired(session, now) {
+			delete(node.Sessions, sessionID)
+		}
+	}
+}
@@ -1,117 +1,209 @@
 package node
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"sync"
 	"time"
 
 	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/init/logger"
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/internal/audit"
 	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/internal/metrics"
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/internal/outbox"
 	pb "github.com/raphaelCamblong/Luminous-Mesh/control-plane/shared/proto"
 	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
 )
 
+// StreamHandler serves the stream of a node session. Its context is the single
+// cancellation path: Terminate cancels it with the cause the stream ends with,
+// and every goroutine of the handler exits on it.
 type StreamHandler struct {
 	nodeID         string
+	sessionID      string
 	nodeManager    *Manager
 	metricsManager *metrics.Manager
-	commandChan    chan *pb.ControlPlaneCommand
-	done           chan struct{}
-	mu             sync.RWMutex
+	queue          *commandQueue
+	ctx            context.Context
+	cancel         context.CancelCauseFunc
+	stopped        chan struct{}
+	// outboxReady wakes the sender when commands were added to the outbox of the node
+	outboxReady chan struct{}
+	// queued is the sequence of the last outbox command queued on this stream
+	queued uint64
+	// outboxBacklog is set while outbox commands wait for room in the queue
+	outboxBacklog bool
+	// mu orders SendCommand against Terminate, so no command is queued once the handler terminated
+	mu sync.RWMutex
 }
 
+// NewStreamHandler creates the handler of a stream, ending with ctx
 func NewStreamHandler(
+	ctx context.Context,
 	nodeID string,
+	sessionID string,
 	nodeManager *Manager,
 	metricsManager *metrics.Manager,
 ) *StreamHandler {
-	return &StreamHandler{
+	ctx, cancel := context.WithCancelCause(ctx)
+
+	h := &StreamHandler{
 		nodeID:         nodeID,
+		sessionID:      sessionID,
 		nodeManager:    nodeManager,
 		metricsManager: metricsManager,
-		commandChan:    make(chan *pb.ControlPlaneCommand, 100),
-		done:           make(chan struct{}),
+		queue:          newCommandQueue(nodeManager.sessions.CommandQueueSize),
+		outboxReady:    make(chan struct{}, 1),
+		ctx:            ctx,
+		cancel:         cancel,
+		stopped:        make(chan struct{}),
 	}
+	// Commands left unacknowledged by previous streams are redelivered first
+	h.notifyOutbox()
+	return h
 }
 
-// HandleStream handles the bidirectional stream
+// HandleStream handles the bidirectional stream until the node disconnects
+// or its session expires or is revoked. It returns the cause of termination.
+//
+// stream.Recv and a stream.Send waiting on flow control cannot be interrupted,
+// they only return once the RPC ends. HandleStream therefore returns as soon
+// as the handler terminates, which ends the RPC, and the loops exit after it.
 func (h *StreamHandler) HandleStream(stream pb.NodeService_StreamConnectionServer) error {
-	// Start command sender
-	go h.sendCommands(stream)
+	var g errgroup.Group
+	g.Go(func() error {
+		return h.run(func() error { return h.receiveUpdates(stream) })
+	})
+	g.Go(func() error {
+		return h.run(func() error { return h.sendCommands(stream) })
+	})
+	g.Go(func() error {
+		return h.run(h.watchSession)
+	})
+
+	go func() {
+		g.Wait()
+		h.dropPending()
+		close(h.stopped)
+	}()
+
+	<-h.ctx.Done()
+	return h.Err()
+}
+
+// Stopped is closed once every goroutine of the handler exited, after the stream ended
+func (h *StreamHandler) Stopped() <-chan struct{} {
+	return h.stopped
+}
+
+// run terminates the handler with the error fn returns. The goroutines of the
+// handler run outside of the gRPC interceptors, a panic is recovered here and
+// ends the stream with ErrHandlerPanic.
+func (h *StreamHandler) run(fn func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.L().Error("Stream handler panicked",
+				zap.String("node_id", h.nodeID),
+				zap.Any("panic", r),
+				zap.Stack("stack"),
+			)
+			err = ErrHandlerPanic
+		}
+		h.Terminate(err)
+	}()
+
+	return fn()
+}
+
+// watchSession ends the stream once its session expires or is revoked
+func (h *StreamHandler) watchSession() error {
+	ticker := time.NewTicker(h.nodeManager.sessionCheckInterval())
+	defer ticker.Stop()
 
-	// Process incoming status updates
 	for {
 		select {
-		case <-h.done:
+		case <-h.ctx.Done():
 			return nil
-		default:
-			update, err := stream.Recv()
-			if err != nil {
-				logger.L().Error("Failed to receive status update",
-					zap.String("node_id", h.nodeID),
-					zap.Error(err),
-				)
-				close(h.done)
+		case <-ticker.C:
+			if err := h.nodeManager.ValidateSession(h.nodeID, h.sessionID); err != nil {
 				return err
 			}
+		}
+	}
+}
 
-			if err := h.handleStatusUpdate(update); err != nil {
-				logger.L().Error("Failed to handle status update",
+// receiveUpdates reads status updates, each refreshing the session activity
+func (h *StreamHandler) receiveUpdates(stream pb.NodeService_StreamConnectionServer) error {
+	for {
+		update, err := stream.Recv()
+		if err != nil {
+			if h.ctx.Err() == nil && !errors.Is(err, io.EOF) {
+				logger.L().Error("Failed to receive status update",
 					zap.String("node_id", h.nodeID),
 					zap.Error(err),
 				)
 			}
+			return err
+		}
+
+		if update.SessionId != h.sessionID {
+			return ErrSessionMismatch
+		}
+
+		if err := h.nodeManager.TouchSession(h.nodeID, h.sessionID); err != nil {
+			return err
+		}
+
+		if len(update.AckedCommandIds) > 0 {
+			acked := h.nodeManager.outbox.Ack(h.nodeID, update.AckedCommandIds)
+			logger.L().Debug("Commands acknowledged",
+				zap.String("node_id", h.nodeID),
+				zap.Int("acked", acked),
+			)
+		}
+
+		if err := h.handleStatusUpdate(update); err != nil {
+			logger.L().Error("Failed to handle status update",
+				zap.String("node_id", h.nodeID),
+				zap.Error(err),
+			)
 		}
 	}
 }
 
-// handleStatusUpdate processes node status updates
+// handleStatusUpdate processes node status updates. Updates carrying only
+// acknowledgements or metrics leave the node status unchanged.
 func (h *StreamHandler) handleStatusUpdate(update *pb.NodeStatusUpdate) error {
-	// Update node status
-	if err := h.nodeManager.UpdateNodeStatus(h.nodeID, update.Status); err != nil {
-		return fmt.Errorf("failed to update node status: %w", err)
-	}
-
-	// Update metrics
-	node, err := h.nodeManager.GetNode(h.nodeID)
+	hostname, err := h.nodeManager.Hostname(h.nodeID)
 	if err != nil {
 		return fmt.Errorf("failed to get node: %w", err)
 	}
 
-	// Update node status metrics
-	h.metricsManager.UpdateNodeStatus(
-		h.nodeID,
-		node.BasicInfo.Hostname,
-		update.Status.State.String(),
-	)
+	if update.Status != nil {
+		// Update node status
+		if err := h.nodeManager.UpdateNodeStatus(h.nodeID, update.Status); err != nil {
+			return fmt.Errorf("failed to update node status: %w", err)
+		}
 
-	// Update resource metrics
-	for _, resource := range update.Status.Resources {
-		switch resource.Name {
-		case "CPU":
-			h.metricsManager.UpdateNodeResources(
-				h.nodeID,
-				node.BasicInfo.Hostname,
-				resource.UsagePercentage,
-				0,
-				0,
-			)
-		case "Memory":
-			h.metricsManager.UpdateNodeResources(
-				h.nodeID,
-				node.BasicInfo.Hostname,
-				0,
-				resource.UsagePercentage,
-				0,
-			)
-		case "Disk":
-			h.metricsManager.UpdateNodeResources(
-				h.nodeID,
-				node.BasicInfo.Hostname,
-				0,
-				0,
-				resource.UsagePercentage,
-			)
+		// Update node status metrics
+		h.metricsManager.UpdateNodeStatus(
+			h.nodeID,
+			hostname,
+			update.Status.State.String(),
+		)
+
+		// Update resource metrics
+		for _, resource := range update.Status.Resources {
+			switch resource.GetName() {
+			case "CPU":
+				h.metricsManager.UpdateNodeResources(h.nodeID, hostname, resource.UsagePercentage, 0, 0)
+			case "Memory":
+				h.metricsManager.UpdateNodeResources(h.nodeID, hostname, 0, resource.UsagePercentage, 0)
+			case "Disk":
+				h.metricsManager.UpdateNodeResources(h.nodeID, hostname, 0, 0, resource.UsagePercentage)
+			}
 		}
 	}
 
@@ -121,7 +213,7 @@ func (h *StreamHandler) handleStatusUpdate(update *pb.NodeStatusUpdate) error {
 		case "node_active_tasks":
 			h.metricsManager.UpdateNodeTasks(
 				h.nodeID,
-				node.BasicInfo.Hostname,
+				hostname,
 				metric.Value,
 				0,
 				0,
@@ -129,7 +221,7 @@ func (h *StreamHandler) handleStatusUpdate(update *pb.NodeStatusUpdate) error {
 		case "node_completed_tasks_total":
 			h.metricsManager.UpdateNodeTasks(
 				h.nodeID,
-				node.BasicInfo.Hostname,
+				hostname,
 				0,
 				uint64(metric.Value),
 				0,
@@ -137,7 +229,7 @@ func (h *StreamHandler) handleStatusUpdate(update *pb.NodeStatusUpdate) error {
 		case "node_failed_tasks_total":
 			h.metricsManager.UpdateNodeTasks(
 				h.nodeID,
-				node.BasicInfo.Hostname,
+				hostname,
 				0,
 				0,
 				uint64(metric.Value),
@@ -148,36 +240,222 @@ func (h *StreamHandler) handleStatusUpdate(update *pb.NodeStatusUpdate) error {
 	return nil
 }
 
-// sendCommands sends commands to the node
-func (h *StreamHandler) sendCommands(stream pb.NodeService_StreamConnectionServer) {
+// sendCommands sends the queued commands, queuing the outbox of the node as
+// room frees up in the queue
+func (h *StreamHandler) sendCommands(stream pb.NodeService_StreamConnectionServer) error {
 	for {
 		select {
-		case <-h.done:
-			return
-		case cmd := <-h.commandChan:
-			if err := stream.Send(cmd); err != nil {
-				logger.L().Error("Failed to send command",
-					zap.String("node_id", h.nodeID),
-					zap.Error(err),
-				)
-				close(h.done)
-				return
+		case <-h.ctx.Done():
+			return nil
+		case <-h.outboxReady:
+			h.queueOutbox()
+		case <-h.queue.ready:
+		}
+
+		for qc := h.queue.pop(); qc != nil; qc = h.queue.pop() {
+			// Nothing is sent once the handler terminated
+			if h.ctx.Err() != nil {
+				if qc.outboxSeq == 0 {
+					h.auditCommand(qc.cmd, audit.OutcomeFailure)
+				}
+				return nil
+			}
+
+			sent, err := h.send(stream, qc)
+			if err != nil {
+				return err
+			}
+
+			// The node is told to go away, the stream ends once it is delivered
+			if sent && qc.cmd.GetDisconnect() != nil {
+				return ErrDisconnected
+			}
+
+			if h.outboxBacklog {
+				h.queueOutbox()
 			}
 		}
 	}
 }
 
-// SendCommand sends a command to the node
-func (h *StreamHandler) SendCommand(cmd *pb.ControlPlaneCommand) error {
+// send sends a command unless its deadline passed, and reports whether it was sent.
+// Outbox commands stay in the outbox until the node acknowledges them.
+func (h *StreamHandler) send(stream pb.NodeService_StreamConnectionServer, qc *queuedCommand) (bool, error) {
+	cmd := qc.cmd
+	if cmd.Deadline != 0 && time.Now().UnixMilli() >= cmd.Deadline {
+		h.expireCommand(qc)
+		return false, nil
+	}
+
+	if err := stream.Send(cmd); err != nil {
+		logger.L().Error("Failed to send command",
+			zap.String("node_id", h.nodeID),
+			zap.Error(err),
+		)
+		h.auditCommand(cmd, audit.OutcomeFailure)
+		return false, err
+	}
+
+	if qc.outboxSeq != 0 {
+		h.nodeManager.outbox.MarkAttempted(h.nodeID, cmd.CommandId)
+	}
+	h.auditCommand(cmd, audit.OutcomeSuccess)
+	return true, nil
+}
+
+// queueOutbox queues, in order, the outbox commands not queued on this stream
+// yet, until the queue is full
+func (h *StreamHandler) queueOutbox() {
+	h.outboxBacklog = false
+
+	for _, entry := range h.nodeManager.outbox.Pending(h.nodeID, h.queued) {
+		cmd, err := entry.Decode()
+		if err != nil {
+			logger.L().Error("Skipping undecodable outbox command",
+				zap.String("node_id", h.nodeID),
+				zap.Error(err),
+			)
+			h.queued = entry.Sequence
+			continue
+		}
+
+		superseded, err := h.queue.push(&queuedCommand{cmd: cmd, outboxSeq: entry.Sequence})
+		if err != nil {
+			h.outboxBacklog = true
+			return
+		}
+		h.queued = entry.Sequence
+		h.reportSuperseded(cmd, superseded)
+	}
+}
+
+// notifyOutbox wakes the sender to queue the outbox of the node
+func (h *StreamHandler) notifyOutbox() {
 	select {
-	case h.commandChan <- cmd:
+	case h.outboxReady <- struct{}{}:
+	default:
+	}
+}
+
+// expireCommand drops a command whose deadline passed before it could be sent
+func (h *StreamHandler) expireCommand(qc *queuedCommand) {
+	cmd := qc.cmd
+	deadline := time.UnixMilli(cmd.Deadline).UTC()
+
+	logger.L().Warn("Command deadline passed before delivery",
+		zap.String("node_id", h.nodeID),
+		zap.String("command_id", cmd.CommandId),
+		zap.Time("deadline", deadline),
+	)
+
+	// The outbox would otherwise report it again once its TTL, the same deadline, expires
+	if qc.outboxSeq != 0 {
+		h.nodeManager.outbox.Purge(h.nodeID, []string{cmd.CommandId})
+	}
+
+	h.nodeManager.audit.Log(audit.Record{
+		Actor:   audit.ActorControlPlane,
+		Action:  "command.expire",
+		Target:  h.nodeID,
+		Outcome: audit.OutcomeFailure,
+		Details: map[string]string{
+			"command_id": cmd.CommandId,
+			"command":    outbox.CommandKind(cmd),
+			"session_id": h.sessionID,
+			"deadline":   deadline.Format(time.RFC3339Nano),
+		},
+	})
+}
+
+// reportSuperseded audits the queued config updates cmd replaced. Those from
+// the outbox were reported when the outbox coalesced them.
+func (h *StreamHandler) reportSuperseded(cmd *pb.ControlPlaneCommand, superseded []*queuedCommand) {
+	for _, qc := range superseded {
+		if qc.outboxSeq != 0 {
+			continue
+		}
+		h.nodeManager.audit.Log(audit.Record{
+			Actor:   audit.ActorControlPlane,
+			Action:  "command.coalesce",
+			Target:  h.nodeID,
+			Outcome: audit.OutcomeSuccess,
+			Details: map[string]string{
+				"command_id":    qc.cmd.CommandId,
+				"command":       outbox.CommandKind(qc.cmd),
+				"superseded_by": cmd.CommandId,
+				"session_id":    h.sessionID,
+			},
+		})
+	}
+}
+
+// auditCommand records the delivery of a command to the node
+func (h *StreamHandler) auditCommand(cmd *pb.ControlPlaneCommand, outcome string) {
+	h.nodeManager.audit.Log(audit.Record{
+		Actor:   audit.ActorControlPlane,
+		Action:  "command.send",
+		Target:  h.nodeID,
+		Outcome: outcome,
+		Details: map[string]string{
+			"command_id": cmd.CommandId,
+			"command":    outbox.CommandKind(cmd),
+			"priority":   cmd.Priority.String(),
+			"session_id": h.sessionID,
+		},
+	})
+}
+
+// SendCommand queues a command for the node in the lane of its priority. It
+// never blocks: a full lane or a terminated stream refuse the command.
+func (h *StreamHandler) SendCommand(cmd *pb.ControlPlaneCommand) error {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if h.ctx.Err() != nil {
+		return ErrStreamClosed
+	}
+
+	superseded, err := h.queue.push(&queuedCommand{cmd: cmd})
+	if err != nil {
+		return err
+	}
+	h.reportSuperseded(cmd, superseded)
+	return nil
+}
+
+// Done is closed once the stream handler terminates
+func (h *StreamHandler) Done() <-chan struct{} {
+	return h.ctx.Done()
+}
+
+// Err returns the cause the handler terminated with, nil while it runs and
+// when the stream ended normally
+func (h *StreamHandler) Err() error {
+	err := context.Cause(h.ctx)
+	if errors.Is(err, context.Canceled) || errors.Is(err, io.EOF) {
 		return nil
-	case <-time.After(5 * time.Second):
-		return fmt.Errorf("command channel full")
 	}
+	return err
 }
 
-// Close closes the stream handler
-func (h *StreamHandler) Close() {
-	close(h.done)
+// Terminate ends the stream with err. Only the first call has an effect.
+func (h *StreamHandler) Terminate(err error) {
+	if err == nil {
+		err = context.Canceled
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.cancel(err)
+}
+
+// dropPending audits the commands still queued when the stream ended as
+// undelivered. Outbox commands are left for the next stream of the node.
+func (h *StreamHandler) dropPending() {
+	for _, qc := range h.queue.drain() {
+		if qc.outboxSeq == 0 {
+			h.auditCommand(qc.cmd, audit.OutcomeFailure)
+		}
+	}
 }
@@ -0,0 +1,321 @@
+package node
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/init/config"
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/init/logger"
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/internal/audit"
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/internal/outbox"
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/internal/store"
+	pb "github.com/raphaelCamblong/Luminous-Mesh/control-plane/shared/proto"
+	"google.golang.org/grpc"
+)
+
+// testTimeout bounds every wait of the tests
+const testTimeout = 5 * time.Second
+
+var errRPCEnded = errors.New("rpc ended")
+
+// fakeStream is the server side of a node stream. Like a gRPC stream, Recv
+// and Send block until a message is exchanged or the RPC ends.
+type fakeStream struct {
+	grpc.ServerStream
+	ctx context.Context
+	// updates are received by the handler, the node closes it to hang up
+	updates chan *pb.NodeStatusUpdate
+	// sent receives the commands the handler sends; nothing reads it unless a
+	// test does, so Send blocks as on a stalled flow control window
+	sent chan *pb.ControlPlaneCommand
+	// sending is signalled each time Send starts waiting
+	sending chan struct{}
+	ended   chan struct{}
+	endOnce sync.Once
+}
+
+func newFakeStream() *fakeStream {
+	return &fakeStream{
+		ctx:     context.Background(),
+		updates: make(chan *pb.NodeStatusUpdate),
+		sent:    make(chan *pb.ControlPlaneCommand),
+		sending: make(chan struct{}, 16),
+		ended:   make(chan struct{}),
+	}
+}
+
+func (s *fakeStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *fakeStream) Recv() (*pb.NodeStatusUpdate, error) {
+	select {
+	case update, ok := <-s.updates:
+		if !ok {
+			return nil, io.EOF
+		}
+		return update, nil
+	case <-s.ended:
+		return nil, errRPCEnded
+	}
+}
+
+func (s *fakeStream) Send(cmd *pb.ControlPlaneCommand) error {
+	select {
+	case s.sending <- struct{}{}:
+	default:
+	}
+
+	select {
+	case s.sent <- cmd:
+		return nil
+	case <-s.ended:
+		return errRPCEnded
+	}
+}
+
+// end ends the RPC, as gRPC does once the handler returned
+func (s *fakeStream) end() {
+	s.endOnce.Do(func() { close(s.ended) })
+}
+
+// newTestManager returns a manager holding a registered node with a session
+func newTestManager(t *testing.T) (*Manager, string, string) {
+	t.Helper()
+	if logger.L() == nil {
+		logger.NewDevelopmentLogger()
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.Core.Audit.Path = ""
+	config.Set(cfg)
+
+	auditLogger, err := audit.NewLogger(nil)
+	if err != nil {
+		t.Fatalf("failed to create audit logger: %v", err)
+	}
+	outboxManager, err := outbox.NewManager(store.NewMemory(), auditLogger)
+	if err != nil {
+		t.Fatalf("failed to create outbox: %v", err)
+	}
+	m, err := NewManager(nil, auditLogger, outboxManager)
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+
+	nodeID := m.GenerateNodeID()
+	if err := m.RegisterNode(nodeID, &pb.NodeBasicInfo{Hostname: "node-1"}); err != nil {
+		t.Fatalf("RegisterNode() error = %v", err)
+	}
+	sessionID, err := m.CreateSession(nodeID)
+	if err != nil {
+		t.Fatalf("CreateSession() error = %v", err)
+	}
+	return m, nodeID, sessionID
+}
+
+// serve runs HandleStream as the gRPC server does: the RPC ends once it returns
+func serve(h *StreamHandler, stream *fakeStream) <-chan error {
+	result := make(chan error, 1)
+	go func() {
+		err := h.HandleStream(stream)
+		stream.end()
+		result <- err
+	}()
+	return result
+}
+
+func waitResult(t *testing.T, result <-chan error) error {
+	t.Helper()
+	select {
+	case err := <-result:
+		return err
+	case <-time.After(testTimeout):
+		t.Fatalf("HandleStream did not return")
+		return nil
+	}
+}
+
+// waitStopped checks every goroutine of the handler exited
+func waitStopped(t *testing.T, h *StreamHandler) {
+	t.Helper()
+	select {
+	case <-h.Stopped():
+	case <-time.After(testTimeout):
+		t.Fatalf("stream handler goroutines did not exit")
+	}
+}
+
+func testCommand(id string) *pb.ControlPlaneCommand {
+	return &pb.ControlPlaneCommand{
+		CommandId: id,
+		Command:   &pb.ControlPlaneCommand_HealthCheck{HealthCheck: &pb.HealthCheck{CheckId: id}},
+		Priority:  pb.ControlPlaneCommand_NORMAL,
+	}
+}
+
+func TestStreamDeliversCommands(t *testing.T) {
+	m, nodeID, sessionID := newTestManager(t)
+	stream := newFakeStream()
+	h := NewStreamHandler(context.Background(), nodeID, sessionID, m, nil)
+	result := serve(h, stream)
+
+	if err := h.SendCommand(testCommand("cmd-1")); err != nil {
+		t.Fatalf("SendCommand() error = %v", err)
+	}
+	select {
+	case cmd := <-stream.sent:
+		if cmd.CommandId != "cmd-1" {
+			t.Fatalf("sent command %q, want cmd-1", cmd.CommandId)
+		}
+	case <-time.After(testTimeout):
+		t.Fatalf("command was not sent")
+	}
+
+	stream.updates <- &pb.NodeStatusUpdate{NodeId: nodeID, SessionId: sessionID}
+	close(stream.updates)
+
+	if err := waitResult(t, result); err != nil {
+		t.Fatalf("HandleStream() error = %v, want none once the node hangs up", err)
+	}
+	waitStopped(t, h)
+}
+
+func TestTerminateTwice(t *testing.T) {
+	m, nodeID, sessionID := newTestManager(t)
+	stream := newFakeStream()
+	h := NewStreamHandler(context.Background(), nodeID, sessionID, m, nil)
+	result := serve(h, stream)
+
+	h.Terminate(ErrSessionRevoked)
+	h.Terminate(ErrDisconnected)
+
+	if err := waitResult(t, result); !errors.Is(err, ErrSessionRevoked) {
+		t.Fatalf("HandleStream() error = %v, want the first cause %v", err, ErrSessionRevoked)
+	}
+	waitStopped(t, h)
+
+	h.Terminate(nil)
+	if err := h.Err(); !errors.Is(err, ErrSessionRevoked) {
+		t.Fatalf("Err() = %v after a later Terminate, want %v", err, ErrSessionRevoked)
+	}
+}
+
+func TestTerminateConcurrently(t *testing.T) {
+	m, nodeID, sessionID := newTestManager(t)
+	stream := newFakeStream()
+	h := NewStreamHandler(context.Background(), nodeID, sessionID, m, nil)
+	result := serve(h, stream)
+
+	causes := []error{ErrSessionRevoked, ErrSessionExpired, ErrDisconnected, nil}
+	var wg sync.WaitGroup
+	for _, cause := range causes {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			h.Terminate(cause)
+		}()
+		go func() {
+			defer wg.Done()
+			h.SendCommand(testCommand("racing"))
+		}()
+	}
+	wg.Wait()
+
+	err := waitResult(t, result)
+	if err != h.Err() {
+		t.Fatalf("HandleStream() error = %v, Err() = %v, want the same cause", err, h.Err())
+	}
+	waitStopped(t, h)
+}
+
+func TestSendCommandAfterDisconnect(t *testing.T) {
+	m, nodeID, sessionID := newTestManager(t)
+	stream := newFakeStream()
+	h := NewStreamHandler(context.Background(), nodeID, sessionID, m, nil)
+	result := serve(h, stream)
+
+	// The node hangs up
+	close(stream.updates)
+	if err := waitResult(t, result); err != nil {
+		t.Fatalf("HandleStream() error = %v, want none", err)
+	}
+	waitStopped(t, h)
+
+	if err := h.SendCommand(testCommand("late")); !errors.Is(err, ErrStreamClosed) {
+		t.Fatalf("SendCommand() error = %v, want %v", err, ErrStreamClosed)
+	}
+	if queued := h.queue.drain(); len(queued) != 0 {
+		t.Fatalf("%d commands queued on a closed stream", len(queued))
+	}
+}
+
+func TestCancelWhileSendBlocked(t *testing.T) {
+	m, nodeID, sessionID := newTestManager(t)
+	stream := newFakeStream()
+	h := NewStreamHandler(context.Background(), nodeID, sessionID, m, nil)
+	result := serve(h, stream)
+
+	if err := h.SendCommand(testCommand("stalled")); err != nil {
+		t.Fatalf("SendCommand() error = %v", err)
+	}
+	select {
+	case <-stream.sending:
+	case <-time.After(testTimeout):
+		t.Fatalf("command was not sent")
+	}
+
+	// Send is stuck on flow control; the handler still returns with the cause
+	h.Terminate(ErrSessionRevoked)
+	if err := waitResult(t, result); !errors.Is(err, ErrSessionRevoked) {
+		t.Fatalf("HandleStream() error = %v, want %v", err, ErrSessionRevoked)
+	}
+
+	// The RPC ended with HandleStream, which releases the blocked Send
+	waitStopped(t, h)
+	if err := h.Err(); !errors.Is(err, ErrSessionRevoked) {
+		t.Fatalf("Err() = %v, want %v", err, ErrSessionRevoked)
+	}
+}
+
+func TestParentCancelWithCause(t *testing.T) {
+	m, nodeID, sessionID := newTestManager(t)
+	stream := newFakeStream()
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	h := NewStreamHandler(ctx, nodeID, sessionID, m, nil)
+	result := serve(h, stream)
+
+	if err := h.SendCommand(testCommand("stalled")); err != nil {
+		t.Fatalf("SendCommand() error = %v", err)
+	}
+	select {
+	case <-stream.sending:
+	case <-time.After(testTimeout):
+		t.Fatalf("command was not sent")
+	}
+
+	cancel(ErrDisconnected)
+	if err := waitResult(t, result); !errors.Is(err, ErrDisconnected) {
+		t.Fatalf("HandleStream() error = %v, want %v", err, ErrDisconnected)
+	}
+	waitStopped(t, h)
+}
+
+func TestSessionMismatchEndsStream(t *testing.T) {
+	m, nodeID, sessionID := newTestManager(t)
+	stream := newFakeStream()
+	h := NewStreamHandler(context.Background(), nodeID, sessionID, m, nil)
+	result := serve(h, stream)
+
+	stream.updates <- &pb.NodeStatusUpdate{NodeId: nodeID, SessionId: "another-session"}
+
+	if err := waitResult(t, result); !errors.Is(err, ErrSessionMismatch) {
+		t.Fatalf("HandleStream() error = %v, want %v", err, ErrSessionMismatch)
+	}
+	waitStopped(t, h)
+}
@@ -0,0 +1,464 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/init/config"
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/init/logger"
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/internal/access"
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/internal/audit"
+	lmhttp "github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/internal/http"
+	"go.uber.org/zap"
+)
+
+// Logins must come back from the provider within loginTimeout
+const loginTimeout = 10 * time.Minute
+
+// stateCookie binds a login in progress to the browser that started it
+const stateCookie = "lm_oidc_state"
+
+// sessionCookie carries the operator session of the signed in browser
+const sessionCookie = "lm_session"
+
+// Logins in progress at most, older ones are dropped first
+const maxPendingLogins = 10000
+
+// pendingLogin is an authorization request awaiting the provider callback
+type pendingLogin struct {
+	nonce     string
+	verifier  string
+	expiresAt time.Time
+}
+
+// Handler serves the single sign-on endpoints: the authorization code flow
+// with PKCE, the session of the signed in operator and logout
+type Handler struct {
+	config   *config.OIDCConfig
+	access   *access.Manager
+	audit    *audit.Logger
+	provider *Provider
+	client   *http.Client
+	secure   bool
+	pending  map[string]*pendingLogin
+	mu       sync.Mutex
+}
+
+// NewHandler creates the single sign-on handler, nil when OIDC is disabled
+func NewHandler(accessManager *access.Manager, auditLogger *audit.Logger) (*Handler, error) {
+	cfg := config.Get().Core.Access.OIDC
+	if !cfg.Enabled {
+		return nil, nil
+	}
+
+	roles := make(map[string]bool)
+	for _, role := range accessManager.Roles() {
+		roles[role.Name] = true
+	}
+	for group, mapped := range cfg.GroupRoles {
+		for _, role := range mapped {
+			if !roles[role] {
+				return nil, fmt.Errorf("group %q is mapped to unknown role %q", group, role)
+			}
+		}
+	}
+	for _, role := range cfg.DefaultRoles {
+		if !roles[role] {
+			return nil, fmt.Errorf("unknown default role %q", role)
+		}
+	}
+
+	redirect, err := url.Parse(cfg.RedirectURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redirect_url: %w", err)
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	return &Handler{
+		config:   &cfg,
+		access:   accessManager,
+		audit:    auditLogger,
+		provider: NewProvider(cfg.Issuer, cfg.ClientID, client),
+		client:   client,
+		secure:   redirect.Scheme == "https",
+		pending:  make(map[string]*pendingLogin),
+	}, nil
+}
+
+// Register adds the single sign-on endpoints to the HTTP server
+func (h *Handler) Register(s *lmhttp.Server) {
+	s.Handle("GET /auth/oidc/login", http.HandlerFunc(h.login))
+	s.Handle("GET /auth/oidc/callback", http.HandlerFunc(h.callback))
+	s.Handle("GET /auth/session", http.HandlerFunc(h.session))
+	s.Handle("POST /auth/logout", http.HandlerFunc(h.logout))
+}
+
+// login redirects the browser to the provider
+func (h *Handler) login(w http.ResponseWriter, r *http.Request) {
+	md, err := h.provider.discover(r.Context())
+	if err != nil {
+		logger.L().Error("OIDC provider unavailable", zap.Error(err))
+		http.Error(w, "identity provider unavailable", http.StatusBadGateway)
+		return
+	}
+
+	state, err1 := randomString()
+	nonce, err2 := randomString()
+	verifier, err3 := randomString()
+	if err := errors.Join(err1, err2, err3); err != nil {
+		logger.L().Error("Failed to start OIDC login", zap.Error(err))
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	h.mu.Lock()
+	h.prunePending()
+	h.pending[state] = &pendingLogin{
+		nonce:     nonce,
+		verifier:  verifier,
+		expiresAt: time.Now().Add(loginTimeout),
+	}
+	h.mu.Unlock()
+
+	challenge := sha256.Sum256([]byte(verifier))
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {h.config.ClientID},
+		"redirect_uri":          {h.config.RedirectURL},
+		"scope":                 {strings.Join(h.config.Scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     stateCookie,
+		Value:    state,
+		Path:     "/auth/oidc",
+		MaxAge:   int(loginTimeout.Seconds()),
+		HttpOnly: true,
+		Secure:   h.secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, md.AuthorizationEndpoint+"?"+query.Encode(), http.StatusFound)
+}
+
+// callback completes the login: the code is exchanged, the ID token
+// validated, the groups mapped to roles and a session cookie set
+func (h *Handler) callback(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	clearStateCookie(w, h.secure)
+
+	if e := query.Get("error"); e != "" {
+		h.refuse(w, r, "", fmt.Sprintf("provider error: %s", e), http.StatusUnauthorized)
+		return
+	}
+
+	state := query.Get("state")
+	cookie, err := r.Cookie(stateCookie)
+	if err != nil || state == "" || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(state)) != 1 {
+		h.refuse(w, r, "", "state does not match the browser", http.StatusBadRequest)
+		return
+	}
+
+	h.mu.Lock()
+	login, ok := h.pending[state]
+	delete(h.pending, state)
+	h.mu.Unlock()
+	if !ok || time.Now().After(login.expiresAt) {
+		h.refuse(w, r, "", "login expired", http.StatusBadRequest)
+		return
+	}
+
+	rawIDToken, err := h.exchange(r.Context(), query.Get("code"), login.verifier)
+	if err != nil {
+		h.refuse(w, r, "", err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	claims, err := h.provider.Verify(r.Context(), rawIDToken, login.nonce)
+	if err != nil {
+		h.refuse(w, r, "", err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	username, err := h.username(claims)
+	if err != nil {
+		h.refuse(w, r, "", err.Error(), http.StatusForbidden)
+		return
+	}
+	groups := claimStrings(claims, h.config.GroupsClaim)
+	roles := h.mapRoles(groups)
+	if len(roles) == 0 {
+		h.refuse(w, r, username, "no role is mapped to the groups of the operator", http.StatusForbidden)
+		return
+	}
+
+	subject := access.KindOIDC + ":" + username
+	token, expiresAt, identity, err := h.access.OpenFederatedSession(subject, roles)
+	if err != nil {
+		h.refuse(w, r, username, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    token,
+		Path:     "/",
+		Expires:  expiresAt,
+		HttpOnly: true,
+		Secure:   h.secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	h.audit.Log(audit.Record{
+		Actor:       subject,
+		Action:      "operator.login",
+		Target:      username,
+		Outcome:     audit.OutcomeSuccess,
+		PeerAddress: remoteAddress(r),
+		Details: map[string]string{
+			"method": "oidc",
+			"groups": strings.Join(groups, ","),
+			"roles":  strings.Join(identity.Roles, ","),
+		},
+	})
+	logger.L().Info("Operator signed in",
+		zap.String("subject", subject),
+		zap.Strings("roles", identity.Roles),
+	)
+
+	http.Redirect(w, r, h.config.PostLoginURL, http.StatusSeeOther)
+}
+
+// session describes the operator of the session cookie, for the frontend
+func (h *Handler) session(w http.ResponseWriter, r *http.Request) {
+	cookie, err := r.Cookie(sessionCookie)
+	if err != nil {
+		http.Error(w, "not signed in", http.StatusUnauthorized)
+		return
+	}
+
+	identity, err := h.access.Authenticate(cookie.Value)
+	if err != nil {
+		http.Error(w, "not signed in", http.StatusUnauthorized)
+		return
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"subject":     identity.Subject,
+		"roles":       identity.Roles,
+		"permissions": identity.PermissionList(),
+	})
+	if err != nil {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write(body)
+}
+
+// logout closes the session of the cookie and clears it
+func (h *Handler) logout(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(sessionCookie); err == nil {
+		if identity, err := h.access.Authenticate(cookie.Value); err == nil {
+			h.audit.Log(audit.Record{
+				Actor:       identity.Subject,
+				Action:      "operator.logout",
+				Outcome:     audit.OutcomeSuccess,
+				PeerAddress: remoteAddress(r),
+			})
+		}
+		h.access.Logout(cookie.Value)
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   h.secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// exchange redeems the authorization code for the ID token
+func (h *Handler) exchange(ctx context.Context, code, verifier string) (string, error) {
+	if code == "" {
+		return "", fmt.Errorf("missing authorization code")
+	}
+
+	md, err := h.provider.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {h.config.RedirectURL},
+		"client_id":     {h.config.ClientID},
+		"code_verifier": {verifier},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, md.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if h.config.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(h.config.ClientID), url.QueryEscape(h.config.ClientSecret))
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to redeem authorization code: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var tokens struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := decodeResponse(resp, &tokens); err != nil {
+		return "", fmt.Errorf("failed to redeem authorization code: %w", err)
+	}
+	if tokens.IDToken == "" {
+		return "", fmt.Errorf("provider returned no id token")
+	}
+	return tokens.IDToken, nil
+}
+
+// mapRoles returns the roles of the groups, the default roles when none is mapped
+func (h *Handler) mapRoles(groups []string) []string {
+	var roles []string
+	for _, group := range groups {
+		for _, role := range h.config.GroupRoles[group] {
+			if !slices.Contains(roles, role) {
+				roles = append(roles, role)
+			}
+		}
+	}
+	if len(roles) == 0 {
+		roles = h.config.DefaultRoles
+	}
+	return roles
+}
+
+// refuse fails a login, recording why in the audit trail only
+func (h *Handler) refuse(w http.ResponseWriter, r *http.Request, username, reason string, code int) {
+	logger.L().Warn("Operator sign-in refused",
+		zap.String("username", username),
+		zap.String("reason", reason),
+	)
+	h.audit.Log(audit.Record{
+		Actor:       audit.ActorAnonymous,
+		Action:      "operator.login",
+		Target:      username,
+		Outcome:     audit.OutcomeFailure,
+		PeerAddress: remoteAddress(r),
+		Details: map[string]string{
+			"method": "oidc",
+			"reason": reason,
+		},
+	})
+	http.Error(w, "sign-in failed", code)
+}
+
+// prunePending drops expired logins, and the oldest ones past the limit. Callers hold h.mu.
+func (h *Handler) prunePending() {
+	now := time.Now()
+	for state, login := range h.pending {
+		if now.After(login.expiresAt) {
+			delete(h.pending, state)
+		}
+	}
+
+	for len(h.pending) >= maxPendingLogins {
+		var oldest string
+		for state, login := range h.pending {
+			if oldest == "" || login.expiresAt.Before(h.pending[oldest].expiresAt) {
+				oldest = state
+			}
+		}
+		delete(h.pending, oldest)
+	}
+}
+
+func clearStateCookie(w http.ResponseWriter, secure bool) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     stateCookie,
+		Path:     "/auth/oidc",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// username names the operator by the configured claim, by its subject when
+// the token lacks it. An email must be verified by the provider, or anyone
+// could sign in as an address they merely entered there.
+func (h *Handler) username(claims jwt.MapClaims) (string, error) {
+	username, _ := claims[h.config.UsernameClaim].(string)
+	if username == "" {
+		sub, _ := claims["sub"].(string)
+		return sub, nil
+	}
+	if h.config.UsernameClaim == "email" {
+		if verified, _ := claims["email_verified"].(bool); !verified {
+			return "", fmt.Errorf("email %s is not verified by the provider", username)
+		}
+	}
+	return username, nil
+}
+
+// claimStrings reads a claim holding a string or a list of strings
+func claimStrings(claims jwt.MapClaims, name string) []string {
+	switch v := claims[name].(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+func remoteAddress(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func randomString() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
@@ -0,0 +1,356 @@
+package oidc
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/init/config"
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/internal/access"
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/internal/audit"
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/internal/store"
+)
+
+// newTestHandler returns a handler signing operators in at p, the groups sre
+// and platform-admins mapped to roles and no default role
+func newTestHandler(t *testing.T, p *standInProvider, defaultRoles ...string) *Handler {
+	t.Helper()
+
+	cfg := config.DefaultConfig()
+	cfg.Core.Audit.Path = ""
+	cfg.Core.Access.OIDC = config.OIDCConfig{
+		Enabled:       true,
+		Issuer:        p.issuer(),
+		ClientID:      testClientID,
+		ClientSecret:  testClientSecret,
+		RedirectURL:   testRedirectURL,
+		Scopes:        []string{"openid", "email", "groups"},
+		UsernameClaim: "email",
+		GroupsClaim:   "groups",
+		GroupRoles: map[string][]string{
+			"sre":             {access.RoleOperator},
+			"platform-admins": {access.RoleAdmin, access.RoleOperator},
+		},
+		DefaultRoles: defaultRoles,
+		PostLoginURL: "/",
+	}
+	config.Set(cfg)
+
+	dataStore := store.NewMemory()
+	accessManager, err := access.NewManager(dataStore)
+	if err != nil {
+		t.Fatalf("failed to create access manager: %v", err)
+	}
+	auditLogger, err := audit.NewLogger(dataStore)
+	if err != nil {
+		t.Fatalf("failed to create audit logger: %v", err)
+	}
+
+	h, err := NewHandler(accessManager, auditLogger)
+	if err != nil {
+		t.Fatalf("NewHandler() error = %v", err)
+	}
+	h.client = p.server.Client()
+	h.provider.client = p.server.Client()
+	return h
+}
+
+// startLogin calls the login endpoint and returns the state cookie it set and
+// the redirect to the provider
+func startLogin(t *testing.T, h *Handler) (*http.Cookie, string) {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	h.login(rec, httptest.NewRequest(http.MethodGet, "/auth/oidc/login", nil))
+	if rec.Code != http.StatusFound {
+		t.Fatalf("login status = %d, want %d", rec.Code, http.StatusFound)
+	}
+
+	for _, cookie := range rec.Result().Cookies() {
+		if cookie.Name == stateCookie {
+			return cookie, rec.Header().Get("Location")
+		}
+	}
+	t.Fatalf("login set no state cookie")
+	return nil, ""
+}
+
+// finishLogin calls the callback endpoint as the browser coming back from the provider
+func finishLogin(h *Handler, cookie *http.Cookie, state, code string) *httptest.ResponseRecorder {
+	query := url.Values{"state": {state}, "code": {code}}
+	req := httptest.NewRequest(http.MethodGet, "/auth/oidc/callback?"+query.Encode(), nil)
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+
+	rec := httptest.NewRecorder()
+	h.callback(rec, req)
+	return rec
+}
+
+// sessionOf returns the identity behind the session cookie set by a callback
+func sessionOf(t *testing.T, h *Handler, rec *httptest.ResponseRecorder) *access.Identity {
+	t.Helper()
+
+	for _, cookie := range rec.Result().Cookies() {
+		if cookie.Name != sessionCookie {
+			continue
+		}
+		identity, err := h.access.Authenticate(cookie.Value)
+		if err != nil {
+			t.Fatalf("session cookie does not authenticate: %v", err)
+		}
+		return identity
+	}
+	t.Fatalf("callback set no session cookie")
+	return nil
+}
+
+func TestLogin(t *testing.T) {
+	p := newStandInProvider(t)
+	p.claims = jwt.MapClaims{"email": "alice@example.com", "email_verified": true, "groups": []string{"sre", "platform-admins", "unmapped"}}
+	h := newTestHandler(t, p)
+
+	cookie, location := startLogin(t, h)
+	state, code := p.authorize(location)
+	if cookie.Value != state || !cookie.HttpOnly || !cookie.Secure {
+		t.Fatalf("state cookie = %+v, want an HttpOnly, Secure cookie holding state", cookie)
+	}
+
+	rec := finishLogin(h, cookie, state, code)
+	if rec.Code != http.StatusSeeOther || rec.Header().Get("Location") != "/" {
+		t.Fatalf("callback = %d to %q, want %d to /", rec.Code, rec.Header().Get("Location"), http.StatusSeeOther)
+	}
+
+	identity := sessionOf(t, h, rec)
+	if identity.Subject != "oidc:alice@example.com" || identity.Kind != access.KindOIDC {
+		t.Fatalf("identity = %s (%s), want oidc:alice@example.com", identity.Subject, identity.Kind)
+	}
+	if want := []string{access.RoleOperator, access.RoleAdmin}; !reflect.DeepEqual(identity.Roles, want) {
+		t.Fatalf("roles = %v, want %v", identity.Roles, want)
+	}
+
+	// The state is spent, the callback cannot be replayed
+	if rec := finishLogin(h, cookie, state, code); rec.Code != http.StatusBadRequest {
+		t.Fatalf("replayed callback status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestCallbackRefusesStateMismatch(t *testing.T) {
+	p := newStandInProvider(t)
+	p.claims = jwt.MapClaims{"email": "alice@example.com", "email_verified": true, "groups": "sre"}
+	h := newTestHandler(t, p)
+
+	cookie, location := startLogin(t, h)
+	state, code := p.authorize(location)
+
+	other, otherLocation := startLogin(t, h)
+	otherState, _ := p.authorize(otherLocation)
+
+	tests := []struct {
+		name   string
+		cookie *http.Cookie
+		state  string
+	}{
+		{"no cookie", nil, state},
+		{"cookie of another login", other, state},
+		{"state of another login", cookie, otherState},
+		{"no state", cookie, ""},
+		{"unknown state", &http.Cookie{Name: stateCookie, Value: "forged"}, "forged"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := finishLogin(h, tt.cookie, tt.state, code)
+			if rec.Code != http.StatusBadRequest {
+				t.Fatalf("callback status = %d, want %d", rec.Code, http.StatusBadRequest)
+			}
+			for _, c := range rec.Result().Cookies() {
+				if c.Name == sessionCookie {
+					t.Fatalf("callback set a session cookie")
+				}
+			}
+		})
+	}
+
+	// The refused attempts did not spend the login
+	if rec := finishLogin(h, cookie, state, code); rec.Code != http.StatusSeeOther {
+		t.Fatalf("callback status = %d, want %d", rec.Code, http.StatusSeeOther)
+	}
+}
+
+func TestCallbackRefusesExpiredLogin(t *testing.T) {
+	p := newStandInProvider(t)
+	p.claims = jwt.MapClaims{"email": "alice@example.com", "email_verified": true, "groups": "sre"}
+	h := newTestHandler(t, p)
+
+	cookie, location := startLogin(t, h)
+	state, code := p.authorize(location)
+	h.pending[state].expiresAt = time.Now().Add(-time.Second)
+
+	if rec := finishLogin(h, cookie, state, code); rec.Code != http.StatusBadRequest {
+		t.Fatalf("callback status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestCallbackRefusesNonceMismatch(t *testing.T) {
+	p := newStandInProvider(t)
+	p.claims = jwt.MapClaims{"email": "alice@example.com", "email_verified": true, "groups": "sre", "nonce": "replayed"}
+	h := newTestHandler(t, p)
+
+	cookie, location := startLogin(t, h)
+	state, code := p.authorize(location)
+
+	if rec := finishLogin(h, cookie, state, code); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("callback status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestCallbackSendsPKCEVerifier(t *testing.T) {
+	p := newStandInProvider(t)
+	p.claims = jwt.MapClaims{"email": "alice@example.com", "email_verified": true, "groups": "sre"}
+	h := newTestHandler(t, p)
+
+	// A code intercepted and redeemed with another verifier is refused by the provider
+	cookie, location := startLogin(t, h)
+	state, code := p.authorize(location)
+	h.pending[state].verifier = "intercepted"
+
+	if rec := finishLogin(h, cookie, state, code); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("callback status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestCallbackRefusesTokenClaims(t *testing.T) {
+	tests := []struct {
+		name   string
+		claims jwt.MapClaims
+	}{
+		{"other issuer", jwt.MapClaims{"iss": "https://evil.example"}},
+		{"other audience", jwt.MapClaims{"aud": "other-client"}},
+		{"audiences without azp", jwt.MapClaims{"aud": []string{testClientID, "other-client"}}},
+		{"audiences with other azp", jwt.MapClaims{"aud": []string{testClientID, "other-client"}, "azp": "other-client"}},
+		{"expired", jwt.MapClaims{"exp": time.Now().Add(-time.Minute).Unix()}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newStandInProvider(t)
+			p.claims = jwt.MapClaims{"email": "alice@example.com", "email_verified": true, "groups": "sre"}
+			for name, value := range tt.claims {
+				p.claims[name] = value
+			}
+			h := newTestHandler(t, p)
+
+			cookie, location := startLogin(t, h)
+			state, code := p.authorize(location)
+
+			if rec := finishLogin(h, cookie, state, code); rec.Code != http.StatusUnauthorized {
+				t.Fatalf("callback status = %d, want %d", rec.Code, http.StatusUnauthorized)
+			}
+		})
+	}
+}
+
+func TestCallbackMapsGroupsToRoles(t *testing.T) {
+	tests := []struct {
+		name         string
+		groups       interface{}
+		defaultRoles []string
+		wantRoles    []string
+	}{
+		{"single group claim", "sre", nil, []string{access.RoleOperator}},
+		{"roles of several groups merged", []string{"platform-admins", "sre"}, nil, []string{access.RoleAdmin, access.RoleOperator}},
+		{"unmapped group gets default roles", []string{"finance"}, []string{access.RoleViewer}, []string{access.RoleViewer}},
+		{"no group gets default roles", nil, []string{access.RoleViewer}, []string{access.RoleViewer}},
+		{"mapped group overrides default roles", []string{"sre"}, []string{access.RoleViewer}, []string{access.RoleOperator}},
+		{"unmapped group without default roles is refused", []string{"finance"}, nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newStandInProvider(t)
+			p.claims = jwt.MapClaims{"email": "alice@example.com", "email_verified": true}
+			if tt.groups != nil {
+				p.claims["groups"] = tt.groups
+			}
+			h := newTestHandler(t, p, tt.defaultRoles...)
+
+			cookie, location := startLogin(t, h)
+			state, code := p.authorize(location)
+			rec := finishLogin(h, cookie, state, code)
+
+			if tt.wantRoles == nil {
+				if rec.Code != http.StatusForbidden {
+					t.Fatalf("callback status = %d, want %d", rec.Code, http.StatusForbidden)
+				}
+				return
+			}
+			if rec.Code != http.StatusSeeOther {
+				t.Fatalf("callback status = %d, want %d", rec.Code, http.StatusSeeOther)
+			}
+			if roles := sessionOf(t, h, rec).Roles; !reflect.DeepEqual(roles, tt.wantRoles) {
+				t.Fatalf("roles = %v, want %v", roles, tt.wantRoles)
+			}
+		})
+	}
+}
+
+func TestCallbackUsername(t *testing.T) {
+	tests := []struct {
+		name          string
+		usernameClaim string
+		claims        jwt.MapClaims
+		// wantSubject is empty when the sign-in is refused
+		wantSubject string
+	}{
+		{"verified email", "email", jwt.MapClaims{"email": "alice@example.com", "email_verified": true}, "oidc:alice@example.com"},
+		{"unverified email", "email", jwt.MapClaims{"email": "alice@example.com", "email_verified": false}, ""},
+		{"email without verification", "email", jwt.MapClaims{"email": "alice@example.com"}, ""},
+		{"verification as a string", "email", jwt.MapClaims{"email": "alice@example.com", "email_verified": "true"}, ""},
+		{"no email falls back to the subject", "email", jwt.MapClaims{}, "oidc:0b6c4d2e"},
+		{"preferred username", "preferred_username", jwt.MapClaims{"preferred_username": "alice", "email": "bob@example.com"}, "oidc:alice"},
+		{"subject", "sub", jwt.MapClaims{"email": "alice@example.com", "email_verified": true}, "oidc:0b6c4d2e"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newStandInProvider(t)
+			p.claims = tt.claims
+			p.claims["groups"] = "sre"
+			h := newTestHandler(t, p)
+			h.config.UsernameClaim = tt.usernameClaim
+
+			cookie, location := startLogin(t, h)
+			state, code := p.authorize(location)
+			rec := finishLogin(h, cookie, state, code)
+
+			if tt.wantSubject == "" {
+				if rec.Code != http.StatusForbidden {
+					t.Fatalf("callback status = %d, want %d", rec.Code, http.StatusForbidden)
+				}
+				return
+			}
+			if subject := sessionOf(t, h, rec).Subject; subject != tt.wantSubject {
+				t.Fatalf("subject = %s, want %s", subject, tt.wantSubject)
+			}
+		})
+	}
+}
+
+func TestFederatedSessionCannotCreateAPIKeys(t *testing.T) {
+	p := newStandInProvider(t)
+	p.claims = jwt.MapClaims{"email": "alice@example.com", "email_verified": true, "groups": "platform-admins"}
+	h := newTestHandler(t, p)
+
+	cookie, location := startLogin(t, h)
+	state, code := p.authorize(location)
+	identity := sessionOf(t, h, finishLogin(h, cookie, state, code))
+
+	if _, _, err := h.access.CreateAPIKey(identity, "ci", []string{access.PermNodesRead}, 0); err == nil {
+		t.Fatalf("CreateAPIKey() accepted a federated owner, whose roles are unknown once signed out")
+	}
+}
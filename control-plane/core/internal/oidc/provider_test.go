@@ -0,0 +1,296 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/init/logger"
+)
+
+const (
+	testClientID     = "luminous-mesh"
+	testClientSecret = "client-secret"
+	testRedirectURL  = "https://cp.example/auth/oidc/callback"
+	testKeyID        = "key-1"
+)
+
+// authorization is a code handed out by the stand-in provider
+type authorization struct {
+	challenge string
+	nonce     string
+}
+
+// standInProvider serves the discovery document, JWKS, authorization and
+// token endpoints of an OpenID provider
+type standInProvider struct {
+	t      *testing.T
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	// claims are added to, or override, the claims of the ID tokens issued
+	claims jwt.MapClaims
+	// announced replaces the issuer of the discovery document when set
+	announced string
+	codes     map[string]authorization
+	mu        sync.Mutex
+}
+
+func newStandInProvider(t *testing.T) *standInProvider {
+	t.Helper()
+	if logger.L() == nil {
+		logger.NewDevelopmentLogger()
+	}
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate provider key: %v", err)
+	}
+
+	p := &standInProvider{
+		t:      t,
+		key:    key,
+		claims: jwt.MapClaims{},
+		codes:  make(map[string]authorization),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", p.discovery)
+	mux.HandleFunc("GET /jwks", p.jwks)
+	mux.HandleFunc("POST /token", p.token)
+	p.server = httptest.NewServer(mux)
+	t.Cleanup(p.server.Close)
+
+	return p
+}
+
+func (p *standInProvider) issuer() string {
+	return p.server.URL
+}
+
+func (p *standInProvider) discovery(w http.ResponseWriter, r *http.Request) {
+	issuer := p.issuer()
+	if p.announced != "" {
+		issuer = p.announced
+	}
+	json.NewEncoder(w).Encode(map[string]string{
+		"issuer":                 issuer,
+		"authorization_endpoint": p.issuer() + "/authorize",
+		"token_endpoint":         p.issuer() + "/token",
+		"jwks_uri":               p.issuer() + "/jwks",
+	})
+}
+
+func (p *standInProvider) jwks(w http.ResponseWriter, r *http.Request) {
+	encode := func(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": testKeyID,
+			"use": "sig",
+			"n":   encode(p.key.N.Bytes()),
+			"e":   encode(big.NewInt(int64(p.key.E)).Bytes()),
+		}},
+	})
+}
+
+// authorize plays the operator signing in at the redirect of the login
+// endpoint and returns the code the browser brings back
+func (p *standInProvider) authorize(location string) (state, code string) {
+	p.t.Helper()
+
+	u, err := url.Parse(location)
+	if err != nil {
+		p.t.Fatalf("invalid authorization redirect %q: %v", location, err)
+	}
+	if !strings.HasPrefix(location, p.issuer()+"/authorize?") {
+		p.t.Fatalf("redirected to %q, want the authorization endpoint", location)
+	}
+
+	query := u.Query()
+	for name, want := range map[string]string{
+		"response_type":         "code",
+		"client_id":             testClientID,
+		"redirect_uri":          testRedirectURL,
+		"code_challenge_method": "S256",
+	} {
+		if got := query.Get(name); got != want {
+			p.t.Fatalf("authorization request %s = %q, want %q", name, got, want)
+		}
+	}
+	for _, name := range []string{"state", "nonce", "code_challenge"} {
+		if query.Get(name) == "" {
+			p.t.Fatalf("authorization request lacks %s", name)
+		}
+	}
+
+	code, err = randomString()
+	if err != nil {
+		p.t.Fatalf("failed to generate code: %v", err)
+	}
+	p.mu.Lock()
+	p.codes[code] = authorization{
+		challenge: query.Get("code_challenge"),
+		nonce:     query.Get("nonce"),
+	}
+	p.mu.Unlock()
+
+	return query.Get("state"), code
+}
+
+// token redeems a code once, checking the client and the PKCE verifier
+func (p *standInProvider) token(w http.ResponseWriter, r *http.Request) {
+	if id, secret, ok := r.BasicAuth(); !ok || id != testClientID || secret != testClientSecret {
+		http.Error(w, `{"error":"invalid_client"}`, http.StatusUnauthorized)
+		return
+	}
+
+	p.mu.Lock()
+	auth, ok := p.codes[r.FormValue("code")]
+	delete(p.codes, r.FormValue("code"))
+	p.mu.Unlock()
+
+	challenge := sha256.Sum256([]byte(r.FormValue("code_verifier")))
+	if !ok || r.FormValue("grant_type") != "authorization_code" ||
+		r.FormValue("redirect_uri") != testRedirectURL ||
+		base64.RawURLEncoding.EncodeToString(challenge[:]) != auth.challenge {
+		http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+		return
+	}
+
+	claims := jwt.MapClaims{"nonce": auth.nonce}
+	p.mu.Lock()
+	for name, value := range p.claims {
+		claims[name] = value
+	}
+	p.mu.Unlock()
+
+	json.NewEncoder(w).Encode(map[string]string{
+		"access_token": "access-token",
+		"token_type":   "Bearer",
+		"id_token":     p.sign(claims),
+	})
+}
+
+// sign issues an ID token for the client holding claims over the defaults
+func (p *standInProvider) sign(claims jwt.MapClaims) string {
+	p.t.Helper()
+
+	now := time.Now()
+	token := jwt.MapClaims{
+		"iss": p.issuer(),
+		"aud": testClientID,
+		"sub": "0b6c4d2e",
+		"iat": now.Unix(),
+		"exp": now.Add(time.Hour).Unix(),
+	}
+	for name, value := range claims {
+		if value == nil {
+			delete(token, name)
+			continue
+		}
+		token[name] = value
+	}
+
+	signed := jwt.NewWithClaims(jwt.SigningMethodRS256, token)
+	signed.Header["kid"] = testKeyID
+	raw, err := signed.SignedString(p.key)
+	if err != nil {
+		p.t.Fatalf("failed to sign id token: %v", err)
+	}
+	return raw
+}
+
+func TestVerify(t *testing.T) {
+	p := newStandInProvider(t)
+	provider := NewProvider(p.issuer(), testClientID, p.server.Client())
+
+	hs256, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"iss": p.issuer(), "aud": testClientID, "sub": "0b6c4d2e",
+		"exp": time.Now().Add(time.Hour).Unix(), "nonce": "nonce",
+	}).SignedString([]byte("shared"))
+	if err != nil {
+		t.Fatalf("failed to sign HS256 token: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		raw    string
+		nonce  string
+		wantOK bool
+	}{
+		{"valid", p.sign(jwt.MapClaims{"nonce": "nonce"}), "nonce", true},
+		{"issuer with trailing slash", p.sign(jwt.MapClaims{"nonce": "nonce", "iss": p.issuer() + "/"}), "nonce", true},
+		{"audience list", p.sign(jwt.MapClaims{"nonce": "nonce", "aud": []string{testClientID}}), "nonce", true},
+		{"audiences with azp", p.sign(jwt.MapClaims{"nonce": "nonce", "aud": []string{testClientID, "other"}, "azp": testClientID}), "nonce", true},
+		{"nonce mismatch", p.sign(jwt.MapClaims{"nonce": "other"}), "nonce", false},
+		{"no nonce", p.sign(nil), "nonce", false},
+		{"other issuer", p.sign(jwt.MapClaims{"nonce": "nonce", "iss": "https://evil.example"}), "nonce", false},
+		{"other audience", p.sign(jwt.MapClaims{"nonce": "nonce", "aud": "other"}), "nonce", false},
+		{"audiences without azp", p.sign(jwt.MapClaims{"nonce": "nonce", "aud": []string{testClientID, "other"}}), "nonce", false},
+		{"audiences with other azp", p.sign(jwt.MapClaims{"nonce": "nonce", "aud": []string{testClientID, "other"}, "azp": "other"}), "nonce", false},
+		{"expired", p.sign(jwt.MapClaims{"nonce": "nonce", "exp": time.Now().Add(-time.Minute).Unix()}), "nonce", false},
+		{"no expiry", p.sign(jwt.MapClaims{"nonce": "nonce", "exp": nil}), "nonce", false},
+		{"no subject", p.sign(jwt.MapClaims{"nonce": "nonce", "sub": nil}), "nonce", false},
+		{"symmetric signature", hs256, "nonce", false},
+		{"tampered", p.sign(jwt.MapClaims{"nonce": "nonce"}) + "x", "nonce", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := provider.Verify(context.Background(), tt.raw, tt.nonce)
+			if tt.wantOK {
+				if err != nil {
+					t.Fatalf("Verify() error = %v, want none", err)
+				}
+				if claims["sub"] != "0b6c4d2e" {
+					t.Fatalf("Verify() sub = %v", claims["sub"])
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("Verify() accepted the token")
+			}
+		})
+	}
+}
+
+func TestVerifyRefusesUnknownKey(t *testing.T) {
+	p := newStandInProvider(t)
+	provider := NewProvider(p.issuer(), testClientID, p.server.Client())
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss": p.issuer(), "aud": testClientID, "sub": "0b6c4d2e",
+		"exp": time.Now().Add(time.Hour).Unix(), "nonce": "nonce",
+	})
+	token.Header["kid"] = "unknown"
+	raw, err := token.SignedString(p.key)
+	if err != nil {
+		t.Fatalf("failed to sign id token: %v", err)
+	}
+
+	if _, err := provider.Verify(context.Background(), raw, "nonce"); err == nil {
+		t.Fatalf("Verify() accepted a token signed with an unknown key ID")
+	}
+}
+
+func TestDiscoverRefusesOtherIssuer(t *testing.T) {
+	p := newStandInProvider(t)
+	p.announced = "https://idp.example"
+	provider := NewProvider(p.issuer(), testClientID, p.server.Client())
+
+	if _, err := provider.discover(context.Background()); err == nil {
+		t.Fatalf("discover() accepted metadata announcing another issuer")
+	}
+}
@@ -0,0 +1,369 @@
+package outbox
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/init/config"
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/init/logger"
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/internal/audit"
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/shared/interfaces"
+	pb "github.com/raphaelCamblong/Luminous-Mesh/control-plane/shared/proto"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+)
+
+const outboxBucket = "outbox"
+
+var (
+	ErrFull       = errors.New("too many commands pending for the node")
+	ErrInvalidTTL = errors.New("invalid command expiry")
+)
+
+// Entry is a command waiting for its node to acknowledge it
+type Entry struct {
+	NodeID      string                          `json:"node_id"`
+	CommandID   string                          `json:"command_id"`
+	Sequence    uint64                          `json:"sequence"`
+	Kind        string                          `json:"kind"`
+	Priority    pb.ControlPlaneCommand_Priority `json:"priority"`
+	Command     []byte                          `json:"command"`
+	EnqueuedAt  time.Time                       `json:"enqueued_at"`
+	ExpiresAt   time.Time                       `json:"expires_at"`
+	Attempts    int                             `json:"attempts"`
+	LastAttempt time.Time                       `json:"last_attempt,omitempty"`
+}
+
+// Decode returns the command of the entry
+func (e *Entry) Decode() (*pb.ControlPlaneCommand, error) {
+	cmd := &pb.ControlPlaneCommand{}
+	if err := proto.Unmarshal(e.Command, cmd); err != nil {
+		return nil, fmt.Errorf("failed to decode command %s: %w", e.CommandID, err)
+	}
+	return cmd, nil
+}
+
+// Receipt is the outcome of Enqueue
+type Receipt struct {
+	Entry *Entry
+	// Duplicate is set when the command_id was already pending, nothing was queued
+	Duplicate bool
+	// Superseded lists the pending config updates the command replaced
+	Superseded []string
+}
+
+// Manager keeps a persistent, ordered outbox of commands per node. Commands
+// stay in the outbox until the node acknowledges them or they expire, so a
+// node offline or reconnecting receives them once its stream is back.
+type Manager struct {
+	config   *config.OutboxConfig
+	store    interfaces.DataStore
+	audit    *audit.Logger
+	queues   map[string][]*Entry
+	sequence uint64
+	mu       sync.Mutex
+}
+
+// NewManager restores the outboxes from store
+func NewManager(store interfaces.DataStore, auditLogger *audit.Logger) (*Manager, error) {
+	cfg := config.Get().Core.Outbox
+
+	m := &Manager{
+		config: &cfg,
+		store:  store,
+		audit:  auditLogger,
+		queues: make(map[string][]*Entry),
+	}
+
+	if store == nil {
+		return m, nil
+	}
+
+	stored, err := store.List(outboxBucket)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load outbox: %w", err)
+	}
+	for _, data := range stored {
+		var entry Entry
+		if err := json.Unmarshal(data, &entry); err != nil {
+			return nil, fmt.Errorf("failed to decode outbox entry: %w", err)
+		}
+		m.queues[entry.NodeID] = append(m.queues[entry.NodeID], &entry)
+		if entry.Sequence > m.sequence {
+			m.sequence = entry.Sequence
+		}
+	}
+	for _, queue := range m.queues {
+		sort.Slice(queue, func(i, j int) bool {
+			return queue[i].Sequence < queue[j].Sequence
+		})
+	}
+
+	return m, nil
+}
+
+// Enqueue appends a command to the outbox of a node, expiring after ttl or
+// the default TTL when zero, or at the command deadline when earlier. The
+// command is stamped with the resulting deadline. A command_id already pending
+// for the node is not queued again. A config update supersedes the config
+// updates still pending, only the newest configuration is sent.
+func (m *Manager) Enqueue(nodeID string, cmd *pb.ControlPlaneCommand, ttl time.Duration) (*Receipt, error) {
+	if ttl == 0 {
+		ttl = m.config.DefaultTTL
+	}
+	if ttl < 0 || ttl > m.config.MaxTTL {
+		return nil, fmt.Errorf("%w: ttl must be positive and at most %s", ErrInvalidTTL, m.config.MaxTTL)
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(ttl)
+	if cmd.Deadline != 0 {
+		deadline := time.UnixMilli(cmd.Deadline)
+		if !deadline.After(now) {
+			return nil, fmt.Errorf("%w: deadline has passed", ErrInvalidTTL)
+		}
+		if deadline.Before(expiresAt) {
+			expiresAt = deadline
+		}
+	}
+
+	cmd = proto.Clone(cmd).(*pb.ControlPlaneCommand)
+	cmd.Deadline = expiresAt.UnixMilli()
+	data, err := proto.Marshal(cmd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode command: %w", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.prune(nodeID, now)
+
+	for _, pending := range m.queues[nodeID] {
+		if pending.CommandID == cmd.CommandId {
+			return &Receipt{Entry: m.snapshot(pending), Duplicate: true}, nil
+		}
+	}
+
+	kind := CommandKind(cmd)
+	var superseded []string
+	if cmd.GetConfigUpdate() != nil {
+		for _, pending := range m.queues[nodeID] {
+			if pending.Kind == kind {
+				superseded = append(superseded, pending.CommandID)
+			}
+		}
+	}
+	if len(m.queues[nodeID])-len(superseded) >= m.config.MaxPending {
+		return nil, ErrFull
+	}
+
+	entry := &Entry{
+		NodeID:     nodeID,
+		CommandID:  cmd.CommandId,
+		Sequence:   m.sequence + 1,
+		Kind:       kind,
+		Priority:   cmd.Priority,
+		Command:    data,
+		EnqueuedAt: now,
+		ExpiresAt:  expiresAt,
+	}
+	if err := m.save(entry); err != nil {
+		return nil, err
+	}
+	m.sequence = entry.Sequence
+	m.remove(nodeID, superseded)
+	m.queues[nodeID] = append(m.queues[nodeID], entry)
+
+	for _, id := range superseded {
+		m.audit.Log(audit.Record{
+			Actor:   audit.ActorControlPlane,
+			Action:  "command.coalesce",
+			Target:  nodeID,
+			Outcome: audit.OutcomeSuccess,
+			Details: map[string]string{
+				"command_id":    id,
+				"command":       kind,
+				"superseded_by": entry.CommandID,
+			},
+		})
+	}
+
+	return &Receipt{Entry: m.snapshot(entry), Superseded: superseded}, nil
+}
+
+// Pending returns the commands of a node queued after sequence, in order
+func (m *Manager) Pending(nodeID string, after uint64) []*Entry {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.prune(nodeID, time.Now())
+
+	var entries []*Entry
+	for _, entry := range m.queues[nodeID] {
+		if entry.Sequence > after {
+			entries = append(entries, m.snapshot(entry))
+		}
+	}
+	return entries
+}
+
+// MarkAttempted records a delivery of a command to its node
+func (m *Manager) MarkAttempted(nodeID, commandID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, entry := range m.queues[nodeID] {
+		if entry.CommandID != commandID {
+			continue
+		}
+		entry.Attempts++
+		entry.LastAttempt = time.Now()
+		if err := m.save(entry); err != nil {
+			logger.L().Warn("Failed to persist command delivery",
+				zap.String("node_id", nodeID),
+				zap.String("command_id", commandID),
+				zap.Error(err),
+			)
+		}
+		return
+	}
+}
+
+// Ack removes the commands a node acknowledged and returns how many were pending
+func (m *Manager) Ack(nodeID string, commandIDs []string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.remove(nodeID, commandIDs)
+}
+
+// Purge drops the pending commands of a node listed in commandIDs, or all of
+// them when empty, and returns how many were dropped
+func (m *Manager) Purge(nodeID string, commandIDs []string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(commandIDs) == 0 {
+		for _, entry := range m.queues[nodeID] {
+			commandIDs = append(commandIDs, entry.CommandID)
+		}
+	}
+	return m.remove(nodeID, commandIDs)
+}
+
+// remove deletes the commands of a node listed in commandIDs
+func (m *Manager) remove(nodeID string, commandIDs []string) int {
+	if len(commandIDs) == 0 {
+		return 0
+	}
+	ids := make(map[string]bool, len(commandIDs))
+	for _, id := range commandIDs {
+		ids[id] = true
+	}
+
+	removed := 0
+	kept := m.queues[nodeID][:0]
+	for _, entry := range m.queues[nodeID] {
+		if ids[entry.CommandID] {
+			m.delete(entry)
+			removed++
+			continue
+		}
+		kept = append(kept, entry)
+	}
+	m.setQueue(nodeID, kept)
+
+	return removed
+}
+
+// prune drops the expired commands of a node, auditing them as undelivered
+func (m *Manager) prune(nodeID string, now time.Time) {
+	kept := m.queues[nodeID][:0]
+	for _, entry := range m.queues[nodeID] {
+		if now.Before(entry.ExpiresAt) {
+			kept = append(kept, entry)
+			continue
+		}
+
+		m.delete(entry)
+		logger.L().Info("Pending command expired",
+			zap.String("node_id", nodeID),
+			zap.String("command_id", entry.CommandID),
+			zap.Int("attempts", entry.Attempts),
+		)
+		m.audit.Log(audit.Record{
+			Actor:   audit.ActorControlPlane,
+			Action:  "command.expire",
+			Target:  nodeID,
+			Outcome: audit.OutcomeFailure,
+			Details: map[string]string{
+				"command_id": entry.CommandID,
+				"command":    entry.Kind,
+				"attempts":   strconv.Itoa(entry.Attempts),
+			},
+		})
+	}
+	m.setQueue(nodeID, kept)
+}
+
+func (m *Manager) setQueue(nodeID string, queue []*Entry) {
+	if len(queue) == 0 {
+		delete(m.queues, nodeID)
+		return
+	}
+	m.queues[nodeID] = queue
+}
+
+func (m *Manager) save(entry *Entry) error {
+	if m.store == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode outbox entry: %w", err)
+	}
+	if err := m.store.Put(outboxBucket, entryKey(entry), data); err != nil {
+		return fmt.Errorf("failed to persist outbox entry: %w", err)
+	}
+	return nil
+}
+
+func (m *Manager) delete(entry *Entry) {
+	if m.store == nil {
+		return
+	}
+
+	if err := m.store.Delete(outboxBucket, entryKey(entry)); err != nil && !errors.Is(err, interfaces.ErrNotFound) {
+		logger.L().Warn("Failed to delete outbox entry",
+			zap.String("node_id", entry.NodeID),
+			zap.String("command_id", entry.CommandID),
+			zap.Error(err),
+		)
+	}
+}
+
+func (m *Manager) snapshot(entry *Entry) *Entry {
+	copied := *entry
+	return &copied
+}
+
+// entryKey orders the keys of a node by sequence
+func entryKey(entry *Entry) string {
+	return fmt.Sprintf("%s/%020d", entry.NodeID, entry.Sequence)
+}
+
+// CommandKind names the command set in cmd, e.g. config_update
+func CommandKind(cmd *pb.ControlPlaneCommand) string {
+	msg := cmd.ProtoReflect()
+	if field := msg.WhichOneof(msg.Descriptor().Oneofs().ByName("command")); field != nil {
+		return string(field.Name())
+	}
+	return "unknown"
+}
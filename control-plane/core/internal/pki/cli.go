@@ -0,0 +1,246 @@
+package pki
+
+import (
+	"crypto/x509"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/pkg/certs"
+)
+
+const (
+	defaultDir      = ".build/certs"
+	caValidity      = 10 * 365 * 24 * time.Hour
+	serverValidity  = 365 * 24 * time.Hour
+	privateKeyPerm  = 0o600
+	certificatePerm = 0o644
+)
+
+type stringList []string
+
+func (s *stringList) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringList) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+// Run executes a `pki` subcommand and returns the process exit code
+func Run(args []string) int {
+	if len(args) == 0 {
+		usage(os.Stderr)
+		return 2
+	}
+
+	var err error
+	switch args[0] {
+	case "init":
+		err = runInit(args[1:])
+	case "issue-server":
+		err = runIssueServer(args[1:])
+	case "inspect":
+		err = runInspect(args[1:], os.Stdout)
+	case "help", "-h", "--help":
+		usage(os.Stdout)
+		return 0
+	default:
+		fmt.Fprintf(os.Stderr, "❌ Unknown pki command %q\n\n", args[0])
+		usage(os.Stderr)
+		return 2
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintf(w, "Usage of %s pki:\n", os.Args[0])
+	fmt.Fprintf(w, "  init           Create a certificate authority (ca.crt, ca.key)\n")
+	fmt.Fprintf(w, "  issue-server   Issue a server certificate signed by the CA (server.crt, server.key)\n")
+	fmt.Fprintf(w, "  inspect        Print the certificates of PEM files\n")
+	fmt.Fprintf(w, "\nExamples:\n")
+	fmt.Fprintf(w, "  %s pki init --dir .build/certs\n", os.Args[0])
+	fmt.Fprintf(w, "  %s pki issue-server --san control-plane.example.com --san 10.0.0.1\n", os.Args[0])
+	fmt.Fprintf(w, "  %s pki inspect .build/certs/ca.crt .build/certs/server.crt\n", os.Args[0])
+}
+
+func runInit(args []string) error {
+	fs := flag.NewFlagSet("pki init", flag.ContinueOnError)
+	dir := fs.String("dir", defaultDir, "output directory")
+	commonName := fs.String("cn", "Luminous Mesh CA", "CA common name")
+	keyType := fs.String("key-type", certs.KeyTypeECDSA, "key algorithm: ed25519, ecdsa or rsa")
+	validity := fs.Duration("validity", caValidity, "CA certificate validity")
+	force := fs.Bool("force", false, "overwrite existing files")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	key, err := certs.GenerateKey(*keyType)
+	if err != nil {
+		return err
+	}
+
+	cert, err := certs.CreateCA(*commonName, key, *validity)
+	if err != nil {
+		return err
+	}
+
+	keyPEM, err := certs.EncodePrivateKey(key)
+	if err != nil {
+		return err
+	}
+
+	certPath := filepath.Join(*dir, "ca.crt")
+	keyPath := filepath.Join(*dir, "ca.key")
+	if err := writePair(certPath, keyPath, certs.EncodeCertificates([]*x509.Certificate{cert}), keyPEM, *force); err != nil {
+		return err
+	}
+
+	fmt.Printf("✅ CA created\n")
+	fmt.Printf("   certificate: %s\n", certPath)
+	fmt.Printf("   private key: %s\n", keyPath)
+	fmt.Printf("   fingerprint: %s\n", certs.Fingerprint(cert))
+	return nil
+}
+
+func runIssueServer(args []string) error {
+	fs := flag.NewFlagSet("pki issue-server", flag.ContinueOnError)
+	var sans stringList
+	fs.Var(&sans, "san", "subject alternative name, DNS name or IP (repeatable)")
+	dir := fs.String("dir", defaultDir, "output directory")
+	caCert := fs.String("ca-cert", "", "CA certificate (default: <dir>/ca.crt)")
+	caKey := fs.String("ca-key", "", "CA private key (default: <dir>/ca.key)")
+	passphraseEnv := fs.String("ca-key-passphrase-env", "", "environment variable holding the CA key passphrase")
+	commonName := fs.String("cn", "", "server common name (default: first SAN)")
+	name := fs.String("name", "server", "output file name, without extension")
+	keyType := fs.String("key-type", certs.KeyTypeECDSA, "key algorithm: ed25519, ecdsa or rsa")
+	validity := fs.Duration("validity", serverValidity, "server certificate validity")
+	force := fs.Bool("force", false, "overwrite existing files")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if len(sans) == 0 {
+		return fmt.Errorf("at least one --san is required")
+	}
+	if *caCert == "" {
+		*caCert = filepath.Join(*dir, "ca.crt")
+	}
+	if *caKey == "" {
+		*caKey = filepath.Join(*dir, "ca.key")
+	}
+	if *commonName == "" {
+		*commonName = sans[0]
+	}
+
+	var passphrase []byte
+	if *passphraseEnv != "" {
+		passphrase = []byte(os.Getenv(*passphraseEnv))
+	}
+
+	ca, err := certs.NewFileSigner(*caCert, *caKey, passphrase)
+	if err != nil {
+		return fmt.Errorf("failed to load CA: %w", err)
+	}
+
+	key, err := certs.GenerateKey(*keyType)
+	if err != nil {
+		return err
+	}
+
+	cert, err := certs.IssueServerCertificate(ca, key.Public(), *commonName, sans, *validity)
+	if err != nil {
+		return err
+	}
+
+	keyPEM, err := certs.EncodePrivateKey(key)
+	if err != nil {
+		return err
+	}
+
+	certPath := filepath.Join(*dir, *name+".crt")
+	keyPath := filepath.Join(*dir, *name+".key")
+	if err := writePair(certPath, keyPath, certs.EncodeCertificates([]*x509.Certificate{cert}), keyPEM, *force); err != nil {
+		return err
+	}
+
+	fmt.Printf("✅ Server certificate issued\n")
+	fmt.Printf("   certificate: %s\n", certPath)
+	fmt.Printf("   private key: %s\n", keyPath)
+	fmt.Printf("   SANs:        %s\n", sans.String())
+	fmt.Printf("   expires:     %s\n", cert.NotAfter.Format(time.RFC3339))
+	return nil
+}
+
+func runInspect(args []string, w io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("at least one PEM file is required")
+	}
+
+	for _, path := range args {
+		list, err := certs.LoadCertificates(path)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+
+		for i, cert := range list {
+			fmt.Fprintf(w, "%s [%d]\n", path, i)
+			printCertificate(w, cert)
+			fmt.Fprintln(w)
+		}
+	}
+	return nil
+}
+
+func printCertificate(w io.Writer, cert *x509.Certificate) {
+	fmt.Fprintf(w, "  Subject:      %s\n", cert.Subject)
+	fmt.Fprintf(w, "  Issuer:       %s\n", cert.Issuer)
+	fmt.Fprintf(w, "  Serial:       %s\n", cert.SerialNumber.Text(16))
+	fmt.Fprintf(w, "  Not before:   %s\n", cert.NotBefore.Format(time.RFC3339))
+	fmt.Fprintf(w, "  Not after:    %s\n", cert.NotAfter.Format(time.RFC3339))
+	fmt.Fprintf(w, "  Key:          %s\n", certs.KeyType(cert.PublicKey))
+	fmt.Fprintf(w, "  Signature:    %s\n", cert.SignatureAlgorithm)
+	fmt.Fprintf(w, "  CA:           %t\n", cert.IsCA)
+
+	var sans []string
+	sans = append(sans, cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+	if len(sans) > 0 {
+		fmt.Fprintf(w, "  SANs:         %s\n", strings.Join(sans, ", "))
+	}
+
+	fmt.Fprintf(w, "  SHA-256:      %s\n", certs.Fingerprint(cert))
+	fmt.Fprintf(w, "  SPKI pin:     %s\n", certs.SPKIPin(cert))
+
+	if time.Now().After(cert.NotAfter) {
+		fmt.Fprintf(w, "  ⚠️ expired\n")
+	}
+}
+
+// writePair writes a certificate and its key, the key readable by the owner only
+func writePair(certPath, keyPath string, certPEM, keyPEM []byte, overwrite bool) error {
+	if !overwrite {
+		for _, path := range []string{certPath, keyPath} {
+			if _, err := os.Stat(path); err == nil {
+				return fmt.Errorf("%s already exists (use --force to overwrite)", path)
+			}
+		}
+	}
+
+	if err := certs.WriteFile(keyPath, keyPEM, privateKeyPerm, overwrite); err != nil {
+		return err
+	}
+	return certs.WriteFile(certPath, certPEM, certificatePerm, overwrite)
+}
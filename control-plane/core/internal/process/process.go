@@ -1,7 +1,15 @@
 package process
 
 import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/init/config"
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/init/logger"
 	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/internal/infra"
+	"go.uber.org/zap"
 )
 
 type Process struct {
@@ -9,6 +17,10 @@ type Process struct {
 }
 
 func NewProcess() *Process {
+	if config.Get().Dev {
+		logger.NewDevelopmentLogger()
+	}
+
 	return &Process{
 		infra: infra.NewInfra(),
 	}
@@ -16,8 +28,31 @@ func NewProcess() *Process {
 
 func (p *Process) Launch() {
 	p.infra.LoadPlugins()
-	p.infra.LoadGrpcServer()
+	p.infra.LoadDevEnvironment()
 	p.infra.IntegrityCheck()
 
-	p.infra.Server.Start(p.infra.Ctx)
+	// The data store must be running before the server restores its state
+	p.infra.StartDataStore()
+	defer p.infra.StopPlugins()
+
+	p.infra.LoadGrpcServer()
+	p.infra.StartApiGateway()
+	p.infra.LoadHttpServer()
+
+	if err := p.infra.HttpServer.Start(); err != nil {
+		logger.L().Fatal("Failed to start HTTP server", zap.Error(err))
+	}
+	defer p.infra.HttpServer.Stop()
+
+	if p.infra.Dev != nil {
+		p.infra.Dev.PrintBanner(config.Get())
+	}
+
+	// SIGTERM and SIGINT drain the fleet and stop the server. Once received the
+	// default handling is restored, so a second signal kills the process.
+	ctx, stop := signal.NotifyContext(p.infra.Ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	context.AfterFunc(ctx, stop)
+
+	p.infra.Server.Start(ctx)
 }
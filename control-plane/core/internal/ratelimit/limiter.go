@@ -0,0 +1,193 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/init/config"
+)
+
+// Scopes a limit is keyed on
+const (
+	ScopePeer = "peer"
+	// ScopeNode is keyed on the thumbprint of a verified node certificate
+	ScopeNode = "node"
+)
+
+// Reasons a call is refused
+const (
+	ReasonRate    = "rate"
+	ReasonLockout = "lockout"
+)
+
+// Idle entries are forgotten this often
+const pruneInterval = time.Minute
+
+// Decision is the outcome of drawing a call from a bucket
+type Decision struct {
+	Allowed    bool
+	Reason     string
+	RetryAfter time.Duration
+}
+
+// entry is the bucket and failure history of one peer or node
+type entry struct {
+	tokens       float64
+	updated      time.Time
+	failures     int
+	firstFailure time.Time
+	lockouts     int
+	lockedUntil  time.Time
+}
+
+// Limiter throttles calls per peer address and per node certificate with token
+// buckets, and locks keys out after repeated failures
+type Limiter struct {
+	config    *config.RateLimitConfig
+	entries   map[string]map[string]*entry
+	lastPrune time.Time
+	mu        sync.Mutex
+}
+
+// NewLimiter creates a limiter from the rate limit configuration
+func NewLimiter() *Limiter {
+	cfg := config.Get().Core.RateLimit
+
+	return &Limiter{
+		config: &cfg,
+		entries: map[string]map[string]*entry{
+			ScopePeer: make(map[string]*entry),
+			ScopeNode: make(map[string]*entry),
+		},
+		lastPrune: time.Now(),
+	}
+}
+
+// Allow draws a call of key from its bucket
+func (l *Limiter) Allow(scope, key string) Decision {
+	if !l.config.Enabled || key == "" {
+		return Decision{Allowed: true}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.prune(now)
+
+	e := l.entry(scope, key, now)
+	if now.Before(e.lockedUntil) {
+		return Decision{Reason: ReasonLockout, RetryAfter: e.lockedUntil.Sub(now)}
+	}
+
+	rate, burst := l.bucket(scope)
+	e.tokens += now.Sub(e.updated).Seconds() * rate
+	if e.tokens > burst {
+		e.tokens = burst
+	}
+	e.updated = now
+
+	if e.tokens < 1 {
+		wait := time.Duration((1 - e.tokens) / rate * float64(time.Second))
+		return Decision{Reason: ReasonRate, RetryAfter: wait}
+	}
+	e.tokens--
+	return Decision{Allowed: true}
+}
+
+// Failure records a failed call of key. It returns the lockout duration when
+// the failure locked the key out, zero otherwise.
+func (l *Limiter) Failure(scope, key string) time.Duration {
+	if !l.config.Enabled || key == "" {
+		return 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	e := l.entry(scope, key, now)
+
+	// Lockouts only escalate while failures keep coming
+	if e.lockouts > 0 && now.Sub(e.lockedUntil) > l.config.LockoutMax {
+		e.lockouts = 0
+	}
+	if e.failures == 0 || now.Sub(e.firstFailure) > l.config.FailureWindow {
+		e.failures = 0
+		e.firstFailure = now
+	}
+
+	e.failures++
+	if e.failures < l.config.LockoutThreshold {
+		return 0
+	}
+
+	lockout := l.config.LockoutDuration << e.lockouts
+	if lockout > l.config.LockoutMax || lockout <= 0 {
+		lockout = l.config.LockoutMax
+	}
+	e.lockouts++
+	e.failures = 0
+	e.lockedUntil = now.Add(lockout)
+	return lockout
+}
+
+// Success clears the failure history of key
+func (l *Limiter) Success(scope, key string) {
+	if !l.config.Enabled || key == "" {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if e, ok := l.entries[scope][key]; ok {
+		e.failures = 0
+		e.lockouts = 0
+	}
+}
+
+// bucket returns the refill rate and capacity of scope
+func (l *Limiter) bucket(scope string) (float64, float64) {
+	if scope == ScopeNode {
+		return l.config.NodeRate, float64(l.config.NodeBurst)
+	}
+	return l.config.PeerRate, float64(l.config.PeerBurst)
+}
+
+// entry returns the entry of key, starting with a full bucket. Callers hold l.mu.
+func (l *Limiter) entry(scope, key string, now time.Time) *entry {
+	e, ok := l.entries[scope][key]
+	if !ok {
+		_, burst := l.bucket(scope)
+		e = &entry{tokens: burst, updated: now}
+		l.entries[scope][key] = e
+	}
+	return e
+}
+
+// prune forgets keys with a full bucket and no failure history worth keeping. Callers hold l.mu.
+func (l *Limiter) prune(now time.Time) {
+	if now.Sub(l.lastPrune) < pruneInterval {
+		return
+	}
+	l.lastPrune = now
+
+	for scope, entries := range l.entries {
+		rate, burst := l.bucket(scope)
+		refill := time.Duration(burst / rate * float64(time.Second))
+		for key, e := range entries {
+			idle := now.Sub(e.updated)
+			if idle < refill || now.Before(e.lockedUntil) {
+				continue
+			}
+			if e.failures > 0 && now.Sub(e.firstFailure) <= l.config.FailureWindow {
+				continue
+			}
+			if e.lockouts > 0 && now.Sub(e.lockedUntil) <= l.config.LockoutMax {
+				continue
+			}
+			delete(entries, key)
+		}
+	}
+}
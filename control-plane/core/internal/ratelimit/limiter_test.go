@@ -0,0 +1,146 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/init/config"
+)
+
+// newTestLimiter creates a limiter with the rate limit configuration adjusted by configure
+func newTestLimiter(configure func(*config.RateLimitConfig)) *Limiter {
+	cfg := config.DefaultConfig()
+	configure(&cfg.Core.RateLimit)
+	config.Set(cfg)
+	return NewLimiter()
+}
+
+// fail records failures of key and returns the last lockout
+func fail(l *Limiter, key string, failures int) time.Duration {
+	var lockout time.Duration
+	for i := 0; i < failures; i++ {
+		lockout = l.Failure(ScopePeer, key)
+	}
+	return lockout
+}
+
+func TestAllow(t *testing.T) {
+	l := newTestLimiter(func(cfg *config.RateLimitConfig) {
+		cfg.PeerRate, cfg.PeerBurst = 20, 3
+		cfg.NodeRate, cfg.NodeBurst = 20, 1
+	})
+
+	for i := 0; i < 3; i++ {
+		if d := l.Allow(ScopePeer, "10.0.0.1"); !d.Allowed {
+			t.Fatalf("call %d refused within the burst: %+v", i+1, d)
+		}
+	}
+	d := l.Allow(ScopePeer, "10.0.0.1")
+	if d.Allowed || d.Reason != ReasonRate || d.RetryAfter <= 0 || d.RetryAfter > 50*time.Millisecond {
+		t.Fatalf("Allow() past the burst = %+v, want a rate refusal within 50ms", d)
+	}
+
+	// Buckets are per key and per scope
+	if d := l.Allow(ScopePeer, "10.0.0.2"); !d.Allowed {
+		t.Fatalf("another peer refused: %+v", d)
+	}
+	if d := l.Allow(ScopeNode, "10.0.0.1"); !d.Allowed {
+		t.Fatalf("node scope refused: %+v", d)
+	}
+	if d := l.Allow(ScopeNode, "10.0.0.1"); d.Allowed {
+		t.Fatalf("node scope allowed past its burst of 1")
+	}
+
+	// The bucket refills at the configured rate
+	time.Sleep(60 * time.Millisecond)
+	if d := l.Allow(ScopePeer, "10.0.0.1"); !d.Allowed {
+		t.Fatalf("Allow() after refill = %+v", d)
+	}
+}
+
+func TestDisabled(t *testing.T) {
+	l := newTestLimiter(func(cfg *config.RateLimitConfig) {
+		cfg.Enabled = false
+		cfg.PeerBurst = 1
+		cfg.LockoutThreshold = 1
+	})
+
+	for i := 0; i < 5; i++ {
+		if d := l.Allow(ScopePeer, "10.0.0.1"); !d.Allowed {
+			t.Fatalf("disabled limiter refused call %d: %+v", i+1, d)
+		}
+	}
+	if lockout := fail(l, "10.0.0.1", 5); lockout != 0 {
+		t.Fatalf("disabled limiter locked out for %v", lockout)
+	}
+}
+
+func TestLockoutEscalation(t *testing.T) {
+	const duration = 40 * time.Millisecond
+	l := newTestLimiter(func(cfg *config.RateLimitConfig) {
+		cfg.LockoutThreshold = 3
+		cfg.FailureWindow = time.Minute
+		cfg.LockoutDuration = duration
+		cfg.LockoutMax = 100 * time.Millisecond
+	})
+	const key = "10.0.0.1"
+
+	if lockout := fail(l, key, 2); lockout != 0 {
+		t.Fatalf("locked out below the threshold for %v", lockout)
+	}
+	if lockout := fail(l, key, 1); lockout != duration {
+		t.Fatalf("first lockout = %v, want %v", lockout, duration)
+	}
+	d := l.Allow(ScopePeer, key)
+	if d.Allowed || d.Reason != ReasonLockout || d.RetryAfter <= 0 || d.RetryAfter > duration {
+		t.Fatalf("Allow() while locked out = %+v", d)
+	}
+	if d := l.Allow(ScopePeer, "10.0.0.2"); !d.Allowed {
+		t.Fatalf("another peer refused during the lockout: %+v", d)
+	}
+
+	// Repeated lockouts double up to the maximum
+	for _, want := range []time.Duration{2 * duration, 100 * time.Millisecond, 100 * time.Millisecond} {
+		time.Sleep(l.Allow(ScopePeer, key).RetryAfter)
+		if d := l.Allow(ScopePeer, key); !d.Allowed {
+			t.Fatalf("Allow() after the lockout = %+v", d)
+		}
+		if lockout := fail(l, key, 3); lockout != want {
+			t.Fatalf("repeated lockout = %v, want %v", lockout, want)
+		}
+	}
+
+	// A success clears the history
+	time.Sleep(l.Allow(ScopePeer, key).RetryAfter)
+	l.Success(ScopePeer, key)
+	if lockout := fail(l, key, 3); lockout != duration {
+		t.Fatalf("lockout after a success = %v, want %v", lockout, duration)
+	}
+}
+
+func TestLockoutHistoryExpires(t *testing.T) {
+	const duration = 20 * time.Millisecond
+	l := newTestLimiter(func(cfg *config.RateLimitConfig) {
+		cfg.LockoutThreshold = 3
+		cfg.FailureWindow = 30 * time.Millisecond
+		cfg.LockoutDuration = duration
+		cfg.LockoutMax = 40 * time.Millisecond
+	})
+	const key = "10.0.0.1"
+
+	// Failures spread beyond the window do not add up
+	fail(l, key, 2)
+	time.Sleep(40 * time.Millisecond)
+	if lockout := fail(l, key, 2); lockout != 0 {
+		t.Fatalf("failures outside the window locked out for %v", lockout)
+	}
+
+	// Lockouts stop escalating after a quiet period longer than the maximum
+	if lockout := fail(l, key, 1); lockout != duration {
+		t.Fatalf("first lockout = %v, want %v", lockout, duration)
+	}
+	time.Sleep(duration + 60*time.Millisecond)
+	if lockout := fail(l, key, 3); lockout != duration {
+		t.Fatalf("lockout after a quiet period = %v, want %v", lockout, duration)
+	}
+}
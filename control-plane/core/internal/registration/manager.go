@@ -0,0 +1,374 @@
+package registration
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/init/config"
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/init/logger"
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/pkg/certs"
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/shared/interfaces"
+	"go.uber.org/zap"
+)
+
+const registrationsBucket = "registrations"
+
+// Registration states
+const (
+	StatePending  = "pending"
+	StateApproved = "approved"
+	StateRejected = "rejected"
+	StateExpired  = "expired"
+)
+
+// Decided registrations are kept this long so nodes can still collect the decision
+const decisionRetention = 7 * 24 * time.Hour
+
+var (
+	ErrNotFound   = errors.New("registration not found")
+	ErrNotPending = errors.New("registration already decided")
+	ErrDeciding   = errors.New("registration is being decided")
+	ErrCollected  = errors.New("registration credentials already collected")
+)
+
+// Registration is a node waiting for, or having received, an operator decision
+type Registration struct {
+	NodeID         string    `json:"node_id"`
+	State          string    `json:"state"`
+	SecretHash     string    `json:"secret_hash"`
+	BootstrapToken string    `json:"bootstrap_token"`
+	Hostname       string    `json:"hostname"`
+	BasicInfo      []byte    `json:"basic_info"`
+	CSR            []byte    `json:"csr"`
+	PublicKeyPin   string    `json:"public_key_pin"`
+	Certificate    []byte    `json:"certificate,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+	ExpiresAt      time.Time `json:"expires_at"`
+	DecidedAt      time.Time `json:"decided_at,omitempty"`
+	// CollectedAt is set once the node received its certificate, which is then dropped
+	CollectedAt time.Time `json:"collected_at,omitempty"`
+	Reason      string    `json:"reason,omitempty"`
+}
+
+// Manager keeps the queue of registrations awaiting approval
+type Manager struct {
+	config        *config.RegistrationConfig
+	store         interfaces.DataStore
+	registrations map[string]*Registration
+	bySecret      map[string]string
+	waiters       map[string]chan struct{}
+	// deciding holds the registrations whose approval is being issued
+	deciding map[string]bool
+	mu       sync.Mutex
+}
+
+// NewManager restores the queue from store
+func NewManager(store interfaces.DataStore) (*Manager, error) {
+	cfg := config.Get().Core.Registration
+
+	m := &Manager{
+		config:        &cfg,
+		store:         store,
+		registrations: make(map[string]*Registration),
+		bySecret:      make(map[string]string),
+		waiters:       make(map[string]chan struct{}),
+		deciding:      make(map[string]bool),
+	}
+
+	if store == nil {
+		return m, nil
+	}
+
+	stored, err := store.List(registrationsBucket)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load registrations: %w", err)
+	}
+	for _, data := range stored {
+		var reg Registration
+		if err := json.Unmarshal(data, &reg); err != nil {
+			return nil, fmt.Errorf("failed to decode registration: %w", err)
+		}
+		m.registrations[reg.NodeID] = &reg
+		m.bySecret[reg.SecretHash] = reg.NodeID
+	}
+
+	return m, nil
+}
+
+// Submit queues a registration for approval. The returned secret lets the
+// node poll for the decision; only its hash is kept.
+func (m *Manager) Submit(nodeID, bootstrapToken, hostname string, basicInfo, csrBytes []byte) (*Registration, string, error) {
+	csr, err := certs.ParseCSR(csrBytes)
+	if err != nil {
+		return nil, "", err
+	}
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, "", fmt.Errorf("failed to generate registration secret: %w", err)
+	}
+	secret := base64.RawURLEncoding.EncodeToString(buf)
+
+	pin := sha256.Sum256(csr.RawSubjectPublicKeyInfo)
+	now := time.Now()
+	reg := &Registration{
+		NodeID:         nodeID,
+		State:          StatePending,
+		SecretHash:     hashSecret(secret),
+		BootstrapToken: bootstrapToken,
+		Hostname:       hostname,
+		BasicInfo:      basicInfo,
+		CSR:            csrBytes,
+		PublicKeyPin:   "sha256/" + base64.StdEncoding.EncodeToString(pin[:]),
+		CreatedAt:      now,
+		ExpiresAt:      now.Add(m.config.PendingTimeout),
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.prune(now)
+	if m.deciding[nodeID] {
+		return nil, "", ErrDeciding
+	}
+	if err := m.save(reg); err != nil {
+		return nil, "", err
+	}
+	if previous, ok := m.registrations[nodeID]; ok {
+		delete(m.bySecret, previous.SecretHash)
+	}
+	m.registrations[nodeID] = reg
+	m.bySecret[reg.SecretHash] = nodeID
+
+	logger.L().Info("Registration awaiting approval",
+		zap.String("node_id", nodeID),
+		zap.String("hostname", hostname),
+		zap.String("bootstrap_token", bootstrapToken),
+		zap.String("public_key_pin", reg.PublicKeyPin),
+	)
+	return m.snapshot(reg), secret, nil
+}
+
+// Wait returns the registration of secret once decided, or still pending after timeout
+func (m *Manager) Wait(ctx context.Context, secret string, timeout time.Duration) (*Registration, error) {
+	hash := hashSecret(secret)
+
+	m.mu.Lock()
+	reg, err := m.lookup(hash)
+	if err != nil || reg.State != StatePending || timeout <= 0 {
+		m.mu.Unlock()
+		return reg, err
+	}
+	waiter, ok := m.waiters[reg.NodeID]
+	if !ok {
+		waiter = make(chan struct{})
+		m.waiters[reg.NodeID] = waiter
+	}
+	m.mu.Unlock()
+
+	select {
+	case <-waiter:
+	case <-time.After(timeout):
+	case <-ctx.Done():
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.lookup(hash)
+}
+
+// Collect hands out the certificate of the approved registration of secret,
+// once: later calls get ErrCollected
+func (m *Manager) Collect(secret string) (*Registration, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	reg, err := m.lookup(hashSecret(secret))
+	if err != nil {
+		return nil, err
+	}
+	if reg.State != StateApproved {
+		return nil, ErrNotFound
+	}
+	if !reg.CollectedAt.IsZero() {
+		return nil, ErrCollected
+	}
+
+	collected := *reg
+	collected.CollectedAt = time.Now()
+	collected.Certificate = nil
+	if err := m.save(&collected); err != nil {
+		return nil, err
+	}
+	*m.registrations[reg.NodeID] = collected
+
+	return reg, nil
+}
+
+// Get returns the registration of a node
+func (m *Manager) Get(nodeID string) (*Registration, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	reg, ok := m.registrations[nodeID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return m.snapshot(reg), nil
+}
+
+// List returns the registrations in state, or all of them when state is empty, oldest first
+func (m *Manager) List(state string) []*Registration {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.prune(time.Now())
+
+	var regs []*Registration
+	for _, reg := range m.registrations {
+		if state == "" || reg.State == state {
+			regs = append(regs, m.snapshot(reg))
+		}
+	}
+	sort.Slice(regs, func(i, j int) bool {
+		return regs[i].CreatedAt.Before(regs[j].CreatedAt)
+	})
+	return regs
+}
+
+// Decide approves or rejects a pending registration. issue runs before an
+// approval is recorded and returns the certificate delivered to the node;
+// if it fails the registration stays pending. issue runs without the lock
+// held, the registration being marked as deciding meanwhile.
+func (m *Manager) Decide(nodeID string, approve bool, reason string, issue func(*Registration) ([]byte, error)) (*Registration, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.prune(time.Now())
+
+	reg, ok := m.registrations[nodeID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if reg.State != StatePending {
+		return m.snapshot(reg), ErrNotPending
+	}
+	if m.deciding[nodeID] {
+		return m.snapshot(reg), ErrDeciding
+	}
+
+	decided := *reg
+	decided.Reason = reason
+	decided.State = StateRejected
+	if approve {
+		m.deciding[nodeID] = true
+		pending := m.snapshot(reg)
+
+		m.mu.Unlock()
+		cert, err := issue(pending)
+		m.mu.Lock()
+
+		delete(m.deciding, nodeID)
+		if err != nil {
+			return nil, err
+		}
+		decided.State = StateApproved
+		decided.Certificate = cert
+	}
+	decided.DecidedAt = time.Now()
+
+	if err := m.save(&decided); err != nil {
+		return nil, err
+	}
+	*reg = decided
+	m.notify(nodeID)
+
+	logger.L().Info("Registration decided",
+		zap.String("node_id", nodeID),
+		zap.String("state", reg.State),
+		zap.String("reason", reason),
+	)
+	return m.snapshot(reg), nil
+}
+
+// lookup returns the registration of a secret hash. Callers hold m.mu.
+func (m *Manager) lookup(hash string) (*Registration, error) {
+	m.prune(time.Now())
+
+	nodeID, ok := m.bySecret[hash]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return m.snapshot(m.registrations[nodeID]), nil
+}
+
+// prune expires stale pending registrations and forgets old decisions. Callers hold m.mu.
+func (m *Manager) prune(now time.Time) {
+	for nodeID, reg := range m.registrations {
+		switch {
+		case reg.State == StatePending && now.After(reg.ExpiresAt) && !m.deciding[nodeID]:
+			reg.State = StateExpired
+			reg.DecidedAt = now
+			if err := m.save(reg); err != nil {
+				logger.L().Warn("Failed to persist expired registration",
+					zap.String("node_id", nodeID),
+					zap.Error(err),
+				)
+			}
+			m.notify(nodeID)
+		case reg.State != StatePending && now.Sub(reg.DecidedAt) > decisionRetention:
+			delete(m.registrations, nodeID)
+			delete(m.bySecret, reg.SecretHash)
+			if m.store != nil {
+				if err := m.store.Delete(registrationsBucket, nodeID); err != nil && !errors.Is(err, interfaces.ErrNotFound) {
+					logger.L().Warn("Failed to delete registration",
+						zap.String("node_id", nodeID),
+						zap.Error(err),
+					)
+				}
+			}
+		}
+	}
+}
+
+// notify wakes the nodes waiting on a registration. Callers hold m.mu.
+func (m *Manager) notify(nodeID string) {
+	if waiter, ok := m.waiters[nodeID]; ok {
+		close(waiter)
+		delete(m.waiters, nodeID)
+	}
+}
+
+func (m *Manager) save(reg *Registration) error {
+	if m.store == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(reg)
+	if err != nil {
+		return fmt.Errorf("failed to encode registration: %w", err)
+	}
+	if err := m.store.Put(registrationsBucket, reg.NodeID, data); err != nil {
+		return fmt.Errorf("failed to persist registration: %w", err)
+	}
+	return nil
+}
+
+func (m *Manager) snapshot(reg *Registration) *Registration {
+	copied := *reg
+	return &copied
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,107 @@
+package registration
+
+import (
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"testing"
+
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/init/config"
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/init/logger"
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/internal/store"
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/core/pkg/certs"
+)
+
+func newCSR(t *testing.T, nodeID string) []byte {
+	t.Helper()
+
+	key, err := certs.GenerateKey(certs.KeyTypeEd25519)
+	if err != nil {
+		t.Fatalf("failed to generate node key: %v", err)
+	}
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{Subject: pkix.Name{CommonName: nodeID}}, key)
+	if err != nil {
+		t.Fatalf("failed to create CSR: %v", err)
+	}
+	return csr
+}
+
+// submitApproved queues a registration and approves it, returning its secret
+func submitApproved(t *testing.T, m *Manager, nodeID string) string {
+	t.Helper()
+
+	_, secret, err := m.Submit(nodeID, "bootstrap", "node-1", nil, newCSR(t, nodeID))
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	issue := func(*Registration) ([]byte, error) { return []byte("certificate"), nil }
+	if _, err := m.Decide(nodeID, true, "", issue); err != nil {
+		t.Fatalf("Decide() error = %v", err)
+	}
+	return secret
+}
+
+func TestCollectOnce(t *testing.T) {
+	if logger.L() == nil {
+		logger.NewDevelopmentLogger()
+	}
+	config.Set(config.DefaultConfig())
+
+	dataStore := store.NewMemory()
+	m, err := NewManager(dataStore)
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	secret := submitApproved(t, m, "node-1")
+
+	reg, err := m.Collect(secret)
+	if err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+	if string(reg.Certificate) != "certificate" {
+		t.Fatalf("Collect() certificate = %q", reg.Certificate)
+	}
+
+	if _, err := m.Collect(secret); !errors.Is(err, ErrCollected) {
+		t.Fatalf("second Collect() error = %v, want %v", err, ErrCollected)
+	}
+	if reg, err := m.Get("node-1"); err != nil || reg.Certificate != nil || reg.CollectedAt.IsZero() {
+		t.Fatalf("Get() = %+v, %v, want a collected registration without certificate", reg, err)
+	}
+
+	// The collection is persisted
+	m, err = NewManager(dataStore)
+	if err != nil {
+		t.Fatalf("NewManager() after restart error = %v", err)
+	}
+	if _, err := m.Collect(secret); !errors.Is(err, ErrCollected) {
+		t.Fatalf("Collect() after restart error = %v, want %v", err, ErrCollected)
+	}
+}
+
+func TestCollectRefusesUndecided(t *testing.T) {
+	if logger.L() == nil {
+		logger.NewDevelopmentLogger()
+	}
+	config.Set(config.DefaultConfig())
+
+	m, err := NewManager(nil)
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	_, secret, err := m.Submit("node-1", "bootstrap", "node-1", nil, newCSR(t, "node-1"))
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+
+	if _, err := m.Collect(secret); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Collect() of a pending registration error = %v, want %v", err, ErrNotFound)
+	}
+	if _, err := m.Decide("node-1", false, "not ours", nil); err != nil {
+		t.Fatalf("Decide() error = %v", err)
+	}
+	if _, err := m.Collect(secret); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Collect() of a rejected registration error = %v, want %v", err, ErrNotFound)
+	}
+}
@@ -0,0 +1,90 @@
+package store
+
+import (
+	"sync"
+
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/shared/interfaces"
+)
+
+var _ interfaces.DataStore = &Memory{}
+
+// Memory is a volatile DataStore, used in development mode
+type Memory struct {
+	buckets map[string]map[string][]byte
+	mu      sync.RWMutex
+}
+
+func NewMemory() *Memory {
+	return &Memory{
+		buckets: make(map[string]map[string][]byte),
+	}
+}
+
+func (m *Memory) GetName() string {
+	return "memory-store"
+}
+
+func (m *Memory) GetVersion() string {
+	return "0.0.1"
+}
+
+func (m *Memory) Init(settings map[string]string) error {
+	return nil
+}
+
+func (m *Memory) Start() error {
+	return nil
+}
+
+func (m *Memory) Stop() error {
+	return nil
+}
+
+func (m *Memory) Get(bucket, key string) ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	value, ok := m.buckets[bucket][key]
+	if !ok {
+		return nil, interfaces.ErrNotFound
+	}
+	return append([]byte(nil), value...), nil
+}
+
+func (m *Memory) Put(bucket, key string, value []byte) error {
+	// Refused like the file store does, so development catches such names
+	if err := interfaces.CheckName(bucket); err != nil {
+		return err
+	}
+	if err := interfaces.CheckName(key); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.buckets[bucket] == nil {
+		m.buckets[bucket] = make(map[string][]byte)
+	}
+	m.buckets[bucket][key] = append([]byte(nil), value...)
+	return nil
+}
+
+func (m *Memory) Delete(bucket, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.buckets[bucket], key)
+	return nil
+}
+
+func (m *Memory) List(bucket string) (map[string][]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	values := make(map[string][]byte, len(m.buckets[bucket]))
+	for key, value := range m.buckets[bucket] {
+		values[key] = append([]byte(nil), value...)
+	}
+	return values, nil
+}
@@ -0,0 +1,224 @@
+package validation
+
+import (
+	"fmt"
+	"math"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// Limits of the fields without a rule of their own, so no field is unbounded
+const (
+	DefaultMaxStringLen = 1024
+	DefaultMaxBytesLen  = 64 << 10
+	DefaultMaxItems     = 256
+)
+
+// maxViolations bounds the violations reported for one message
+const maxViolations = 20
+
+// Field constrains a field of a message. Limits left at zero fall back to the
+// defaults; MaxLen also bounds the keys of maps.
+type Field struct {
+	// Required fields must be set: non-empty for strings, bytes, lists and
+	// maps, non-zero for numbers
+	Required bool
+	MaxLen   int
+	MaxItems int
+	// Bounds constrain numbers, and every number of lists and maps
+	Bounds *Bounds
+	// KnownEnum refuses enum values missing from the descriptor. Enums are
+	// open otherwise, so peers built from a newer schema are not refused.
+	KnownEnum bool
+}
+
+// Bounds is an inclusive range of numbers
+type Bounds struct {
+	Min, Max float64
+}
+
+// Between bounds numbers to [min, max]
+func Between(min, max float64) *Bounds {
+	return &Bounds{Min: min, Max: max}
+}
+
+// AtLeast bounds numbers to [min, +inf)
+func AtLeast(min float64) *Bounds {
+	return &Bounds{Min: min, Max: math.Inf(1)}
+}
+
+// Rules are the field constraints of each message type
+type Rules map[protoreflect.FullName]map[protoreflect.Name]Field
+
+// Message returns the rules key of the type of msg
+func Message(msg proto.Message) protoreflect.FullName {
+	return msg.ProtoReflect().Descriptor().FullName()
+}
+
+// Violation is a field breaking its rule, named by its path from the validated
+// message, e.g. status.resources["cpu"].usage_percentage
+type Violation struct {
+	Field       string
+	Description string
+}
+
+// Validator checks messages and the messages they hold against rules. Every
+// field is checked: non-finite floats are refused, and strings, bytes, lists
+// and maps are bounded by the default limits.
+type Validator struct {
+	rules Rules
+}
+
+// NewValidator creates a validator enforcing rules, which must name registered
+// messages and their fields
+func NewValidator(rules Rules) (*Validator, error) {
+	for message, fields := range rules {
+		desc, err := protoregistry.GlobalFiles.FindDescriptorByName(message)
+		if err != nil {
+			return nil, fmt.Errorf("rules of %s: %w", message, err)
+		}
+		md, ok := desc.(protoreflect.MessageDescriptor)
+		if !ok {
+			return nil, fmt.Errorf("rules of %s: not a message", message)
+		}
+		for name, rule := range fields {
+			fd := md.Fields().ByName(name)
+			if fd == nil {
+				return nil, fmt.Errorf("rules of %s: unknown field %s", message, name)
+			}
+			if rule.KnownEnum && fd.Kind() != protoreflect.EnumKind {
+				return nil, fmt.Errorf("rules of %s: field %s is not an enum", message, name)
+			}
+		}
+	}
+	return &Validator{rules: rules}, nil
+}
+
+// Validate returns the violations of msg, at most maxViolations
+func (v *Validator) Validate(msg proto.Message) []Violation {
+	c := &check{rules: v.rules}
+	c.message(msg.ProtoReflect(), "")
+	return c.violations
+}
+
+type check struct {
+	rules      Rules
+	violations []Violation
+}
+
+func (c *check) add(path, format string, args ...interface{}) {
+	if c.full() {
+		return
+	}
+	c.violations = append(c.violations, Violation{Field: path, Description: fmt.Sprintf(format, args...)})
+}
+
+func (c *check) full() bool {
+	return len(c.violations) >= maxViolations
+}
+
+func (c *check) message(m protoreflect.Message, prefix string) {
+	desc := m.Descriptor()
+	rules := c.rules[desc.FullName()]
+
+	fields := desc.Fields()
+	for i := 0; i < fields.Len() && !c.full(); i++ {
+		fd := fields.Get(i)
+		rule := rules[fd.Name()]
+		path := string(fd.Name())
+		if prefix != "" {
+			path = prefix + "." + path
+		}
+
+		if !m.Has(fd) {
+			if rule.Required {
+				c.add(path, "is required")
+			}
+			continue
+		}
+
+		val := m.Get(fd)
+		switch {
+		case fd.IsList():
+			list := val.List()
+			if max := limit(rule.MaxItems, DefaultMaxItems); list.Len() > max {
+				c.add(path, "must have at most %d items", max)
+				continue
+			}
+			for j := 0; j < list.Len() && !c.full(); j++ {
+				c.value(fd, list.Get(j), fmt.Sprintf("%s[%d]", path, j), rule)
+			}
+		case fd.IsMap():
+			entries := val.Map()
+			if max := limit(rule.MaxItems, DefaultMaxItems); entries.Len() > max {
+				c.add(path, "must have at most %d entries", max)
+				continue
+			}
+			entries.Range(func(key protoreflect.MapKey, value protoreflect.Value) bool {
+				entry := fmt.Sprintf("%s[%q]", path, key.String())
+				if max := limit(rule.MaxLen, DefaultMaxStringLen); len(key.String()) > max {
+					c.add(entry, "key must be at most %d bytes", max)
+					return !c.full()
+				}
+				c.value(fd.MapValue(), value, entry, rule)
+				return !c.full()
+			})
+		default:
+			c.value(fd, val, path, rule)
+		}
+	}
+}
+
+// value checks a single value of fd: the field itself, or an item of a list or map
+func (c *check) value(fd protoreflect.FieldDescriptor, val protoreflect.Value, path string, rule Field) {
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		if max := limit(rule.MaxLen, DefaultMaxStringLen); len(val.String()) > max {
+			c.add(path, "must be at most %d bytes", max)
+		}
+	case protoreflect.BytesKind:
+		if max := limit(rule.MaxLen, DefaultMaxBytesLen); len(val.Bytes()) > max {
+			c.add(path, "must be at most %d bytes", max)
+		}
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		c.message(val.Message(), path)
+	case protoreflect.EnumKind:
+		if rule.KnownEnum && fd.Enum().Values().ByNumber(val.Enum()) == nil {
+			c.add(path, "unknown value %d", val.Enum())
+		}
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		f := val.Float()
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			c.add(path, "must be a finite number")
+			return
+		}
+		c.bounds(f, path, rule)
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		c.bounds(float64(val.Int()), path, rule)
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind,
+		protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		c.bounds(float64(val.Uint()), path, rule)
+	}
+}
+
+func (c *check) bounds(f float64, path string, rule Field) {
+	b := rule.Bounds
+	if b == nil || (f >= b.Min && f <= b.Max) {
+		return
+	}
+	if math.IsInf(b.Max, 1) {
+		c.add(path, "must be at least %g", b.Min)
+		return
+	}
+	c.add(path, "must be between %g and %g", b.Min, b.Max)
+}
+
+func limit(value, fallback int) int {
+	if value > 0 {
+		return value
+	}
+	return fallback
+}
@@ -0,0 +1,46 @@
+package validation
+
+import (
+	"testing"
+
+	pb "github.com/raphaelCamblong/Luminous-Mesh/control-plane/shared/proto"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestUnknownEnumValues(t *testing.T) {
+	v, err := NewValidator(Rules{
+		Message(&pb.ControlPlaneCommand{}): {"priority": {KnownEnum: true}},
+	})
+	if err != nil {
+		t.Fatalf("NewValidator() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		msg  proto.Message
+		want int
+	}{
+		{"known value", &pb.NodeStatusUpdate{Status: &pb.NodeStatus{State: pb.NodeStatus_HEALTHY}}, 0},
+		// Sent by a node built from a newer schema
+		{"unknown value of an open enum", &pb.NodeStatusUpdate{Status: &pb.NodeStatus{State: 42}}, 0},
+		{"known value of a closed enum", &pb.ControlPlaneCommand{Priority: pb.ControlPlaneCommand_BULK}, 0},
+		{"unknown value of a closed enum", &pb.ControlPlaneCommand{Priority: 42}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if violations := v.Validate(tt.msg); len(violations) != tt.want {
+				t.Fatalf("Validate() = %v, want %d violations", violations, tt.want)
+			}
+		})
+	}
+}
+
+func TestKnownEnumOnlyOnEnums(t *testing.T) {
+	_, err := NewValidator(Rules{
+		Message(&pb.ControlPlaneCommand{}): {"command_id": {KnownEnum: true}},
+	})
+	if err == nil {
+		t.Fatalf("NewValidator() accepted KnownEnum on a string field")
+	}
+}
@@ -0,0 +1,114 @@
+package certs
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+)
+
+// The signing agent protocol is one JSON request and one JSON response per
+// connection over a local socket. The CA key never leaves the agent process,
+// an external daemon which is responsible for restricting who may connect.
+
+const agentTimeout = 10 * time.Second
+
+type agentRequest struct {
+	Op            string      `json:"op"`
+	Digest        []byte      `json:"digest,omitempty"`
+	Hash          crypto.Hash `json:"hash,omitempty"`
+	PSS           bool        `json:"pss,omitempty"`
+	PSSSaltLength int         `json:"pss_salt_length,omitempty"`
+}
+
+type agentResponse struct {
+	PublicKey []byte `json:"public_key,omitempty"`
+	Signature []byte `json:"signature,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+type agentSigner struct {
+	network string
+	address string
+	cert    *x509.Certificate
+	public  crypto.PublicKey
+}
+
+// NewAgentSigner returns a CASigner delegating signatures to a signing agent
+// listening on a unix socket
+func NewAgentSigner(certPath, socketPath string) (CASigner, error) {
+	cert, err := LoadCertificate(certPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load CA cert: %w", err)
+	}
+
+	s := &agentSigner{network: "unix", address: socketPath, cert: cert}
+
+	resp, err := s.call(&agentRequest{Op: "public"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch agent public key: %w", err)
+	}
+
+	pub, err := x509.ParsePKIXPublicKey(resp.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse agent public key: %w", err)
+	}
+
+	if err := checkCACertificate(cert, pub); err != nil {
+		return nil, err
+	}
+
+	s.public = pub
+	return s, nil
+}
+
+func (s *agentSigner) Certificate() *x509.Certificate {
+	return s.cert
+}
+
+func (s *agentSigner) Public() crypto.PublicKey {
+	return s.public
+}
+
+func (s *agentSigner) Sign(_ io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	req := &agentRequest{Op: "sign", Digest: digest, Hash: opts.HashFunc()}
+	if pss, ok := opts.(*rsa.PSSOptions); ok {
+		req.PSS = true
+		req.PSSSaltLength = pss.SaltLength
+	}
+
+	resp, err := s.call(req)
+	if err != nil {
+		return nil, fmt.Errorf("signing agent: %w", err)
+	}
+	return resp.Signature, nil
+}
+
+func (s *agentSigner) call(req *agentRequest) (*agentResponse, error) {
+	conn, err := net.DialTimeout(s.network, s.address, agentTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	_ = conn.SetDeadline(time.Now().Add(agentTimeout))
+
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return nil, err
+	}
+
+	var resp agentResponse
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return nil, err
+	}
+
+	if resp.Error != "" {
+		return nil, errors.New(resp.Error)
+	}
+	return &resp, nil
+}
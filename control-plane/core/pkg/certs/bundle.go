@@ -0,0 +1,161 @@
+package certs
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"time"
+)
+
+// TrustBundle is the set of CA certificates trusted for node certificates.
+// Exactly one of them, the issuer, signs new certificates; the others are
+// kept so certificates they issued stay valid while nodes re-key.
+type TrustBundle struct {
+	issuer CASigner
+	certs  []*x509.Certificate
+	pool   *x509.CertPool
+}
+
+// NewTrustBundle builds a bundle from the issuing CA and previously trusted CAs
+func NewTrustBundle(issuer CASigner, trusted ...*x509.Certificate) (*TrustBundle, error) {
+	b := &TrustBundle{
+		issuer: issuer,
+		certs:  []*x509.Certificate{issuer.Certificate()},
+		pool:   x509.NewCertPool(),
+	}
+	b.pool.AddCert(issuer.Certificate())
+
+	for _, cert := range trusted {
+		if !cert.IsCA {
+			return nil, fmt.Errorf("certificate %q is not a CA", cert.Subject.CommonName)
+		}
+		if b.contains(cert) {
+			continue
+		}
+		b.certs = append(b.certs, cert)
+		b.pool.AddCert(cert)
+	}
+
+	return b, nil
+}
+
+// Issuer returns the CA signing new certificates
+func (b *TrustBundle) Issuer() CASigner {
+	return b.issuer
+}
+
+// Certificates returns every trusted CA, issuer first
+func (b *TrustBundle) Certificates() []*x509.Certificate {
+	return b.certs
+}
+
+// Pool returns the bundle as a certificate pool
+func (b *TrustBundle) Pool() *x509.CertPool {
+	return b.pool
+}
+
+// PEM returns the concatenated PEM encoding of the bundle
+func (b *TrustBundle) PEM() []byte {
+	return EncodeCertificates(b.certs)
+}
+
+// Version identifies the content of the bundle, the hex SHA-256 of its PEM
+func (b *TrustBundle) Version() string {
+	sum := sha256.Sum256(b.PEM())
+	return hex.EncodeToString(sum[:])
+}
+
+// IsIssuedByCurrentCA reports whether the certificate was signed by the issuer
+func (b *TrustBundle) IsIssuedByCurrentCA(cert *x509.Certificate) bool {
+	return cert.CheckSignatureFrom(b.issuer.Certificate()) == nil
+}
+
+// Verify checks the certificate chains to one of the trusted CAs and returns that CA
+func (b *TrustBundle) Verify(cert *x509.Certificate, usage x509.ExtKeyUsage) (*x509.Certificate, error) {
+	chains, err := cert.Verify(x509.VerifyOptions{
+		Roots:       b.pool,
+		CurrentTime: time.Now(),
+		KeyUsages:   []x509.ExtKeyUsage{usage},
+	})
+	if err != nil {
+		return nil, err
+	}
+	chain := chains[0]
+	return chain[len(chain)-1], nil
+}
+
+func (b *TrustBundle) contains(cert *x509.Certificate) bool {
+	for _, c := range b.certs {
+		if c.Equal(cert) {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadCertificates loads every certificate of a PEM file
+func LoadCertificates(path string) ([]*x509.Certificate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read certificates: %w", err)
+	}
+	return ParseCertificatesPEM(data)
+}
+
+// ParseCertificatesPEM parses all CERTIFICATE blocks of a PEM document
+func ParseCertificatesPEM(data []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse certificate: %w", err)
+		}
+		certs = append(certs, cert)
+	}
+
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("no certificate found in PEM data")
+	}
+	return certs, nil
+}
+
+// EncodeCertificates PEM-encodes a list of certificates
+func EncodeCertificates(certs []*x509.Certificate) []byte {
+	var buf bytes.Buffer
+	for _, cert := range certs {
+		_ = pem.Encode(&buf, &pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+	}
+	return buf.Bytes()
+}
+
+// Fingerprint returns the hex SHA-256 fingerprint of a certificate
+func Fingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// Thumbprint returns the base64url SHA-256 thumbprint of a certificate, the
+// x5t#S256 value of RFC 8705 certificate-bound tokens
+func Thumbprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// SPKIPin returns the "sha256/<base64>" pin of a certificate public key
+func SPKIPin(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return "sha256/" + base64.StdEncoding.EncodeToString(sum[:])
+}
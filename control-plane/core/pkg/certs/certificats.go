@@ -1,6 +1,9 @@
 package certs
 
 import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
@@ -11,56 +14,118 @@ import (
 	"os"
 )
 
+// ErrInvalidCSR is returned for certificate requests that do not parse or verify
+var ErrInvalidCSR = errors.New("invalid certificate signing request")
+
+// ParseCSR parses a DER encoded certificate request and checks its self-signature
+func ParseCSR(der []byte) (*x509.CertificateRequest, error) {
+	csr, err := x509.ParseCertificateRequest(der)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidCSR, err)
+	}
+	if err := csr.CheckSignature(); err != nil {
+		return nil, fmt.Errorf("%w: bad signature: %w", ErrInvalidCSR, err)
+	}
+	return csr, nil
+}
+
 func GenerateSerialNumber() *big.Int {
 	serialNumberLimit := new(big.Int).Lsh(big.NewInt(1), 128)
 	serialNumber, _ := rand.Int(rand.Reader, serialNumberLimit)
 	return serialNumber
 }
 
-func LoadCA(certPath, keyPath string) (*x509.Certificate, *rsa.PrivateKey, error) {
-	certPEM, err := os.ReadFile(certPath)
+// LoadCA loads a CA certificate and its private key from PEM files.
+// The key may be RSA, ECDSA or Ed25519, optionally encrypted with passphrase.
+func LoadCA(certPath, keyPath string, passphrase []byte) (*x509.Certificate, crypto.Signer, error) {
+	cert, err := LoadCertificate(certPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load CA cert: %w", err)
+	}
+
+	keyPEM, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read CA key: %w", err)
+	}
+
+	key, err := ParsePrivateKeyPEM(keyPEM, passphrase)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse CA key: %w", err)
+	}
+
+	return cert, key, nil
+}
+
+// LoadCertificate loads the first certificate of a PEM file
+func LoadCertificate(path string) (*x509.Certificate, error) {
+	certPEM, err := os.ReadFile(path)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to read CA cert: %w", err)
+		return nil, fmt.Errorf("failed to read cert: %w", err)
 	}
 
 	block, _ := pem.Decode(certPEM)
 	if block == nil {
-		return nil, nil, fmt.Errorf("failed to decode CA cert PEM")
+		return nil, fmt.Errorf("failed to decode cert PEM")
 	}
 
 	cert, err := x509.ParseCertificate(block.Bytes)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to parse CA cert: %w", err)
+		return nil, fmt.Errorf("failed to parse cert: %w", err)
 	}
+	return cert, nil
+}
 
-	// Load CA private key
-	keyPEM, err := os.ReadFile(keyPath)
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to read CA key: %w", err)
+// ParsePrivateKeyPEM decodes a PEM private key, decrypting it when needed
+func ParsePrivateKeyPEM(keyPEM, passphrase []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, fmt.Errorf("failed to decode key PEM")
 	}
 
-	block, _ = pem.Decode(keyPEM)
-	if block == nil {
-		return nil, nil, fmt.Errorf("failed to decode CA key PEM")
+	der := block.Bytes
+	switch {
+	case block.Type == "ENCRYPTED PRIVATE KEY":
+		if len(passphrase) == 0 {
+			return nil, fmt.Errorf("key is encrypted but no passphrase was provided")
+		}
+		decrypted, err := decryptPKCS8(der, passphrase)
+		if err != nil {
+			return nil, err
+		}
+		der = decrypted
+	case block.Headers["Proc-Type"] == "4,ENCRYPTED":
+		// Legacy RFC 1423 encryption is unauthenticated and open to padding oracles
+		return nil, fmt.Errorf("legacy PEM encryption is not supported, convert the key with `openssl pkcs8 -topk8 -v2 aes-256-cbc`")
 	}
 
+	return ParsePrivateKey(der)
+}
+
+// ParsePrivateKey parses a DER private key in PKCS#8, PKCS#1 or SEC 1 form
+func ParsePrivateKey(der []byte) (crypto.Signer, error) {
 	// Try PKCS#8
-	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
-	if err == nil {
-		return cert, key.(*rsa.PrivateKey), nil
+	if key, err := x509.ParsePKCS8PrivateKey(der); err == nil {
+		switch k := key.(type) {
+		case *rsa.PrivateKey:
+			return k, nil
+		case *ecdsa.PrivateKey:
+			return k, nil
+		case ed25519.PrivateKey:
+			return k, nil
+		default:
+			return nil, fmt.Errorf("unsupported PKCS#8 key type %T", key)
+		}
 	}
 
 	// Fallback to RSA (PKCS#1)
-	key2, err2 := x509.ParsePKCS1PrivateKey(block.Bytes)
-	if err2 == nil {
-		return cert, key2, nil
+	if key, err := x509.ParsePKCS1PrivateKey(der); err == nil {
+		return key, nil
 	}
 
-	// // Or maybe EC
-	// key3, err3 := x509.ParseECPrivateKey(block.Bytes)
-	// if err3 == nil {
-	// 	return cert, key3, nil
-	// }
+	// Or maybe EC
+	if key, err := x509.ParseECPrivateKey(der); err == nil {
+		return key, nil
+	}
 
-	return cert, nil, errors.New("unknown private key format")
+	return nil, errors.New("unknown private key format")
 }
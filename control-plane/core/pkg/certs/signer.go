@@ -0,0 +1,66 @@
+package certs
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/x509"
+	"fmt"
+)
+
+// CASigner is a certificate authority whose private key is only reachable
+// through crypto.Signer. Implementations may hold the key in memory, behind a
+// passphrase or in an external signing daemon.
+type CASigner interface {
+	crypto.Signer
+	Certificate() *x509.Certificate
+}
+
+type keySigner struct {
+	crypto.Signer
+	cert *x509.Certificate
+}
+
+// NewCASigner wraps an in-memory key as a CASigner after checking it matches the CA certificate
+func NewCASigner(cert *x509.Certificate, key crypto.Signer) (CASigner, error) {
+	if err := checkCACertificate(cert, key.Public()); err != nil {
+		return nil, err
+	}
+	return &keySigner{Signer: key, cert: cert}, nil
+}
+
+// NewFileSigner loads a CA certificate and its (optionally encrypted) key from PEM files
+func NewFileSigner(certPath, keyPath string, passphrase []byte) (CASigner, error) {
+	cert, key, err := LoadCA(certPath, keyPath, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return NewCASigner(cert, key)
+}
+
+func (s *keySigner) Certificate() *x509.Certificate {
+	return s.cert
+}
+
+// checkCACertificate verifies the certificate can act as a CA for the given public key
+func checkCACertificate(cert *x509.Certificate, pub crypto.PublicKey) error {
+	if !cert.IsCA {
+		return fmt.Errorf("certificate %q is not a CA", cert.Subject.CommonName)
+	}
+
+	if cert.KeyUsage != 0 && cert.KeyUsage&x509.KeyUsageCertSign == 0 {
+		return fmt.Errorf("certificate %q is not allowed to sign certificates", cert.Subject.CommonName)
+	}
+
+	key, ok := pub.(interface{ Equal(crypto.PublicKey) bool })
+	if !ok || !key.Equal(cert.PublicKey) {
+		return fmt.Errorf("private key does not match CA certificate %q", cert.Subject.CommonName)
+	}
+
+	return nil
+}
+
+// IsRSAKey reports whether the public key is an RSA key
+func IsRSAKey(pub crypto.PublicKey) bool {
+	_, ok := pub.(*rsa.PublicKey)
+	return ok
+}
@@ -2,15 +2,24 @@ package main
 
 import (
 	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
 
 	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/shared/interfaces"
 )
 
 const PluginSymbolName = "DataStore"
 
+const defaultDir = "data"
+
 var _ interfaces.DataStore = &dataStore{}
 
+// dataStore keeps one file per key under <dir>/<bucket>/
 type dataStore struct {
+	dir string
+	mu  sync.RWMutex
 }
 
 func (d *dataStore) GetName() string {
@@ -21,12 +30,146 @@ func (d *dataStore) GetVersion() string {
 	return "0.0.1"
 }
 
+func (d *dataStore) Init(settings map[string]string) error {
+	d.dir = settings["dir"]
+	if d.dir == "" {
+		d.dir = defaultDir
+	}
+	return nil
+}
+
 func (d *dataStore) Start() error {
 	fmt.Println("Starting data-store")
-	return nil
+	if d.dir == "" {
+		d.dir = defaultDir
+	}
+	return os.MkdirAll(d.dir, 0o700)
 }
 
 func (d *dataStore) Stop() error {
 	fmt.Println("Stopping data-store")
 	return nil
 }
+
+func (d *dataStore) Get(bucket, key string) ([]byte, error) {
+	path, err := d.keyPath(bucket, key)
+	if err != nil {
+		return nil, err
+	}
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	value, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, interfaces.ErrNotFound
+	}
+	return value, err
+}
+
+func (d *dataStore) Put(bucket, key string, value []byte) error {
+	path, err := d.keyPath(bucket, key)
+	if err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(value); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+func (d *dataStore) Delete(bucket, key string) error {
+	path, err := d.keyPath(bucket, key)
+	if err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	err = os.Remove(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+func (d *dataStore) List(bucket string) (map[string][]byte, error) {
+	dir, err := d.bucketPath(bucket)
+	if err != nil {
+		return nil, err
+	}
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return map[string][]byte{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string][]byte, len(entries))
+	for _, entry := range entries {
+		// Dot files are the temporary files of writes in progress, no key starts with a dot
+		if entry.IsDir() || entry.Name()[0] == '.' {
+			continue
+		}
+		key, err := url.PathUnescape(entry.Name())
+		if err != nil {
+			continue
+		}
+		value, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		values[key] = value
+	}
+	return values, nil
+}
+
+// bucketPath returns the directory of a bucket. Names are path escaped, and
+// refused when escaping cannot keep them inside the store or out of dot files.
+func (d *dataStore) bucketPath(bucket string) (string, error) {
+	if err := interfaces.CheckName(bucket); err != nil {
+		return "", err
+	}
+	return filepath.Join(d.dir, url.PathEscape(bucket)), nil
+}
+
+func (d *dataStore) keyPath(bucket, key string) (string, error) {
+	dir, err := d.bucketPath(bucket)
+	if err != nil {
+		return "", err
+	}
+	if err := interfaces.CheckName(key); err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, url.PathEscape(key)), nil
+}
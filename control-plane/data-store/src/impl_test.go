@@ -0,0 +1,78 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/raphaelCamblong/Luminous-Mesh/control-plane/shared/interfaces"
+)
+
+func newTestStore(t *testing.T) *dataStore {
+	t.Helper()
+
+	d := &dataStore{}
+	if err := d.Init(map[string]string{"dir": t.TempDir()}); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+	if err := d.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	return d
+}
+
+func TestKeysRoundTrip(t *testing.T) {
+	d := newTestStore(t)
+
+	keys := []string{"node-1", "a/b", "%2e%2e", "sha256:ab", "-kid_"}
+	for _, key := range keys {
+		if err := d.Put("bucket", key, []byte(key)); err != nil {
+			t.Fatalf("Put(%q) error = %v", key, err)
+		}
+	}
+
+	values, err := d.List("bucket")
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(values) != len(keys) {
+		t.Fatalf("List() = %d keys, want %d", len(values), len(keys))
+	}
+	for _, key := range keys {
+		if string(values[key]) != key {
+			t.Fatalf("List()[%q] = %q", key, values[key])
+		}
+		value, err := d.Get("bucket", key)
+		if err != nil || string(value) != key {
+			t.Fatalf("Get(%q) = %q, %v", key, value, err)
+		}
+	}
+
+	if err := d.Delete("bucket", "a/b"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := d.Get("bucket", "a/b"); !errors.Is(err, interfaces.ErrNotFound) {
+		t.Fatalf("Get() after Delete() error = %v, want %v", err, interfaces.ErrNotFound)
+	}
+}
+
+func TestInvalidNamesRefused(t *testing.T) {
+	d := newTestStore(t)
+
+	for _, name := range []string{"", ".", "..", ".tmp-1"} {
+		if err := d.Put(name, "key", nil); !errors.Is(err, interfaces.ErrInvalidName) {
+			t.Fatalf("Put() into bucket %q error = %v, want %v", name, err, interfaces.ErrInvalidName)
+		}
+		if err := d.Put("bucket", name, nil); !errors.Is(err, interfaces.ErrInvalidName) {
+			t.Fatalf("Put() of key %q error = %v, want %v", name, err, interfaces.ErrInvalidName)
+		}
+		if _, err := d.Get("bucket", name); !errors.Is(err, interfaces.ErrInvalidName) {
+			t.Fatalf("Get() of key %q error = %v, want %v", name, err, interfaces.ErrInvalidName)
+		}
+		if err := d.Delete("bucket", name); !errors.Is(err, interfaces.ErrInvalidName) {
+			t.Fatalf("Delete() of key %q error = %v, want %v", name, err, interfaces.ErrInvalidName)
+		}
+		if _, err := d.List(name); !errors.Is(err, interfaces.ErrInvalidName) {
+			t.Fatalf("List() of bucket %q error = %v, want %v", name, err, interfaces.ErrInvalidName)
+		}
+	}
+}
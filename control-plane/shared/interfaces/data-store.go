@@ -1,7 +1,37 @@
 package interfaces
 
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrNotFound is returned by DataStore.Get when a key does not exist
+	ErrNotFound = errors.New("not found")
+	// ErrInvalidName is returned for buckets and keys a data store cannot hold
+	ErrInvalidName = errors.New("invalid bucket or key name")
+)
+
+// CheckName refuses the bucket and key names data stores reserve: empty names
+// and names starting with a dot, which covers "." and ".."
+func CheckName(name string) error {
+	if name == "" || strings.HasPrefix(name, ".") {
+		return fmt.Errorf("%w: %q", ErrInvalidName, name)
+	}
+	return nil
+}
+
+// DataStore persists control-plane state as opaque values grouped in buckets.
+// Bucket and key names must pass CheckName.
 type DataStore interface {
 	Plugin
 	Start() error
 	Stop() error
+
+	Get(bucket, key string) ([]byte, error)
+	Put(bucket, key string, value []byte) error
+	Delete(bucket, key string) error
+	// List returns every key and value of a bucket
+	List(bucket string) (map[string][]byte, error)
 }
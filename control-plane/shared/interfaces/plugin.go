@@ -3,4 +3,6 @@ package interfaces
 type Plugin interface {
 	GetName() string
 	GetVersion() string
+	// Init configures the plugin from its [plugins.settings.<name>] table
+	Init(settings map[string]string) error
 }
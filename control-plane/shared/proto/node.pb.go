@@ -21,6 +21,281 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+// Reason of the google.rpc.ErrorInfo detail carried by every error the control
+// plane returns, in the "luminousmesh.io" domain. Errors also carry
+// google.rpc.RetryInfo when the call may be retried later, and
+// google.rpc.BadRequest when request fields are invalid.
+type ErrorReason int32
+
+const (
+	ErrorReason_ERROR_REASON_UNSPECIFIED ErrorReason = 0
+	// Internal failure, the details are only logged by the control plane
+	ErrorReason_INTERNAL ErrorReason = 1
+	// Credentials: re-register on *_INVALID, *_UNBOUND and TOKEN_REUSED
+	ErrorReason_CREDENTIALS_MISSING             ErrorReason = 2
+	ErrorReason_BOOTSTRAP_TOKEN_INVALID         ErrorReason = 3
+	ErrorReason_AUTH_TOKEN_INVALID              ErrorReason = 4
+	ErrorReason_AUTH_TOKEN_UNBOUND              ErrorReason = 5
+	ErrorReason_AUTH_TOKEN_CERTIFICATE_MISMATCH ErrorReason = 6
+	ErrorReason_TOKEN_REUSED                    ErrorReason = 7
+	ErrorReason_CERTIFICATE_REQUIRED            ErrorReason = 8
+	ErrorReason_CERTIFICATE_INVALID             ErrorReason = 9
+	ErrorReason_CERTIFICATE_REVOKED             ErrorReason = 10
+	// The certificate in the request is not the TLS client certificate
+	ErrorReason_CERTIFICATE_MISMATCH ErrorReason = 11
+	ErrorReason_CSR_INVALID          ErrorReason = 12
+	// The node was decommissioned and must not reconnect; ErrorInfo metadata
+	// holds decommissioned_at and reason
+	ErrorReason_NODE_DECOMMISSIONED ErrorReason = 13
+	ErrorReason_NODE_MISMATCH       ErrorReason = 14
+	// Sessions: authenticate again
+	ErrorReason_SESSION_REQUIRED ErrorReason = 15
+	ErrorReason_SESSION_INVALID  ErrorReason = 16
+	ErrorReason_SESSION_EXPIRED  ErrorReason = 17
+	ErrorReason_SESSION_REVOKED  ErrorReason = 18
+	ErrorReason_SESSION_MISMATCH ErrorReason = 19
+	// Registrations
+	ErrorReason_REGISTRATION_NOT_FOUND ErrorReason = 20
+	ErrorReason_REGISTRATION_INVALID   ErrorReason = 21
+	// Throttling: retry after the RetryInfo delay
+	ErrorReason_RATE_LIMITED ErrorReason = 22
+	ErrorReason_LOCKED_OUT   ErrorReason = 23
+	ErrorReason_OVERLOADED   ErrorReason = 24
+	ErrorReason_DRAINING     ErrorReason = 25
+	// Callers lacking the permission or policy for the method
+	ErrorReason_PERMISSION_DENIED           ErrorReason = 26
+	ErrorReason_OPERATOR_CREDENTIAL_INVALID ErrorReason = 27
+	// Request fields break their validation rules, listed in BadRequest
+	ErrorReason_INVALID_REQUEST ErrorReason = 28
+	// The node ID is unknown to the control plane
+	ErrorReason_NODE_NOT_FOUND    ErrorReason = 29
+	ErrorReason_SESSION_NOT_FOUND ErrorReason = 30
+	// The registration was already decided, or its decision is being issued
+	ErrorReason_REGISTRATION_NOT_PENDING ErrorReason = 31
+	ErrorReason_REGISTRATION_DECIDING    ErrorReason = 32
+	// Operator accounts and API keys
+	ErrorReason_USER_EXISTS       ErrorReason = 33
+	ErrorReason_USER_NOT_FOUND    ErrorReason = 34
+	ErrorReason_API_KEY_NOT_FOUND ErrorReason = 35
+	// Too many commands wait for the node to acknowledge them
+	ErrorReason_OUTBOX_FULL ErrorReason = 36
+)
+
+// Enum value maps for ErrorReason.
+var (
+	ErrorReason_name = map[int32]string{
+		0:  "ERROR_REASON_UNSPECIFIED",
+		1:  "INTERNAL",
+		2:  "CREDENTIALS_MISSING",
+		3:  "BOOTSTRAP_TOKEN_INVALID",
+		4:  "AUTH_TOKEN_INVALID",
+		5:  "AUTH_TOKEN_UNBOUND",
+		6:  "AUTH_TOKEN_CERTIFICATE_MISMATCH",
+		7:  "TOKEN_REUSED",
+		8:  "CERTIFICATE_REQUIRED",
+		9:  "CERTIFICATE_INVALID",
+		10: "CERTIFICATE_REVOKED",
+		11: "CERTIFICATE_MISMATCH",
+		12: "CSR_INVALID",
+		13: "NODE_DECOMMISSIONED",
+		14: "NODE_MISMATCH",
+		15: "SESSION_REQUIRED",
+		16: "SESSION_INVALID",
+		17: "SESSION_EXPIRED",
+		18: "SESSION_REVOKED",
+		19: "SESSION_MISMATCH",
+		20: "REGISTRATION_NOT_FOUND",
+		21: "REGISTRATION_INVALID",
+		22: "RATE_LIMITED",
+		23: "LOCKED_OUT",
+		24: "OVERLOADED",
+		25: "DRAINING",
+		26: "PERMISSION_DENIED",
+		27: "OPERATOR_CREDENTIAL_INVALID",
+		28: "INVALID_REQUEST",
+		29: "NODE_NOT_FOUND",
+		30: "SESSION_NOT_FOUND",
+		31: "REGISTRATION_NOT_PENDING",
+		32: "REGISTRATION_DECIDING",
+		33: "USER_EXISTS",
+		34: "USER_NOT_FOUND",
+		35: "API_KEY_NOT_FOUND",
+		36: "OUTBOX_FULL",
+	}
+	ErrorReason_value = map[string]int32{
+		"ERROR_REASON_UNSPECIFIED":        0,
+		"INTERNAL":                        1,
+		"CREDENTIALS_MISSING":             2,
+		"BOOTSTRAP_TOKEN_INVALID":         3,
+		"AUTH_TOKEN_INVALID":              4,
+		"AUTH_TOKEN_UNBOUND":              5,
+		"AUTH_TOKEN_CERTIFICATE_MISMATCH": 6,
+		"TOKEN_REUSED":                    7,
+		"CERTIFICATE_REQUIRED":            8,
+		"CERTIFICATE_INVALID":             9,
+		"CERTIFICATE_REVOKED":             10,
+		"CERTIFICATE_MISMATCH":            11,
+		"CSR_INVALID":                     12,
+		"NODE_DECOMMISSIONED":             13,
+		"NODE_MISMATCH":                   14,
+		"SESSION_REQUIRED":                15,
+		"SESSION_INVALID":                 16,
+		"SESSION_EXPIRED":                 17,
+		"SESSION_REVOKED":                 18,
+		"SESSION_MISMATCH":                19,
+		"REGISTRATION_NOT_FOUND":          20,
+		"REGISTRATION_INVALID":            21,
+		"RATE_LIMITED":                    22,
+		"LOCKED_OUT":                      23,
+		"OVERLOADED":                      24,
+		"DRAINING":                        25,
+		"PERMISSION_DENIED":               26,
+		"OPERATOR_CREDENTIAL_INVALID":     27,
+		"INVALID_REQUEST":                 28,
+		"NODE_NOT_FOUND":                  29,
+		"SESSION_NOT_FOUND":               30,
+		"REGISTRATION_NOT_PENDING":        31,
+		"REGISTRATION_DECIDING":           32,
+		"USER_EXISTS":                     33,
+		"USER_NOT_FOUND":                  34,
+		"API_KEY_NOT_FOUND":               35,
+		"OUTBOX_FULL":                     36,
+	}
+)
+
+func (x ErrorReason) Enum() *ErrorReason {
+	p := new(ErrorReason)
+	*p = x
+	return p
+}
+
+func (x ErrorReason) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ErrorReason) Descriptor() protoreflect.EnumDescriptor {
+	return file_node_proto_enumTypes[0].Descriptor()
+}
+
+func (ErrorReason) Type() protoreflect.EnumType {
+	return &file_node_proto_enumTypes[0]
+}
+
+func (x ErrorReason) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use ErrorReason.Descriptor instead.
+func (ErrorReason) EnumDescriptor() ([]byte, []int) {
+	return file_node_proto_rawDescGZIP(), []int{0}
+}
+
+type RegisterNodeResponse_RegistrationState int32
+
+const (
+	RegisterNodeResponse_UNSPECIFIED      RegisterNodeResponse_RegistrationState = 0
+	RegisterNodeResponse_ADMITTED         RegisterNodeResponse_RegistrationState = 1
+	RegisterNodeResponse_PENDING_APPROVAL RegisterNodeResponse_RegistrationState = 2
+	RegisterNodeResponse_REJECTED         RegisterNodeResponse_RegistrationState = 3
+	RegisterNodeResponse_EXPIRED          RegisterNodeResponse_RegistrationState = 4
+)
+
+// Enum value maps for RegisterNodeResponse_RegistrationState.
+var (
+	RegisterNodeResponse_RegistrationState_name = map[int32]string{
+		0: "UNSPECIFIED",
+		1: "ADMITTED",
+		2: "PENDING_APPROVAL",
+		3: "REJECTED",
+		4: "EXPIRED",
+	}
+	RegisterNodeResponse_RegistrationState_value = map[string]int32{
+		"UNSPECIFIED":      0,
+		"ADMITTED":         1,
+		"PENDING_APPROVAL": 2,
+		"REJECTED":         3,
+		"EXPIRED":          4,
+	}
+)
+
+func (x RegisterNodeResponse_RegistrationState) Enum() *RegisterNodeResponse_RegistrationState {
+	p := new(RegisterNodeResponse_RegistrationState)
+	*p = x
+	return p
+}
+
+func (x RegisterNodeResponse_RegistrationState) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (RegisterNodeResponse_RegistrationState) Descriptor() protoreflect.EnumDescriptor {
+	return file_node_proto_enumTypes[1].Descriptor()
+}
+
+func (RegisterNodeResponse_RegistrationState) Type() protoreflect.EnumType {
+	return &file_node_proto_enumTypes[1]
+}
+
+func (x RegisterNodeResponse_RegistrationState) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use RegisterNodeResponse_RegistrationState.Descriptor instead.
+func (RegisterNodeResponse_RegistrationState) EnumDescriptor() ([]byte, []int) {
+	return file_node_proto_rawDescGZIP(), []int{1, 0}
+}
+
+// Lane the command is sent in. Lanes are served by weighted round robin,
+// so bulk commands still progress while critical ones are queued.
+type ControlPlaneCommand_Priority int32
+
+const (
+	ControlPlaneCommand_NORMAL   ControlPlaneCommand_Priority = 0
+	ControlPlaneCommand_CRITICAL ControlPlaneCommand_Priority = 1
+	ControlPlaneCommand_BULK     ControlPlaneCommand_Priority = 2
+)
+
+// Enum value maps for ControlPlaneCommand_Priority.
+var (
+	ControlPlaneCommand_Priority_name = map[int32]string{
+		0: "NORMAL",
+		1: "CRITICAL",
+		2: "BULK",
+	}
+	ControlPlaneCommand_Priority_value = map[string]int32{
+		"NORMAL":   0,
+		"CRITICAL": 1,
+		"BULK":     2,
+	}
+)
+
+func (x ControlPlaneCommand_Priority) Enum() *ControlPlaneCommand_Priority {
+	p := new(ControlPlaneCommand_Priority)
+	*p = x
+	return p
+}
+
+func (x ControlPlaneCommand_Priority) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ControlPlaneCommand_Priority) Descriptor() protoreflect.EnumDescriptor {
+	return file_node_proto_enumTypes[2].Descriptor()
+}
+
+func (ControlPlaneCommand_Priority) Type() protoreflect.EnumType {
+	return &file_node_proto_enumTypes[2]
+}
+
+func (x ControlPlaneCommand_Priority) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use ControlPlaneCommand_Priority.Descriptor instead.
+func (ControlPlaneCommand_Priority) EnumDescriptor() ([]byte, []int) {
+	return file_node_proto_rawDescGZIP(), []int{6, 0}
+}
+
 type NodeStatus_State int32
 
 const (
@@ -60,11 +335,11 @@ func (x NodeStatus_State) String() string {
 }
 
 func (NodeStatus_State) Descriptor() protoreflect.EnumDescriptor {
-	return file_node_proto_enumTypes[0].Descriptor()
+	return file_node_proto_enumTypes[3].Descriptor()
 }
 
 func (NodeStatus_State) Type() protoreflect.EnumType {
-	return &file_node_proto_enumTypes[0]
+	return &file_node_proto_enumTypes[3]
 }
 
 func (x NodeStatus_State) Number() protoreflect.EnumNumber {
@@ -73,7 +348,7 @@ func (x NodeStatus_State) Number() protoreflect.EnumNumber {
 
 // Deprecated: Use NodeStatus_State.Descriptor instead.
 func (NodeStatus_State) EnumDescriptor() ([]byte, []int) {
-	return file_node_proto_rawDescGZIP(), []int{7, 0}
+	return file_node_proto_rawDescGZIP(), []int{8, 0}
 }
 
 type RegisterNodeRequest struct {
@@ -136,16 +411,22 @@ func (x *RegisterNodeRequest) GetCsr() []byte {
 	return nil
 }
 
+// Failed registrations are returned as errors with an ErrorReason; success
+// is false only for registrations pending, rejected or expired
 type RegisterNodeResponse struct {
-	state             protoimpl.MessageState `protogen:"open.v1"`
-	Success           bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
-	Message           string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
-	SignedCertificate []byte                 `protobuf:"bytes,3,opt,name=signed_certificate,json=signedCertificate,proto3" json:"signed_certificate,omitempty"` // Signed certificate from control plane
-	NodeId            string                 `protobuf:"bytes,4,opt,name=node_id,json=nodeId,proto3" json:"node_id,omitempty"`
-	InitialAuthToken  string                 `protobuf:"bytes,5,opt,name=initial_auth_token,json=initialAuthToken,proto3" json:"initial_auth_token,omitempty"`
-	ControlPlaneInfo  *ControlPlaneInfo      `protobuf:"bytes,6,opt,name=control_plane_info,json=controlPlaneInfo,proto3" json:"control_plane_info,omitempty"`
-	unknownFields     protoimpl.UnknownFields
-	sizeCache         protoimpl.SizeCache
+	state             protoimpl.MessageState                 `protogen:"open.v1"`
+	Success           bool                                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message           string                                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	SignedCertificate []byte                                 `protobuf:"bytes,3,opt,name=signed_certificate,json=signedCertificate,proto3" json:"signed_certificate,omitempty"` // Signed certificate from control plane
+	NodeId            string                                 `protobuf:"bytes,4,opt,name=node_id,json=nodeId,proto3" json:"node_id,omitempty"`
+	InitialAuthToken  string                                 `protobuf:"bytes,5,opt,name=initial_auth_token,json=initialAuthToken,proto3" json:"initial_auth_token,omitempty"`
+	ControlPlaneInfo  *ControlPlaneInfo                      `protobuf:"bytes,6,opt,name=control_plane_info,json=controlPlaneInfo,proto3" json:"control_plane_info,omitempty"`
+	RegistrationState RegisterNodeResponse_RegistrationState `protobuf:"varint,7,opt,name=registration_state,json=registrationState,proto3,enum=luminousmesh.RegisterNodeResponse_RegistrationState" json:"registration_state,omitempty"`
+	// Secret to poll GetRegistrationStatus with while pending approval
+	RegistrationId      string `protobuf:"bytes,8,opt,name=registration_id,json=registrationId,proto3" json:"registration_id,omitempty"`
+	PollIntervalSeconds int32  `protobuf:"varint,9,opt,name=poll_interval_seconds,json=pollIntervalSeconds,proto3" json:"poll_interval_seconds,omitempty"`
+	unknownFields       protoimpl.UnknownFields
+	sizeCache           protoimpl.SizeCache
 }
 
 func (x *RegisterNodeResponse) Reset() {
@@ -220,6 +501,80 @@ func (x *RegisterNodeResponse) GetControlPlaneInfo() *ControlPlaneInfo {
 	return nil
 }
 
+func (x *RegisterNodeResponse) GetRegistrationState() RegisterNodeResponse_RegistrationState {
+	if x != nil {
+		return x.RegistrationState
+	}
+	return RegisterNodeResponse_UNSPECIFIED
+}
+
+func (x *RegisterNodeResponse) GetRegistrationId() string {
+	if x != nil {
+		return x.RegistrationId
+	}
+	return ""
+}
+
+func (x *RegisterNodeResponse) GetPollIntervalSeconds() int32 {
+	if x != nil {
+		return x.PollIntervalSeconds
+	}
+	return 0
+}
+
+type RegistrationStatusRequest struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	RegistrationId string                 `protobuf:"bytes,1,opt,name=registration_id,json=registrationId,proto3" json:"registration_id,omitempty"`
+	// Wait up to this long for a decision before answering, capped server side
+	WaitSeconds   int32 `protobuf:"varint,2,opt,name=wait_seconds,json=waitSeconds,proto3" json:"wait_seconds,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RegistrationStatusRequest) Reset() {
+	*x = RegistrationStatusRequest{}
+	mi := &file_node_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RegistrationStatusRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RegistrationStatusRequest) ProtoMessage() {}
+
+func (x *RegistrationStatusRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_node_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RegistrationStatusRequest.ProtoReflect.Descriptor instead.
+func (*RegistrationStatusRequest) Descriptor() ([]byte, []int) {
+	return file_node_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *RegistrationStatusRequest) GetRegistrationId() string {
+	if x != nil {
+		return x.RegistrationId
+	}
+	return ""
+}
+
+func (x *RegistrationStatusRequest) GetWaitSeconds() int32 {
+	if x != nil {
+		return x.WaitSeconds
+	}
+	return 0
+}
+
 type AuthenticationRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	NodeId        string                 `protobuf:"bytes,1,opt,name=node_id,json=nodeId,proto3" json:"node_id,omitempty"`
@@ -233,7 +588,7 @@ type AuthenticationRequest struct {
 
 func (x *AuthenticationRequest) Reset() {
 	*x = AuthenticationRequest{}
-	mi := &file_node_proto_msgTypes[2]
+	mi := &file_node_proto_msgTypes[3]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -245,7 +600,7 @@ func (x *AuthenticationRequest) String() string {
 func (*AuthenticationRequest) ProtoMessage() {}
 
 func (x *AuthenticationRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_node_proto_msgTypes[2]
+	mi := &file_node_proto_msgTypes[3]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -258,7 +613,7 @@ func (x *AuthenticationRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use AuthenticationRequest.ProtoReflect.Descriptor instead.
 func (*AuthenticationRequest) Descriptor() ([]byte, []int) {
-	return file_node_proto_rawDescGZIP(), []int{2}
+	return file_node_proto_rawDescGZIP(), []int{3}
 }
 
 func (x *AuthenticationRequest) GetNodeId() string {
@@ -296,6 +651,8 @@ func (x *AuthenticationRequest) GetCapabilities() *NodeCapabilities {
 	return nil
 }
 
+// Failed authentications are returned as errors with an ErrorReason, success
+// is kept for older nodes and always set
 type AuthenticationResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
@@ -303,13 +660,15 @@ type AuthenticationResponse struct {
 	SessionId     string                 `protobuf:"bytes,3,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
 	TokenExpiry   int64                  `protobuf:"varint,4,opt,name=token_expiry,json=tokenExpiry,proto3" json:"token_expiry,omitempty"`
 	InitialConfig *NodeConfiguration     `protobuf:"bytes,5,opt,name=initial_config,json=initialConfig,proto3" json:"initial_config,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	// Current connection settings, which may have changed since registration
+	ConnectionSettings *ConnectionSettings `protobuf:"bytes,6,opt,name=connection_settings,json=connectionSettings,proto3" json:"connection_settings,omitempty"`
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
 }
 
 func (x *AuthenticationResponse) Reset() {
 	*x = AuthenticationResponse{}
-	mi := &file_node_proto_msgTypes[3]
+	mi := &file_node_proto_msgTypes[4]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -321,7 +680,7 @@ func (x *AuthenticationResponse) String() string {
 func (*AuthenticationResponse) ProtoMessage() {}
 
 func (x *AuthenticationResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_node_proto_msgTypes[3]
+	mi := &file_node_proto_msgTypes[4]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -334,7 +693,7 @@ func (x *AuthenticationResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use AuthenticationResponse.ProtoReflect.Descriptor instead.
 func (*AuthenticationResponse) Descriptor() ([]byte, []int) {
-	return file_node_proto_rawDescGZIP(), []int{3}
+	return file_node_proto_rawDescGZIP(), []int{4}
 }
 
 func (x *AuthenticationResponse) GetSuccess() bool {
@@ -372,20 +731,31 @@ func (x *AuthenticationResponse) GetInitialConfig() *NodeConfiguration {
 	return nil
 }
 
+func (x *AuthenticationResponse) GetConnectionSettings() *ConnectionSettings {
+	if x != nil {
+		return x.ConnectionSettings
+	}
+	return nil
+}
+
 type NodeStatusUpdate struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	NodeId        string                 `protobuf:"bytes,1,opt,name=node_id,json=nodeId,proto3" json:"node_id,omitempty"`
-	SessionId     string                 `protobuf:"bytes,2,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
-	Status        *NodeStatus            `protobuf:"bytes,3,opt,name=status,proto3" json:"status,omitempty"`
-	Metrics       []*MetricsReport       `protobuf:"bytes,4,rep,name=metrics,proto3" json:"metrics,omitempty"`
-	Timestamp     int64                  `protobuf:"varint,5,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	NodeId    string                 `protobuf:"bytes,1,opt,name=node_id,json=nodeId,proto3" json:"node_id,omitempty"`
+	SessionId string                 `protobuf:"bytes,2,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	Status    *NodeStatus            `protobuf:"bytes,3,opt,name=status,proto3" json:"status,omitempty"`
+	Metrics   []*MetricsReport       `protobuf:"bytes,4,rep,name=metrics,proto3" json:"metrics,omitempty"`
+	Timestamp int64                  `protobuf:"varint,5,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	// Commands the node received since its previous update. Commands are
+	// redelivered on reconnect until acknowledged, so nodes must ignore a
+	// command_id they already applied.
+	AckedCommandIds []string `protobuf:"bytes,6,rep,name=acked_command_ids,json=ackedCommandIds,proto3" json:"acked_command_ids,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
 }
 
 func (x *NodeStatusUpdate) Reset() {
 	*x = NodeStatusUpdate{}
-	mi := &file_node_proto_msgTypes[4]
+	mi := &file_node_proto_msgTypes[5]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -397,7 +767,7 @@ func (x *NodeStatusUpdate) String() string {
 func (*NodeStatusUpdate) ProtoMessage() {}
 
 func (x *NodeStatusUpdate) ProtoReflect() protoreflect.Message {
-	mi := &file_node_proto_msgTypes[4]
+	mi := &file_node_proto_msgTypes[5]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -410,7 +780,7 @@ func (x *NodeStatusUpdate) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use NodeStatusUpdate.ProtoReflect.Descriptor instead.
 func (*NodeStatusUpdate) Descriptor() ([]byte, []int) {
-	return file_node_proto_rawDescGZIP(), []int{4}
+	return file_node_proto_rawDescGZIP(), []int{5}
 }
 
 func (x *NodeStatusUpdate) GetNodeId() string {
@@ -448,6 +818,13 @@ func (x *NodeStatusUpdate) GetTimestamp() int64 {
 	return 0
 }
 
+func (x *NodeStatusUpdate) GetAckedCommandIds() []string {
+	if x != nil {
+		return x.AckedCommandIds
+	}
+	return nil
+}
+
 type ControlPlaneCommand struct {
 	state     protoimpl.MessageState `protogen:"open.v1"`
 	CommandId string                 `protobuf:"bytes,1,opt,name=command_id,json=commandId,proto3" json:"command_id,omitempty"`
@@ -456,14 +833,19 @@ type ControlPlaneCommand struct {
 	//	*ControlPlaneCommand_ConfigUpdate
 	//	*ControlPlaneCommand_HealthCheck
 	//	*ControlPlaneCommand_Disconnect
-	Command       isControlPlaneCommand_Command `protobuf_oneof:"command"`
+	//	*ControlPlaneCommand_TrustBundleUpdate
+	Command  isControlPlaneCommand_Command `protobuf_oneof:"command"`
+	Priority ControlPlaneCommand_Priority  `protobuf:"varint,6,opt,name=priority,proto3,enum=luminousmesh.ControlPlaneCommand_Priority" json:"priority,omitempty"`
+	// Unix time in milliseconds after which the command is dropped instead of
+	// sent, and must not be applied by the node; no deadline when zero
+	Deadline      int64 `protobuf:"varint,7,opt,name=deadline,proto3" json:"deadline,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *ControlPlaneCommand) Reset() {
 	*x = ControlPlaneCommand{}
-	mi := &file_node_proto_msgTypes[5]
+	mi := &file_node_proto_msgTypes[6]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -475,7 +857,7 @@ func (x *ControlPlaneCommand) String() string {
 func (*ControlPlaneCommand) ProtoMessage() {}
 
 func (x *ControlPlaneCommand) ProtoReflect() protoreflect.Message {
-	mi := &file_node_proto_msgTypes[5]
+	mi := &file_node_proto_msgTypes[6]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -488,7 +870,7 @@ func (x *ControlPlaneCommand) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ControlPlaneCommand.ProtoReflect.Descriptor instead.
 func (*ControlPlaneCommand) Descriptor() ([]byte, []int) {
-	return file_node_proto_rawDescGZIP(), []int{5}
+	return file_node_proto_rawDescGZIP(), []int{6}
 }
 
 func (x *ControlPlaneCommand) GetCommandId() string {
@@ -532,6 +914,29 @@ func (x *ControlPlaneCommand) GetDisconnect() *Disconnect {
 	return nil
 }
 
+func (x *ControlPlaneCommand) GetTrustBundleUpdate() *TrustBundleUpdate {
+	if x != nil {
+		if x, ok := x.Command.(*ControlPlaneCommand_TrustBundleUpdate); ok {
+			return x.TrustBundleUpdate
+		}
+	}
+	return nil
+}
+
+func (x *ControlPlaneCommand) GetPriority() ControlPlaneCommand_Priority {
+	if x != nil {
+		return x.Priority
+	}
+	return ControlPlaneCommand_NORMAL
+}
+
+func (x *ControlPlaneCommand) GetDeadline() int64 {
+	if x != nil {
+		return x.Deadline
+	}
+	return 0
+}
+
 type isControlPlaneCommand_Command interface {
 	isControlPlaneCommand_Command()
 }
@@ -548,12 +953,18 @@ type ControlPlaneCommand_Disconnect struct {
 	Disconnect *Disconnect `protobuf:"bytes,4,opt,name=disconnect,proto3,oneof"`
 }
 
+type ControlPlaneCommand_TrustBundleUpdate struct {
+	TrustBundleUpdate *TrustBundleUpdate `protobuf:"bytes,5,opt,name=trust_bundle_update,json=trustBundleUpdate,proto3,oneof"`
+}
+
 func (*ControlPlaneCommand_ConfigUpdate) isControlPlaneCommand_Command() {}
 
 func (*ControlPlaneCommand_HealthCheck) isControlPlaneCommand_Command() {}
 
 func (*ControlPlaneCommand_Disconnect) isControlPlaneCommand_Command() {}
 
+func (*ControlPlaneCommand_TrustBundleUpdate) isControlPlaneCommand_Command() {}
+
 type NodeBasicInfo struct {
 	state               protoimpl.MessageState `protogen:"open.v1"`
 	Hostname            string                 `protobuf:"bytes,1,opt,name=hostname,proto3" json:"hostname,omitempty"`
@@ -562,13 +973,15 @@ type NodeBasicInfo struct {
 	SupportedModelTypes []string               `protobuf:"bytes,4,rep,name=supported_model_types,json=supportedModelTypes,proto3" json:"supported_model_types,omitempty"`
 	Architecture        string                 `protobuf:"bytes,5,opt,name=architecture,proto3" json:"architecture,omitempty"`
 	Labels              map[string]string      `protobuf:"bytes,6,rep,name=labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
-	unknownFields       protoimpl.UnknownFields
-	sizeCache           protoimpl.SizeCache
+	// Stable host identifier (e.g. /etc/machine-id) used to recognize re-registrations
+	MachineId     string `protobuf:"bytes,7,opt,name=machine_id,json=machineId,proto3" json:"machine_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
 func (x *NodeBasicInfo) Reset() {
 	*x = NodeBasicInfo{}
-	mi := &file_node_proto_msgTypes[6]
+	mi := &file_node_proto_msgTypes[7]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -580,7 +993,7 @@ func (x *NodeBasicInfo) String() string {
 func (*NodeBasicInfo) ProtoMessage() {}
 
 func (x *NodeBasicInfo) ProtoReflect() protoreflect.Message {
-	mi := &file_node_proto_msgTypes[6]
+	mi := &file_node_proto_msgTypes[7]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -593,7 +1006,7 @@ func (x *NodeBasicInfo) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use NodeBasicInfo.ProtoReflect.Descriptor instead.
 func (*NodeBasicInfo) Descriptor() ([]byte, []int) {
-	return file_node_proto_rawDescGZIP(), []int{6}
+	return file_node_proto_rawDescGZIP(), []int{7}
 }
 
 func (x *NodeBasicInfo) GetHostname() string {
@@ -638,6 +1051,13 @@ func (x *NodeBasicInfo) GetLabels() map[string]string {
 	return nil
 }
 
+func (x *NodeBasicInfo) GetMachineId() string {
+	if x != nil {
+		return x.MachineId
+	}
+	return ""
+}
+
 type NodeStatus struct {
 	state         protoimpl.MessageState     `protogen:"open.v1"`
 	State         NodeStatus_State           `protobuf:"varint,1,opt,name=state,proto3,enum=luminousmesh.NodeStatus_State" json:"state,omitempty"`
@@ -649,7 +1069,7 @@ type NodeStatus struct {
 
 func (x *NodeStatus) Reset() {
 	*x = NodeStatus{}
-	mi := &file_node_proto_msgTypes[7]
+	mi := &file_node_proto_msgTypes[8]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -661,7 +1081,7 @@ func (x *NodeStatus) String() string {
 func (*NodeStatus) ProtoMessage() {}
 
 func (x *NodeStatus) ProtoReflect() protoreflect.Message {
-	mi := &file_node_proto_msgTypes[7]
+	mi := &file_node_proto_msgTypes[8]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -674,7 +1094,7 @@ func (x *NodeStatus) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use NodeStatus.ProtoReflect.Descriptor instead.
 func (*NodeStatus) Descriptor() ([]byte, []int) {
-	return file_node_proto_rawDescGZIP(), []int{7}
+	return file_node_proto_rawDescGZIP(), []int{8}
 }
 
 func (x *NodeStatus) GetState() NodeStatus_State {
@@ -709,7 +1129,7 @@ type ResourceStatus struct {
 
 func (x *ResourceStatus) Reset() {
 	*x = ResourceStatus{}
-	mi := &file_node_proto_msgTypes[8]
+	mi := &file_node_proto_msgTypes[9]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -721,7 +1141,7 @@ func (x *ResourceStatus) String() string {
 func (*ResourceStatus) ProtoMessage() {}
 
 func (x *ResourceStatus) ProtoReflect() protoreflect.Message {
-	mi := &file_node_proto_msgTypes[8]
+	mi := &file_node_proto_msgTypes[9]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -734,7 +1154,7 @@ func (x *ResourceStatus) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ResourceStatus.ProtoReflect.Descriptor instead.
 func (*ResourceStatus) Descriptor() ([]byte, []int) {
-	return file_node_proto_rawDescGZIP(), []int{8}
+	return file_node_proto_rawDescGZIP(), []int{9}
 }
 
 func (x *ResourceStatus) GetName() string {
@@ -769,7 +1189,7 @@ type MetricsReport struct {
 
 func (x *MetricsReport) Reset() {
 	*x = MetricsReport{}
-	mi := &file_node_proto_msgTypes[9]
+	mi := &file_node_proto_msgTypes[10]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -781,7 +1201,7 @@ func (x *MetricsReport) String() string {
 func (*MetricsReport) ProtoMessage() {}
 
 func (x *MetricsReport) ProtoReflect() protoreflect.Message {
-	mi := &file_node_proto_msgTypes[9]
+	mi := &file_node_proto_msgTypes[10]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -794,7 +1214,7 @@ func (x *MetricsReport) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use MetricsReport.ProtoReflect.Descriptor instead.
 func (*MetricsReport) Descriptor() ([]byte, []int) {
-	return file_node_proto_rawDescGZIP(), []int{9}
+	return file_node_proto_rawDescGZIP(), []int{10}
 }
 
 func (x *MetricsReport) GetMetricName() string {
@@ -829,7 +1249,7 @@ type TokenRotationRequest struct {
 
 func (x *TokenRotationRequest) Reset() {
 	*x = TokenRotationRequest{}
-	mi := &file_node_proto_msgTypes[10]
+	mi := &file_node_proto_msgTypes[11]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -841,7 +1261,7 @@ func (x *TokenRotationRequest) String() string {
 func (*TokenRotationRequest) ProtoMessage() {}
 
 func (x *TokenRotationRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_node_proto_msgTypes[10]
+	mi := &file_node_proto_msgTypes[11]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -854,7 +1274,7 @@ func (x *TokenRotationRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use TokenRotationRequest.ProtoReflect.Descriptor instead.
 func (*TokenRotationRequest) Descriptor() ([]byte, []int) {
-	return file_node_proto_rawDescGZIP(), []int{10}
+	return file_node_proto_rawDescGZIP(), []int{11}
 }
 
 func (x *TokenRotationRequest) GetNodeId() string {
@@ -888,7 +1308,7 @@ type TokenRotationResponse struct {
 
 func (x *TokenRotationResponse) Reset() {
 	*x = TokenRotationResponse{}
-	mi := &file_node_proto_msgTypes[11]
+	mi := &file_node_proto_msgTypes[12]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -900,7 +1320,7 @@ func (x *TokenRotationResponse) String() string {
 func (*TokenRotationResponse) ProtoMessage() {}
 
 func (x *TokenRotationResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_node_proto_msgTypes[11]
+	mi := &file_node_proto_msgTypes[12]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -913,7 +1333,7 @@ func (x *TokenRotationResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use TokenRotationResponse.ProtoReflect.Descriptor instead.
 func (*TokenRotationResponse) Descriptor() ([]byte, []int) {
-	return file_node_proto_rawDescGZIP(), []int{11}
+	return file_node_proto_rawDescGZIP(), []int{12}
 }
 
 func (x *TokenRotationResponse) GetNewToken() string {
@@ -930,18 +1350,169 @@ func (x *TokenRotationResponse) GetExpiry() int64 {
 	return 0
 }
 
-type ControlPlaneInfo struct {
-	state            protoimpl.MessageState `protogen:"open.v1"`
-	ApiEndpoint      string                 `protobuf:"bytes,1,opt,name=api_endpoint,json=apiEndpoint,proto3" json:"api_endpoint,omitempty"`
-	CaCertificate    []byte                 `protobuf:"bytes,2,opt,name=ca_certificate,json=caCertificate,proto3" json:"ca_certificate,omitempty"`
-	ConnectionParams map[string]string      `protobuf:"bytes,3,rep,name=connection_params,json=connectionParams,proto3" json:"connection_params,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
-	unknownFields    protoimpl.UnknownFields
-	sizeCache        protoimpl.SizeCache
+type CertificateRenewalRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	NodeId        string                 `protobuf:"bytes,1,opt,name=node_id,json=nodeId,proto3" json:"node_id,omitempty"`
+	AuthToken     string                 `protobuf:"bytes,2,opt,name=auth_token,json=authToken,proto3" json:"auth_token,omitempty"`
+	Csr           []byte                 `protobuf:"bytes,3,opt,name=csr,proto3" json:"csr,omitempty"` // Certificate Signing Request for the new key
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
-func (x *ControlPlaneInfo) Reset() {
-	*x = ControlPlaneInfo{}
-	mi := &file_node_proto_msgTypes[12]
+func (x *CertificateRenewalRequest) Reset() {
+	*x = CertificateRenewalRequest{}
+	mi := &file_node_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CertificateRenewalRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CertificateRenewalRequest) ProtoMessage() {}
+
+func (x *CertificateRenewalRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_node_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CertificateRenewalRequest.ProtoReflect.Descriptor instead.
+func (*CertificateRenewalRequest) Descriptor() ([]byte, []int) {
+	return file_node_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *CertificateRenewalRequest) GetNodeId() string {
+	if x != nil {
+		return x.NodeId
+	}
+	return ""
+}
+
+func (x *CertificateRenewalRequest) GetAuthToken() string {
+	if x != nil {
+		return x.AuthToken
+	}
+	return ""
+}
+
+func (x *CertificateRenewalRequest) GetCsr() []byte {
+	if x != nil {
+		return x.Csr
+	}
+	return nil
+}
+
+// Failed renewals are returned as errors with an ErrorReason, success is kept
+// for older nodes and always set
+type CertificateRenewalResponse struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	Success           bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message           string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	SignedCertificate []byte                 `protobuf:"bytes,3,opt,name=signed_certificate,json=signedCertificate,proto3" json:"signed_certificate,omitempty"`
+	CaBundle          []byte                 `protobuf:"bytes,4,opt,name=ca_bundle,json=caBundle,proto3" json:"ca_bundle,omitempty"`
+	// Auth tokens are bound to the client certificate, so renewal rebinds the token
+	AuthToken     string `protobuf:"bytes,5,opt,name=auth_token,json=authToken,proto3" json:"auth_token,omitempty"`
+	TokenExpiry   int64  `protobuf:"varint,6,opt,name=token_expiry,json=tokenExpiry,proto3" json:"token_expiry,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CertificateRenewalResponse) Reset() {
+	*x = CertificateRenewalResponse{}
+	mi := &file_node_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CertificateRenewalResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CertificateRenewalResponse) ProtoMessage() {}
+
+func (x *CertificateRenewalResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_node_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CertificateRenewalResponse.ProtoReflect.Descriptor instead.
+func (*CertificateRenewalResponse) Descriptor() ([]byte, []int) {
+	return file_node_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *CertificateRenewalResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *CertificateRenewalResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *CertificateRenewalResponse) GetSignedCertificate() []byte {
+	if x != nil {
+		return x.SignedCertificate
+	}
+	return nil
+}
+
+func (x *CertificateRenewalResponse) GetCaBundle() []byte {
+	if x != nil {
+		return x.CaBundle
+	}
+	return nil
+}
+
+func (x *CertificateRenewalResponse) GetAuthToken() string {
+	if x != nil {
+		return x.AuthToken
+	}
+	return ""
+}
+
+func (x *CertificateRenewalResponse) GetTokenExpiry() int64 {
+	if x != nil {
+		return x.TokenExpiry
+	}
+	return 0
+}
+
+type ControlPlaneInfo struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ApiEndpoint   string                 `protobuf:"bytes,1,opt,name=api_endpoint,json=apiEndpoint,proto3" json:"api_endpoint,omitempty"`
+	CaCertificate []byte                 `protobuf:"bytes,2,opt,name=ca_certificate,json=caCertificate,proto3" json:"ca_certificate,omitempty"`
+	// Deprecated: the same values as connection_settings, as duration strings
+	ConnectionParams   map[string]string   `protobuf:"bytes,3,rep,name=connection_params,json=connectionParams,proto3" json:"connection_params,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	CaBundle           []byte              `protobuf:"bytes,4,opt,name=ca_bundle,json=caBundle,proto3" json:"ca_bundle,omitempty"`         // PEM-encoded CAs trusted for node certificates
+	CaSpkiPins         []string            `protobuf:"bytes,5,rep,name=ca_spki_pins,json=caSpkiPins,proto3" json:"ca_spki_pins,omitempty"` // "sha256/<base64>" pins of the control-plane CAs
+	ConnectionSettings *ConnectionSettings `protobuf:"bytes,6,opt,name=connection_settings,json=connectionSettings,proto3" json:"connection_settings,omitempty"`
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
+}
+
+func (x *ControlPlaneInfo) Reset() {
+	*x = ControlPlaneInfo{}
+	mi := &file_node_proto_msgTypes[15]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -953,7 +1524,7 @@ func (x *ControlPlaneInfo) String() string {
 func (*ControlPlaneInfo) ProtoMessage() {}
 
 func (x *ControlPlaneInfo) ProtoReflect() protoreflect.Message {
-	mi := &file_node_proto_msgTypes[12]
+	mi := &file_node_proto_msgTypes[15]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -966,7 +1537,7 @@ func (x *ControlPlaneInfo) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ControlPlaneInfo.ProtoReflect.Descriptor instead.
 func (*ControlPlaneInfo) Descriptor() ([]byte, []int) {
-	return file_node_proto_rawDescGZIP(), []int{12}
+	return file_node_proto_rawDescGZIP(), []int{15}
 }
 
 func (x *ControlPlaneInfo) GetApiEndpoint() string {
@@ -990,6 +1561,263 @@ func (x *ControlPlaneInfo) GetConnectionParams() map[string]string {
 	return nil
 }
 
+func (x *ControlPlaneInfo) GetCaBundle() []byte {
+	if x != nil {
+		return x.CaBundle
+	}
+	return nil
+}
+
+func (x *ControlPlaneInfo) GetCaSpkiPins() []string {
+	if x != nil {
+		return x.CaSpkiPins
+	}
+	return nil
+}
+
+func (x *ControlPlaneInfo) GetConnectionSettings() *ConnectionSettings {
+	if x != nil {
+		return x.ConnectionSettings
+	}
+	return nil
+}
+
+// Transport settings the server enforces, for nodes to configure their client with
+type ConnectionSettings struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Ping after keepalive_time without activity, drop the connection when the
+	// ping is not acknowledged within keepalive_timeout
+	KeepaliveTimeMs    int64 `protobuf:"varint,1,opt,name=keepalive_time_ms,json=keepaliveTimeMs,proto3" json:"keepalive_time_ms,omitempty"`
+	KeepaliveTimeoutMs int64 `protobuf:"varint,2,opt,name=keepalive_timeout_ms,json=keepaliveTimeoutMs,proto3" json:"keepalive_timeout_ms,omitempty"`
+	// The server closes connections pinging more often than this
+	MinPingIntervalMs int64 `protobuf:"varint,3,opt,name=min_ping_interval_ms,json=minPingIntervalMs,proto3" json:"min_ping_interval_ms,omitempty"`
+	// Whether pings are allowed while no RPC is active
+	PermitWithoutStream bool `protobuf:"varint,4,opt,name=permit_without_stream,json=permitWithoutStream,proto3" json:"permit_without_stream,omitempty"`
+	// Upper bound of the reconnection backoff
+	MaxReconnectDelayMs int64 `protobuf:"varint,5,opt,name=max_reconnect_delay_ms,json=maxReconnectDelayMs,proto3" json:"max_reconnect_delay_ms,omitempty"`
+	// The server closes connections idle or older than this, 0 when unlimited
+	MaxConnectionIdleMs int64 `protobuf:"varint,6,opt,name=max_connection_idle_ms,json=maxConnectionIdleMs,proto3" json:"max_connection_idle_ms,omitempty"`
+	MaxConnectionAgeMs  int64 `protobuf:"varint,7,opt,name=max_connection_age_ms,json=maxConnectionAgeMs,proto3" json:"max_connection_age_ms,omitempty"`
+	// Largest message the server accepts, and sends, in bytes
+	MaxRequestSize       int32  `protobuf:"varint,8,opt,name=max_request_size,json=maxRequestSize,proto3" json:"max_request_size,omitempty"`
+	MaxResponseSize      int32  `protobuf:"varint,9,opt,name=max_response_size,json=maxResponseSize,proto3" json:"max_response_size,omitempty"`
+	MaxConcurrentStreams uint32 `protobuf:"varint,10,opt,name=max_concurrent_streams,json=maxConcurrentStreams,proto3" json:"max_concurrent_streams,omitempty"`
+	unknownFields        protoimpl.UnknownFields
+	sizeCache            protoimpl.SizeCache
+}
+
+func (x *ConnectionSettings) Reset() {
+	*x = ConnectionSettings{}
+	mi := &file_node_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ConnectionSettings) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ConnectionSettings) ProtoMessage() {}
+
+func (x *ConnectionSettings) ProtoReflect() protoreflect.Message {
+	mi := &file_node_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ConnectionSettings.ProtoReflect.Descriptor instead.
+func (*ConnectionSettings) Descriptor() ([]byte, []int) {
+	return file_node_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *ConnectionSettings) GetKeepaliveTimeMs() int64 {
+	if x != nil {
+		return x.KeepaliveTimeMs
+	}
+	return 0
+}
+
+func (x *ConnectionSettings) GetKeepaliveTimeoutMs() int64 {
+	if x != nil {
+		return x.KeepaliveTimeoutMs
+	}
+	return 0
+}
+
+func (x *ConnectionSettings) GetMinPingIntervalMs() int64 {
+	if x != nil {
+		return x.MinPingIntervalMs
+	}
+	return 0
+}
+
+func (x *ConnectionSettings) GetPermitWithoutStream() bool {
+	if x != nil {
+		return x.PermitWithoutStream
+	}
+	return false
+}
+
+func (x *ConnectionSettings) GetMaxReconnectDelayMs() int64 {
+	if x != nil {
+		return x.MaxReconnectDelayMs
+	}
+	return 0
+}
+
+func (x *ConnectionSettings) GetMaxConnectionIdleMs() int64 {
+	if x != nil {
+		return x.MaxConnectionIdleMs
+	}
+	return 0
+}
+
+func (x *ConnectionSettings) GetMaxConnectionAgeMs() int64 {
+	if x != nil {
+		return x.MaxConnectionAgeMs
+	}
+	return 0
+}
+
+func (x *ConnectionSettings) GetMaxRequestSize() int32 {
+	if x != nil {
+		return x.MaxRequestSize
+	}
+	return 0
+}
+
+func (x *ConnectionSettings) GetMaxResponseSize() int32 {
+	if x != nil {
+		return x.MaxResponseSize
+	}
+	return 0
+}
+
+func (x *ConnectionSettings) GetMaxConcurrentStreams() uint32 {
+	if x != nil {
+		return x.MaxConcurrentStreams
+	}
+	return 0
+}
+
+type TrustBundleRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	NodeId        string                 `protobuf:"bytes,1,opt,name=node_id,json=nodeId,proto3" json:"node_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TrustBundleRequest) Reset() {
+	*x = TrustBundleRequest{}
+	mi := &file_node_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TrustBundleRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TrustBundleRequest) ProtoMessage() {}
+
+func (x *TrustBundleRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_node_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TrustBundleRequest.ProtoReflect.Descriptor instead.
+func (*TrustBundleRequest) Descriptor() ([]byte, []int) {
+	return file_node_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *TrustBundleRequest) GetNodeId() string {
+	if x != nil {
+		return x.NodeId
+	}
+	return ""
+}
+
+type TrustBundleResponse struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	CaCertificate     []byte                 `protobuf:"bytes,1,opt,name=ca_certificate,json=caCertificate,proto3" json:"ca_certificate,omitempty"` // PEM-encoded CAs of the control-plane server certificate
+	CaSpkiPins        []string               `protobuf:"bytes,2,rep,name=ca_spki_pins,json=caSpkiPins,proto3" json:"ca_spki_pins,omitempty"`
+	CaBundle          []byte                 `protobuf:"bytes,3,opt,name=ca_bundle,json=caBundle,proto3" json:"ca_bundle,omitempty"` // PEM-encoded CAs trusted for node certificates
+	IssuerFingerprint string                 `protobuf:"bytes,4,opt,name=issuer_fingerprint,json=issuerFingerprint,proto3" json:"issuer_fingerprint,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *TrustBundleResponse) Reset() {
+	*x = TrustBundleResponse{}
+	mi := &file_node_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TrustBundleResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TrustBundleResponse) ProtoMessage() {}
+
+func (x *TrustBundleResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_node_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TrustBundleResponse.ProtoReflect.Descriptor instead.
+func (*TrustBundleResponse) Descriptor() ([]byte, []int) {
+	return file_node_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *TrustBundleResponse) GetCaCertificate() []byte {
+	if x != nil {
+		return x.CaCertificate
+	}
+	return nil
+}
+
+func (x *TrustBundleResponse) GetCaSpkiPins() []string {
+	if x != nil {
+		return x.CaSpkiPins
+	}
+	return nil
+}
+
+func (x *TrustBundleResponse) GetCaBundle() []byte {
+	if x != nil {
+		return x.CaBundle
+	}
+	return nil
+}
+
+func (x *TrustBundleResponse) GetIssuerFingerprint() string {
+	if x != nil {
+		return x.IssuerFingerprint
+	}
+	return ""
+}
+
 type ConfigurationUpdate struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	ConfigId      string                 `protobuf:"bytes,1,opt,name=config_id,json=configId,proto3" json:"config_id,omitempty"`
@@ -1000,7 +1828,7 @@ type ConfigurationUpdate struct {
 
 func (x *ConfigurationUpdate) Reset() {
 	*x = ConfigurationUpdate{}
-	mi := &file_node_proto_msgTypes[13]
+	mi := &file_node_proto_msgTypes[19]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1012,7 +1840,7 @@ func (x *ConfigurationUpdate) String() string {
 func (*ConfigurationUpdate) ProtoMessage() {}
 
 func (x *ConfigurationUpdate) ProtoReflect() protoreflect.Message {
-	mi := &file_node_proto_msgTypes[13]
+	mi := &file_node_proto_msgTypes[19]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1025,7 +1853,7 @@ func (x *ConfigurationUpdate) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ConfigurationUpdate.ProtoReflect.Descriptor instead.
 func (*ConfigurationUpdate) Descriptor() ([]byte, []int) {
-	return file_node_proto_rawDescGZIP(), []int{13}
+	return file_node_proto_rawDescGZIP(), []int{19}
 }
 
 func (x *ConfigurationUpdate) GetConfigId() string {
@@ -1042,6 +1870,74 @@ func (x *ConfigurationUpdate) GetConfiguration() *NodeConfiguration {
 	return nil
 }
 
+type TrustBundleUpdate struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	CaBundle          []byte                 `protobuf:"bytes,1,opt,name=ca_bundle,json=caBundle,proto3" json:"ca_bundle,omitempty"`                            // PEM-encoded CAs trusted for node certificates
+	IssuerFingerprint string                 `protobuf:"bytes,2,opt,name=issuer_fingerprint,json=issuerFingerprint,proto3" json:"issuer_fingerprint,omitempty"` // SHA-256 fingerprint of the CA issuing new certificates
+	RekeyRequired     bool                   `protobuf:"varint,3,opt,name=rekey_required,json=rekeyRequired,proto3" json:"rekey_required,omitempty"`            // The node certificate was issued by a CA being retired
+	RekeyBefore       int64                  `protobuf:"varint,4,opt,name=rekey_before,json=rekeyBefore,proto3" json:"rekey_before,omitempty"`                  // Unix time after which the current certificate stops being trusted
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *TrustBundleUpdate) Reset() {
+	*x = TrustBundleUpdate{}
+	mi := &file_node_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TrustBundleUpdate) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TrustBundleUpdate) ProtoMessage() {}
+
+func (x *TrustBundleUpdate) ProtoReflect() protoreflect.Message {
+	mi := &file_node_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TrustBundleUpdate.ProtoReflect.Descriptor instead.
+func (*TrustBundleUpdate) Descriptor() ([]byte, []int) {
+	return file_node_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *TrustBundleUpdate) GetCaBundle() []byte {
+	if x != nil {
+		return x.CaBundle
+	}
+	return nil
+}
+
+func (x *TrustBundleUpdate) GetIssuerFingerprint() string {
+	if x != nil {
+		return x.IssuerFingerprint
+	}
+	return ""
+}
+
+func (x *TrustBundleUpdate) GetRekeyRequired() bool {
+	if x != nil {
+		return x.RekeyRequired
+	}
+	return false
+}
+
+func (x *TrustBundleUpdate) GetRekeyBefore() int64 {
+	if x != nil {
+		return x.RekeyBefore
+	}
+	return 0
+}
+
 type HealthCheck struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	CheckId       string                 `protobuf:"bytes,1,opt,name=check_id,json=checkId,proto3" json:"check_id,omitempty"`
@@ -1052,7 +1948,7 @@ type HealthCheck struct {
 
 func (x *HealthCheck) Reset() {
 	*x = HealthCheck{}
-	mi := &file_node_proto_msgTypes[14]
+	mi := &file_node_proto_msgTypes[21]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1064,7 +1960,7 @@ func (x *HealthCheck) String() string {
 func (*HealthCheck) ProtoMessage() {}
 
 func (x *HealthCheck) ProtoReflect() protoreflect.Message {
-	mi := &file_node_proto_msgTypes[14]
+	mi := &file_node_proto_msgTypes[21]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1077,7 +1973,7 @@ func (x *HealthCheck) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use HealthCheck.ProtoReflect.Descriptor instead.
 func (*HealthCheck) Descriptor() ([]byte, []int) {
-	return file_node_proto_rawDescGZIP(), []int{14}
+	return file_node_proto_rawDescGZIP(), []int{21}
 }
 
 func (x *HealthCheck) GetCheckId() string {
@@ -1105,7 +2001,7 @@ type Disconnect struct {
 
 func (x *Disconnect) Reset() {
 	*x = Disconnect{}
-	mi := &file_node_proto_msgTypes[15]
+	mi := &file_node_proto_msgTypes[22]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1117,7 +2013,7 @@ func (x *Disconnect) String() string {
 func (*Disconnect) ProtoMessage() {}
 
 func (x *Disconnect) ProtoReflect() protoreflect.Message {
-	mi := &file_node_proto_msgTypes[15]
+	mi := &file_node_proto_msgTypes[22]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1130,7 +2026,7 @@ func (x *Disconnect) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Disconnect.ProtoReflect.Descriptor instead.
 func (*Disconnect) Descriptor() ([]byte, []int) {
-	return file_node_proto_rawDescGZIP(), []int{15}
+	return file_node_proto_rawDescGZIP(), []int{22}
 }
 
 func (x *Disconnect) GetReason() string {
@@ -1165,7 +2061,7 @@ type NodeConfiguration struct {
 
 func (x *NodeConfiguration) Reset() {
 	*x = NodeConfiguration{}
-	mi := &file_node_proto_msgTypes[16]
+	mi := &file_node_proto_msgTypes[23]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1177,7 +2073,7 @@ func (x *NodeConfiguration) String() string {
 func (*NodeConfiguration) ProtoMessage() {}
 
 func (x *NodeConfiguration) ProtoReflect() protoreflect.Message {
-	mi := &file_node_proto_msgTypes[16]
+	mi := &file_node_proto_msgTypes[23]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1190,7 +2086,7 @@ func (x *NodeConfiguration) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use NodeConfiguration.ProtoReflect.Descriptor instead.
 func (*NodeConfiguration) Descriptor() ([]byte, []int) {
-	return file_node_proto_rawDescGZIP(), []int{16}
+	return file_node_proto_rawDescGZIP(), []int{23}
 }
 
 func (x *NodeConfiguration) GetSettings() map[string]string {
@@ -1225,7 +2121,7 @@ type ResourceLimits struct {
 
 func (x *ResourceLimits) Reset() {
 	*x = ResourceLimits{}
-	mi := &file_node_proto_msgTypes[17]
+	mi := &file_node_proto_msgTypes[24]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1237,7 +2133,7 @@ func (x *ResourceLimits) String() string {
 func (*ResourceLimits) ProtoMessage() {}
 
 func (x *ResourceLimits) ProtoReflect() protoreflect.Message {
-	mi := &file_node_proto_msgTypes[17]
+	mi := &file_node_proto_msgTypes[24]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1250,7 +2146,7 @@ func (x *ResourceLimits) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ResourceLimits.ProtoReflect.Descriptor instead.
 func (*ResourceLimits) Descriptor() ([]byte, []int) {
-	return file_node_proto_rawDescGZIP(), []int{17}
+	return file_node_proto_rawDescGZIP(), []int{24}
 }
 
 func (x *ResourceLimits) GetMaxConcurrentTasks() int32 {
@@ -1285,7 +2181,7 @@ type NodeCapabilities struct {
 
 func (x *NodeCapabilities) Reset() {
 	*x = NodeCapabilities{}
-	mi := &file_node_proto_msgTypes[18]
+	mi := &file_node_proto_msgTypes[25]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1297,7 +2193,7 @@ func (x *NodeCapabilities) String() string {
 func (*NodeCapabilities) ProtoMessage() {}
 
 func (x *NodeCapabilities) ProtoReflect() protoreflect.Message {
-	mi := &file_node_proto_msgTypes[18]
+	mi := &file_node_proto_msgTypes[25]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1310,7 +2206,7 @@ func (x *NodeCapabilities) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use NodeCapabilities.ProtoReflect.Descriptor instead.
 func (*NodeCapabilities) Descriptor() ([]byte, []int) {
-	return file_node_proto_rawDescGZIP(), []int{18}
+	return file_node_proto_rawDescGZIP(), []int{25}
 }
 
 func (x *NodeCapabilities) GetSupportedModelTypes() []string {
@@ -1344,14 +2240,26 @@ const file_node_proto_rawDesc = "" +
 	"\x0fbootstrap_token\x18\x01 \x01(\tR\x0ebootstrapToken\x12:\n" +
 	"\n" +
 	"basic_info\x18\x02 \x01(\v2\x1b.luminousmesh.NodeBasicInfoR\tbasicInfo\x12\x10\n" +
-	"\x03csr\x18\x03 \x01(\fR\x03csr\"\x8e\x02\n" +
+	"\x03csr\x18\x03 \x01(\fR\x03csr\"\xb5\x04\n" +
 	"\x14RegisterNodeResponse\x12\x18\n" +
 	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
 	"\amessage\x18\x02 \x01(\tR\amessage\x12-\n" +
 	"\x12signed_certificate\x18\x03 \x01(\fR\x11signedCertificate\x12\x17\n" +
 	"\anode_id\x18\x04 \x01(\tR\x06nodeId\x12,\n" +
 	"\x12initial_auth_token\x18\x05 \x01(\tR\x10initialAuthToken\x12L\n" +
-	"\x12control_plane_info\x18\x06 \x01(\v2\x1e.luminousmesh.ControlPlaneInfoR\x10controlPlaneInfo\"\xf1\x01\n" +
+	"\x12control_plane_info\x18\x06 \x01(\v2\x1e.luminousmesh.ControlPlaneInfoR\x10controlPlaneInfo\x12c\n" +
+	"\x12registration_state\x18\a \x01(\x0e24.luminousmesh.RegisterNodeResponse.RegistrationStateR\x11registrationState\x12'\n" +
+	"\x0fregistration_id\x18\b \x01(\tR\x0eregistrationId\x122\n" +
+	"\x15poll_interval_seconds\x18\t \x01(\x05R\x13pollIntervalSeconds\"c\n" +
+	"\x11RegistrationState\x12\x0f\n" +
+	"\vUNSPECIFIED\x10\x00\x12\f\n" +
+	"\bADMITTED\x10\x01\x12\x14\n" +
+	"\x10PENDING_APPROVAL\x10\x02\x12\f\n" +
+	"\bREJECTED\x10\x03\x12\v\n" +
+	"\aEXPIRED\x10\x04\"g\n" +
+	"\x19RegistrationStatusRequest\x12'\n" +
+	"\x0fregistration_id\x18\x01 \x01(\tR\x0eregistrationId\x12!\n" +
+	"\fwait_seconds\x18\x02 \x01(\x05R\vwaitSeconds\"\xf1\x01\n" +
 	"\x15AuthenticationRequest\x12\x17\n" +
 	"\anode_id\x18\x01 \x01(\tR\x06nodeId\x12\x1d\n" +
 	"\n" +
@@ -1359,21 +2267,23 @@ const file_node_proto_rawDesc = "" +
 	"\vcertificate\x18\x03 \x01(\fR\vcertificate\x12:\n" +
 	"\n" +
 	"basic_info\x18\x04 \x01(\v2\x1b.luminousmesh.NodeBasicInfoR\tbasicInfo\x12B\n" +
-	"\fcapabilities\x18\x05 \x01(\v2\x1e.luminousmesh.NodeCapabilitiesR\fcapabilities\"\xd6\x01\n" +
+	"\fcapabilities\x18\x05 \x01(\v2\x1e.luminousmesh.NodeCapabilitiesR\fcapabilities\"\xa9\x02\n" +
 	"\x16AuthenticationResponse\x12\x18\n" +
 	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
 	"\amessage\x18\x02 \x01(\tR\amessage\x12\x1d\n" +
 	"\n" +
 	"session_id\x18\x03 \x01(\tR\tsessionId\x12!\n" +
 	"\ftoken_expiry\x18\x04 \x01(\x03R\vtokenExpiry\x12F\n" +
-	"\x0einitial_config\x18\x05 \x01(\v2\x1f.luminousmesh.NodeConfigurationR\rinitialConfig\"\xd1\x01\n" +
+	"\x0einitial_config\x18\x05 \x01(\v2\x1f.luminousmesh.NodeConfigurationR\rinitialConfig\x12Q\n" +
+	"\x13connection_settings\x18\x06 \x01(\v2 .luminousmesh.ConnectionSettingsR\x12connectionSettings\"\xfd\x01\n" +
 	"\x10NodeStatusUpdate\x12\x17\n" +
 	"\anode_id\x18\x01 \x01(\tR\x06nodeId\x12\x1d\n" +
 	"\n" +
 	"session_id\x18\x02 \x01(\tR\tsessionId\x120\n" +
 	"\x06status\x18\x03 \x01(\v2\x18.luminousmesh.NodeStatusR\x06status\x125\n" +
 	"\ametrics\x18\x04 \x03(\v2\x1b.luminousmesh.MetricsReportR\ametrics\x12\x1c\n" +
-	"\ttimestamp\x18\x05 \x01(\x03R\ttimestamp\"\x85\x02\n" +
+	"\ttimestamp\x18\x05 \x01(\x03R\ttimestamp\x12*\n" +
+	"\x11acked_command_ids\x18\x06 \x03(\tR\x0fackedCommandIds\"\xec\x03\n" +
 	"\x13ControlPlaneCommand\x12\x1d\n" +
 	"\n" +
 	"command_id\x18\x01 \x01(\tR\tcommandId\x12H\n" +
@@ -1381,8 +2291,16 @@ const file_node_proto_rawDesc = "" +
 	"\fhealth_check\x18\x03 \x01(\v2\x19.luminousmesh.HealthCheckH\x00R\vhealthCheck\x12:\n" +
 	"\n" +
 	"disconnect\x18\x04 \x01(\v2\x18.luminousmesh.DisconnectH\x00R\n" +
-	"disconnectB\t\n" +
-	"\acommand\"\xb8\x02\n" +
+	"disconnect\x12Q\n" +
+	"\x13trust_bundle_update\x18\x05 \x01(\v2\x1f.luminousmesh.TrustBundleUpdateH\x00R\x11trustBundleUpdate\x12F\n" +
+	"\bpriority\x18\x06 \x01(\x0e2*.luminousmesh.ControlPlaneCommand.PriorityR\bpriority\x12\x1a\n" +
+	"\bdeadline\x18\a \x01(\x03R\bdeadline\".\n" +
+	"\bPriority\x12\n" +
+	"\n" +
+	"\x06NORMAL\x10\x00\x12\f\n" +
+	"\bCRITICAL\x10\x01\x12\b\n" +
+	"\x04BULK\x10\x02B\t\n" +
+	"\acommand\"\xd7\x02\n" +
 	"\rNodeBasicInfo\x12\x1a\n" +
 	"\bhostname\x18\x01 \x01(\tR\bhostname\x12\x1d\n" +
 	"\n" +
@@ -1390,7 +2308,9 @@ const file_node_proto_rawDesc = "" +
 	"\aversion\x18\x03 \x01(\tR\aversion\x122\n" +
 	"\x15supported_model_types\x18\x04 \x03(\tR\x13supportedModelTypes\x12\"\n" +
 	"\farchitecture\x18\x05 \x01(\tR\farchitecture\x12?\n" +
-	"\x06labels\x18\x06 \x03(\v2'.luminousmesh.NodeBasicInfo.LabelsEntryR\x06labels\x1a9\n" +
+	"\x06labels\x18\x06 \x03(\v2'.luminousmesh.NodeBasicInfo.LabelsEntryR\x06labels\x12\x1d\n" +
+	"\n" +
+	"machine_id\x18\a \x01(\tR\tmachineId\x1a9\n" +
 	"\vLabelsEntry\x12\x10\n" +
 	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
 	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\xd9\x02\n" +
@@ -1427,17 +2347,59 @@ const file_node_proto_rawDesc = "" +
 	"session_id\x18\x03 \x01(\tR\tsessionId\"L\n" +
 	"\x15TokenRotationResponse\x12\x1b\n" +
 	"\tnew_token\x18\x01 \x01(\tR\bnewToken\x12\x16\n" +
-	"\x06expiry\x18\x02 \x01(\x03R\x06expiry\"\x84\x02\n" +
+	"\x06expiry\x18\x02 \x01(\x03R\x06expiry\"e\n" +
+	"\x19CertificateRenewalRequest\x12\x17\n" +
+	"\anode_id\x18\x01 \x01(\tR\x06nodeId\x12\x1d\n" +
+	"\n" +
+	"auth_token\x18\x02 \x01(\tR\tauthToken\x12\x10\n" +
+	"\x03csr\x18\x03 \x01(\fR\x03csr\"\xde\x01\n" +
+	"\x1aCertificateRenewalResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\x12-\n" +
+	"\x12signed_certificate\x18\x03 \x01(\fR\x11signedCertificate\x12\x1b\n" +
+	"\tca_bundle\x18\x04 \x01(\fR\bcaBundle\x12\x1d\n" +
+	"\n" +
+	"auth_token\x18\x05 \x01(\tR\tauthToken\x12!\n" +
+	"\ftoken_expiry\x18\x06 \x01(\x03R\vtokenExpiry\"\x96\x03\n" +
 	"\x10ControlPlaneInfo\x12!\n" +
 	"\fapi_endpoint\x18\x01 \x01(\tR\vapiEndpoint\x12%\n" +
 	"\x0eca_certificate\x18\x02 \x01(\fR\rcaCertificate\x12a\n" +
-	"\x11connection_params\x18\x03 \x03(\v24.luminousmesh.ControlPlaneInfo.ConnectionParamsEntryR\x10connectionParams\x1aC\n" +
+	"\x11connection_params\x18\x03 \x03(\v24.luminousmesh.ControlPlaneInfo.ConnectionParamsEntryR\x10connectionParams\x12\x1b\n" +
+	"\tca_bundle\x18\x04 \x01(\fR\bcaBundle\x12 \n" +
+	"\fca_spki_pins\x18\x05 \x03(\tR\n" +
+	"caSpkiPins\x12Q\n" +
+	"\x13connection_settings\x18\x06 \x01(\v2 .luminousmesh.ConnectionSettingsR\x12connectionSettings\x1aC\n" +
 	"\x15ConnectionParamsEntry\x12\x10\n" +
 	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
-	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"y\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\x80\x04\n" +
+	"\x12ConnectionSettings\x12*\n" +
+	"\x11keepalive_time_ms\x18\x01 \x01(\x03R\x0fkeepaliveTimeMs\x120\n" +
+	"\x14keepalive_timeout_ms\x18\x02 \x01(\x03R\x12keepaliveTimeoutMs\x12/\n" +
+	"\x14min_ping_interval_ms\x18\x03 \x01(\x03R\x11minPingIntervalMs\x122\n" +
+	"\x15permit_without_stream\x18\x04 \x01(\bR\x13permitWithoutStream\x123\n" +
+	"\x16max_reconnect_delay_ms\x18\x05 \x01(\x03R\x13maxReconnectDelayMs\x123\n" +
+	"\x16max_connection_idle_ms\x18\x06 \x01(\x03R\x13maxConnectionIdleMs\x121\n" +
+	"\x15max_connection_age_ms\x18\a \x01(\x03R\x12maxConnectionAgeMs\x12(\n" +
+	"\x10max_request_size\x18\b \x01(\x05R\x0emaxRequestSize\x12*\n" +
+	"\x11max_response_size\x18\t \x01(\x05R\x0fmaxResponseSize\x124\n" +
+	"\x16max_concurrent_streams\x18\n" +
+	" \x01(\rR\x14maxConcurrentStreams\"-\n" +
+	"\x12TrustBundleRequest\x12\x17\n" +
+	"\anode_id\x18\x01 \x01(\tR\x06nodeId\"\xaa\x01\n" +
+	"\x13TrustBundleResponse\x12%\n" +
+	"\x0eca_certificate\x18\x01 \x01(\fR\rcaCertificate\x12 \n" +
+	"\fca_spki_pins\x18\x02 \x03(\tR\n" +
+	"caSpkiPins\x12\x1b\n" +
+	"\tca_bundle\x18\x03 \x01(\fR\bcaBundle\x12-\n" +
+	"\x12issuer_fingerprint\x18\x04 \x01(\tR\x11issuerFingerprint\"y\n" +
 	"\x13ConfigurationUpdate\x12\x1b\n" +
 	"\tconfig_id\x18\x01 \x01(\tR\bconfigId\x12E\n" +
-	"\rconfiguration\x18\x02 \x01(\v2\x1f.luminousmesh.NodeConfigurationR\rconfiguration\"I\n" +
+	"\rconfiguration\x18\x02 \x01(\v2\x1f.luminousmesh.NodeConfigurationR\rconfiguration\"\xa9\x01\n" +
+	"\x11TrustBundleUpdate\x12\x1b\n" +
+	"\tca_bundle\x18\x01 \x01(\fR\bcaBundle\x12-\n" +
+	"\x12issuer_fingerprint\x18\x02 \x01(\tR\x11issuerFingerprint\x12%\n" +
+	"\x0erekey_required\x18\x03 \x01(\bR\rrekeyRequired\x12!\n" +
+	"\frekey_before\x18\x04 \x01(\x03R\vrekeyBefore\"I\n" +
 	"\vHealthCheck\x12\x19\n" +
 	"\bcheck_id\x18\x01 \x01(\tR\acheckId\x12\x1f\n" +
 	"\vcheck_items\x18\x02 \x03(\tR\n" +
@@ -1464,12 +2426,56 @@ const file_node_proto_rawDesc = "" +
 	"\x06labels\x18\x03 \x03(\v2*.luminousmesh.NodeCapabilities.LabelsEntryR\x06labels\x1a9\n" +
 	"\vLabelsEntry\x12\x10\n" +
 	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
-	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x012\xfa\x02\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01*\xd8\x06\n" +
+	"\vErrorReason\x12\x1c\n" +
+	"\x18ERROR_REASON_UNSPECIFIED\x10\x00\x12\f\n" +
+	"\bINTERNAL\x10\x01\x12\x17\n" +
+	"\x13CREDENTIALS_MISSING\x10\x02\x12\x1b\n" +
+	"\x17BOOTSTRAP_TOKEN_INVALID\x10\x03\x12\x16\n" +
+	"\x12AUTH_TOKEN_INVALID\x10\x04\x12\x16\n" +
+	"\x12AUTH_TOKEN_UNBOUND\x10\x05\x12#\n" +
+	"\x1fAUTH_TOKEN_CERTIFICATE_MISMATCH\x10\x06\x12\x10\n" +
+	"\fTOKEN_REUSED\x10\a\x12\x18\n" +
+	"\x14CERTIFICATE_REQUIRED\x10\b\x12\x17\n" +
+	"\x13CERTIFICATE_INVALID\x10\t\x12\x17\n" +
+	"\x13CERTIFICATE_REVOKED\x10\n" +
+	"\x12\x18\n" +
+	"\x14CERTIFICATE_MISMATCH\x10\v\x12\x0f\n" +
+	"\vCSR_INVALID\x10\f\x12\x17\n" +
+	"\x13NODE_DECOMMISSIONED\x10\r\x12\x11\n" +
+	"\rNODE_MISMATCH\x10\x0e\x12\x14\n" +
+	"\x10SESSION_REQUIRED\x10\x0f\x12\x13\n" +
+	"\x0fSESSION_INVALID\x10\x10\x12\x13\n" +
+	"\x0fSESSION_EXPIRED\x10\x11\x12\x13\n" +
+	"\x0fSESSION_REVOKED\x10\x12\x12\x14\n" +
+	"\x10SESSION_MISMATCH\x10\x13\x12\x1a\n" +
+	"\x16REGISTRATION_NOT_FOUND\x10\x14\x12\x18\n" +
+	"\x14REGISTRATION_INVALID\x10\x15\x12\x10\n" +
+	"\fRATE_LIMITED\x10\x16\x12\x0e\n" +
+	"\n" +
+	"LOCKED_OUT\x10\x17\x12\x0e\n" +
+	"\n" +
+	"OVERLOADED\x10\x18\x12\f\n" +
+	"\bDRAINING\x10\x19\x12\x15\n" +
+	"\x11PERMISSION_DENIED\x10\x1a\x12\x1f\n" +
+	"\x1bOPERATOR_CREDENTIAL_INVALID\x10\x1b\x12\x13\n" +
+	"\x0fINVALID_REQUEST\x10\x1c\x12\x12\n" +
+	"\x0eNODE_NOT_FOUND\x10\x1d\x12\x15\n" +
+	"\x11SESSION_NOT_FOUND\x10\x1e\x12\x1c\n" +
+	"\x18REGISTRATION_NOT_PENDING\x10\x1f\x12\x19\n" +
+	"\x15REGISTRATION_DECIDING\x10 \x12\x0f\n" +
+	"\vUSER_EXISTS\x10!\x12\x12\n" +
+	"\x0eUSER_NOT_FOUND\x10\"\x12\x15\n" +
+	"\x11API_KEY_NOT_FOUND\x10#\x12\x0f\n" +
+	"\vOUTBOX_FULL\x10$2\xa4\x05\n" +
 	"\vNodeService\x12W\n" +
-	"\fRegisterNode\x12!.luminousmesh.RegisterNodeRequest\x1a\".luminousmesh.RegisterNodeResponse\"\x00\x12[\n" +
+	"\fRegisterNode\x12!.luminousmesh.RegisterNodeRequest\x1a\".luminousmesh.RegisterNodeResponse\"\x00\x12f\n" +
+	"\x15GetRegistrationStatus\x12'.luminousmesh.RegistrationStatusRequest\x1a\".luminousmesh.RegisterNodeResponse\"\x00\x12[\n" +
 	"\fAuthenticate\x12#.luminousmesh.AuthenticationRequest\x1a$.luminousmesh.AuthenticationResponse\"\x00\x12[\n" +
 	"\x10StreamConnection\x12\x1e.luminousmesh.NodeStatusUpdate\x1a!.luminousmesh.ControlPlaneCommand\"\x00(\x010\x01\x12X\n" +
-	"\vRotateToken\x12\".luminousmesh.TokenRotationRequest\x1a#.luminousmesh.TokenRotationResponse\"\x00B-Z+github.com/luminousmesh/control-plane/protob\x06proto3"
+	"\vRotateToken\x12\".luminousmesh.TokenRotationRequest\x1a#.luminousmesh.TokenRotationResponse\"\x00\x12g\n" +
+	"\x10RenewCertificate\x12'.luminousmesh.CertificateRenewalRequest\x1a(.luminousmesh.CertificateRenewalResponse\"\x00\x12W\n" +
+	"\x0eGetTrustBundle\x12 .luminousmesh.TrustBundleRequest\x1a!.luminousmesh.TrustBundleResponse\"\x00B-Z+github.com/luminousmesh/control-plane/protob\x06proto3"
 
 var (
 	file_node_proto_rawDescOnce sync.Once
@@ -1483,70 +2489,91 @@ func file_node_proto_rawDescGZIP() []byte {
 	return file_node_proto_rawDescData
 }
 
-var file_node_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
-var file_node_proto_msgTypes = make([]protoimpl.MessageInfo, 25)
+var file_node_proto_enumTypes = make([]protoimpl.EnumInfo, 4)
+var file_node_proto_msgTypes = make([]protoimpl.MessageInfo, 32)
 var file_node_proto_goTypes = []any{
-	(NodeStatus_State)(0),          // 0: luminousmesh.NodeStatus.State
-	(*RegisterNodeRequest)(nil),    // 1: luminousmesh.RegisterNodeRequest
-	(*RegisterNodeResponse)(nil),   // 2: luminousmesh.RegisterNodeResponse
-	(*AuthenticationRequest)(nil),  // 3: luminousmesh.AuthenticationRequest
-	(*AuthenticationResponse)(nil), // 4: luminousmesh.AuthenticationResponse
-	(*NodeStatusUpdate)(nil),       // 5: luminousmesh.NodeStatusUpdate
-	(*ControlPlaneCommand)(nil),    // 6: luminousmesh.ControlPlaneCommand
-	(*NodeBasicInfo)(nil),          // 7: luminousmesh.NodeBasicInfo
-	(*NodeStatus)(nil),             // 8: luminousmesh.NodeStatus
-	(*ResourceStatus)(nil),         // 9: luminousmesh.ResourceStatus
-	(*MetricsReport)(nil),          // 10: luminousmesh.MetricsReport
-	(*TokenRotationRequest)(nil),   // 11: luminousmesh.TokenRotationRequest
-	(*TokenRotationResponse)(nil),  // 12: luminousmesh.TokenRotationResponse
-	(*ControlPlaneInfo)(nil),       // 13: luminousmesh.ControlPlaneInfo
-	(*ConfigurationUpdate)(nil),    // 14: luminousmesh.ConfigurationUpdate
-	(*HealthCheck)(nil),            // 15: luminousmesh.HealthCheck
-	(*Disconnect)(nil),             // 16: luminousmesh.Disconnect
-	(*NodeConfiguration)(nil),      // 17: luminousmesh.NodeConfiguration
-	(*ResourceLimits)(nil),         // 18: luminousmesh.ResourceLimits
-	(*NodeCapabilities)(nil),       // 19: luminousmesh.NodeCapabilities
-	nil,                            // 20: luminousmesh.NodeBasicInfo.LabelsEntry
-	nil,                            // 21: luminousmesh.NodeStatus.ResourcesEntry
-	nil,                            // 22: luminousmesh.MetricsReport.LabelsEntry
-	nil,                            // 23: luminousmesh.ControlPlaneInfo.ConnectionParamsEntry
-	nil,                            // 24: luminousmesh.NodeConfiguration.SettingsEntry
-	nil,                            // 25: luminousmesh.NodeCapabilities.LabelsEntry
+	(ErrorReason)(0), // 0: luminousmesh.ErrorReason
+	(RegisterNodeResponse_RegistrationState)(0), // 1: luminousmesh.RegisterNodeResponse.RegistrationState
+	(ControlPlaneCommand_Priority)(0),           // 2: luminousmesh.ControlPlaneCommand.Priority
+	(NodeStatus_State)(0),                       // 3: luminousmesh.NodeStatus.State
+	(*RegisterNodeRequest)(nil),                 // 4: luminousmesh.RegisterNodeRequest
+	(*RegisterNodeResponse)(nil),                // 5: luminousmesh.RegisterNodeResponse
+	(*RegistrationStatusRequest)(nil),           // 6: luminousmesh.RegistrationStatusRequest
+	(*AuthenticationRequest)(nil),               // 7: luminousmesh.AuthenticationRequest
+	(*AuthenticationResponse)(nil),              // 8: luminousmesh.AuthenticationResponse
+	(*NodeStatusUpdate)(nil),                    // 9: luminousmesh.NodeStatusUpdate
+	(*ControlPlaneCommand)(nil),                 // 10: luminousmesh.ControlPlaneCommand
+	(*NodeBasicInfo)(nil),                       // 11: luminousmesh.NodeBasicInfo
+	(*NodeStatus)(nil),                          // 12: luminousmesh.NodeStatus
+	(*ResourceStatus)(nil),                      // 13: luminousmesh.ResourceStatus
+	(*MetricsReport)(nil),                       // 14: luminousmesh.MetricsReport
+	(*TokenRotationRequest)(nil),                // 15: luminousmesh.TokenRotationRequest
+	(*TokenRotationResponse)(nil),               // 16: luminousmesh.TokenRotationResponse
+	(*CertificateRenewalRequest)(nil),           // 17: luminousmesh.CertificateRenewalRequest
+	(*CertificateRenewalResponse)(nil),          // 18: luminousmesh.CertificateRenewalResponse
+	(*ControlPlaneInfo)(nil),                    // 19: luminousmesh.ControlPlaneInfo
+	(*ConnectionSettings)(nil),                  // 20: luminousmesh.ConnectionSettings
+	(*TrustBundleRequest)(nil),                  // 21: luminousmesh.TrustBundleRequest
+	(*TrustBundleResponse)(nil),                 // 22: luminousmesh.TrustBundleResponse
+	(*ConfigurationUpdate)(nil),                 // 23: luminousmesh.ConfigurationUpdate
+	(*TrustBundleUpdate)(nil),                   // 24: luminousmesh.TrustBundleUpdate
+	(*HealthCheck)(nil),                         // 25: luminousmesh.HealthCheck
+	(*Disconnect)(nil),                          // 26: luminousmesh.Disconnect
+	(*NodeConfiguration)(nil),                   // 27: luminousmesh.NodeConfiguration
+	(*ResourceLimits)(nil),                      // 28: luminousmesh.ResourceLimits
+	(*NodeCapabilities)(nil),                    // 29: luminousmesh.NodeCapabilities
+	nil,                                         // 30: luminousmesh.NodeBasicInfo.LabelsEntry
+	nil,                                         // 31: luminousmesh.NodeStatus.ResourcesEntry
+	nil,                                         // 32: luminousmesh.MetricsReport.LabelsEntry
+	nil,                                         // 33: luminousmesh.ControlPlaneInfo.ConnectionParamsEntry
+	nil,                                         // 34: luminousmesh.NodeConfiguration.SettingsEntry
+	nil,                                         // 35: luminousmesh.NodeCapabilities.LabelsEntry
 }
 var file_node_proto_depIdxs = []int32{
-	7,  // 0: luminousmesh.RegisterNodeRequest.basic_info:type_name -> luminousmesh.NodeBasicInfo
-	13, // 1: luminousmesh.RegisterNodeResponse.control_plane_info:type_name -> luminousmesh.ControlPlaneInfo
-	7,  // 2: luminousmesh.AuthenticationRequest.basic_info:type_name -> luminousmesh.NodeBasicInfo
-	19, // 3: luminousmesh.AuthenticationRequest.capabilities:type_name -> luminousmesh.NodeCapabilities
-	17, // 4: luminousmesh.AuthenticationResponse.initial_config:type_name -> luminousmesh.NodeConfiguration
-	8,  // 5: luminousmesh.NodeStatusUpdate.status:type_name -> luminousmesh.NodeStatus
-	10, // 6: luminousmesh.NodeStatusUpdate.metrics:type_name -> luminousmesh.MetricsReport
-	14, // 7: luminousmesh.ControlPlaneCommand.config_update:type_name -> luminousmesh.ConfigurationUpdate
-	15, // 8: luminousmesh.ControlPlaneCommand.health_check:type_name -> luminousmesh.HealthCheck
-	16, // 9: luminousmesh.ControlPlaneCommand.disconnect:type_name -> luminousmesh.Disconnect
-	20, // 10: luminousmesh.NodeBasicInfo.labels:type_name -> luminousmesh.NodeBasicInfo.LabelsEntry
-	0,  // 11: luminousmesh.NodeStatus.state:type_name -> luminousmesh.NodeStatus.State
-	21, // 12: luminousmesh.NodeStatus.resources:type_name -> luminousmesh.NodeStatus.ResourcesEntry
-	22, // 13: luminousmesh.MetricsReport.labels:type_name -> luminousmesh.MetricsReport.LabelsEntry
-	23, // 14: luminousmesh.ControlPlaneInfo.connection_params:type_name -> luminousmesh.ControlPlaneInfo.ConnectionParamsEntry
-	17, // 15: luminousmesh.ConfigurationUpdate.configuration:type_name -> luminousmesh.NodeConfiguration
-	24, // 16: luminousmesh.NodeConfiguration.settings:type_name -> luminousmesh.NodeConfiguration.SettingsEntry
-	18, // 17: luminousmesh.NodeConfiguration.resource_limits:type_name -> luminousmesh.ResourceLimits
-	25, // 18: luminousmesh.NodeCapabilities.labels:type_name -> luminousmesh.NodeCapabilities.LabelsEntry
-	9,  // 19: luminousmesh.NodeStatus.ResourcesEntry.value:type_name -> luminousmesh.ResourceStatus
-	1,  // 20: luminousmesh.NodeService.RegisterNode:input_type -> luminousmesh.RegisterNodeRequest
-	3,  // 21: luminousmesh.NodeService.Authenticate:input_type -> luminousmesh.AuthenticationRequest
-	5,  // 22: luminousmesh.NodeService.StreamConnection:input_type -> luminousmesh.NodeStatusUpdate
-	11, // 23: luminousmesh.NodeService.RotateToken:input_type -> luminousmesh.TokenRotationRequest
-	2,  // 24: luminousmesh.NodeService.RegisterNode:output_type -> luminousmesh.RegisterNodeResponse
-	4,  // 25: luminousmesh.NodeService.Authenticate:output_type -> luminousmesh.AuthenticationResponse
-	6,  // 26: luminousmesh.NodeService.StreamConnection:output_type -> luminousmesh.ControlPlaneCommand
-	12, // 27: luminousmesh.NodeService.RotateToken:output_type -> luminousmesh.TokenRotationResponse
-	24, // [24:28] is the sub-list for method output_type
-	20, // [20:24] is the sub-list for method input_type
-	20, // [20:20] is the sub-list for extension type_name
-	20, // [20:20] is the sub-list for extension extendee
-	0,  // [0:20] is the sub-list for field type_name
+	11, // 0: luminousmesh.RegisterNodeRequest.basic_info:type_name -> luminousmesh.NodeBasicInfo
+	19, // 1: luminousmesh.RegisterNodeResponse.control_plane_info:type_name -> luminousmesh.ControlPlaneInfo
+	1,  // 2: luminousmesh.RegisterNodeResponse.registration_state:type_name -> luminousmesh.RegisterNodeResponse.RegistrationState
+	11, // 3: luminousmesh.AuthenticationRequest.basic_info:type_name -> luminousmesh.NodeBasicInfo
+	29, // 4: luminousmesh.AuthenticationRequest.capabilities:type_name -> luminousmesh.NodeCapabilities
+	27, // 5: luminousmesh.AuthenticationResponse.initial_config:type_name -> luminousmesh.NodeConfiguration
+	20, // 6: luminousmesh.AuthenticationResponse.connection_settings:type_name -> luminousmesh.ConnectionSettings
+	12, // 7: luminousmesh.NodeStatusUpdate.status:type_name -> luminousmesh.NodeStatus
+	14, // 8: luminousmesh.NodeStatusUpdate.metrics:type_name -> luminousmesh.MetricsReport
+	23, // 9: luminousmesh.ControlPlaneCommand.config_update:type_name -> luminousmesh.ConfigurationUpdate
+	25, // 10: luminousmesh.ControlPlaneCommand.health_check:type_name -> luminousmesh.HealthCheck
+	26, // 11: luminousmesh.ControlPlaneCommand.disconnect:type_name -> luminousmesh.Disconnect
+	24, // 12: luminousmesh.ControlPlaneCommand.trust_bundle_update:type_name -> luminousmesh.TrustBundleUpdate
+	2,  // 13: luminousmesh.ControlPlaneCommand.priority:type_name -> luminousmesh.ControlPlaneCommand.Priority
+	30, // 14: luminousmesh.NodeBasicInfo.labels:type_name -> luminousmesh.NodeBasicInfo.LabelsEntry
+	3,  // 15: luminousmesh.NodeStatus.state:type_name -> luminousmesh.NodeStatus.State
+	31, // 16: luminousmesh.NodeStatus.resources:type_name -> luminousmesh.NodeStatus.ResourcesEntry
+	32, // 17: luminousmesh.MetricsReport.labels:type_name -> luminousmesh.MetricsReport.LabelsEntry
+	33, // 18: luminousmesh.ControlPlaneInfo.connection_params:type_name -> luminousmesh.ControlPlaneInfo.ConnectionParamsEntry
+	20, // 19: luminousmesh.ControlPlaneInfo.connection_settings:type_name -> luminousmesh.ConnectionSettings
+	27, // 20: luminousmesh.ConfigurationUpdate.configuration:type_name -> luminousmesh.NodeConfiguration
+	34, // 21: luminousmesh.NodeConfiguration.settings:type_name -> luminousmesh.NodeConfiguration.SettingsEntry
+	28, // 22: luminousmesh.NodeConfiguration.resource_limits:type_name -> luminousmesh.ResourceLimits
+	35, // 23: luminousmesh.NodeCapabilities.labels:type_name -> luminousmesh.NodeCapabilities.LabelsEntry
+	13, // 24: luminousmesh.NodeStatus.ResourcesEntry.value:type_name -> luminousmesh.ResourceStatus
+	4,  // 25: luminousmesh.NodeService.RegisterNode:input_type -> luminousmesh.RegisterNodeRequest
+	6,  // 26: luminousmesh.NodeService.GetRegistrationStatus:input_type -> luminousmesh.RegistrationStatusRequest
+	7,  // 27: luminousmesh.NodeService.Authenticate:input_type -> luminousmesh.AuthenticationRequest
+	9,  // 28: luminousmesh.NodeService.StreamConnection:input_type -> luminousmesh.NodeStatusUpdate
+	15, // 29: luminousmesh.NodeService.RotateToken:input_type -> luminousmesh.TokenRotationRequest
+	17, // 30: luminousmesh.NodeService.RenewCertificate:input_type -> luminousmesh.CertificateRenewalRequest
+	21, // 31: luminousmesh.NodeService.GetTrustBundle:input_type -> luminousmesh.TrustBundleRequest
+	5,  // 32: luminousmesh.NodeService.RegisterNode:output_type -> luminousmesh.RegisterNodeResponse
+	5,  // 33: luminousmesh.NodeService.GetRegistrationStatus:output_type -> luminousmesh.RegisterNodeResponse
+	8,  // 34: luminousmesh.NodeService.Authenticate:output_type -> luminousmesh.AuthenticationResponse
+	10, // 35: luminousmesh.NodeService.StreamConnection:output_type -> luminousmesh.ControlPlaneCommand
+	16, // 36: luminousmesh.NodeService.RotateToken:output_type -> luminousmesh.TokenRotationResponse
+	18, // 37: luminousmesh.NodeService.RenewCertificate:output_type -> luminousmesh.CertificateRenewalResponse
+	22, // 38: luminousmesh.NodeService.GetTrustBundle:output_type -> luminousmesh.TrustBundleResponse
+	32, // [32:39] is the sub-list for method output_type
+	25, // [25:32] is the sub-list for method input_type
+	25, // [25:25] is the sub-list for extension type_name
+	25, // [25:25] is the sub-list for extension extendee
+	0,  // [0:25] is the sub-list for field type_name
 }
 
 func init() { file_node_proto_init() }
@@ -1554,18 +2581,19 @@ func file_node_proto_init() {
 	if File_node_proto != nil {
 		return
 	}
-	file_node_proto_msgTypes[5].OneofWrappers = []any{
+	file_node_proto_msgTypes[6].OneofWrappers = []any{
 		(*ControlPlaneCommand_ConfigUpdate)(nil),
 		(*ControlPlaneCommand_HealthCheck)(nil),
 		(*ControlPlaneCommand_Disconnect)(nil),
+		(*ControlPlaneCommand_TrustBundleUpdate)(nil),
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_node_proto_rawDesc), len(file_node_proto_rawDesc)),
-			NumEnums:      1,
-			NumMessages:   25,
+			NumEnums:      4,
+			NumMessages:   32,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
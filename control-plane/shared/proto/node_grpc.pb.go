@@ -19,10 +19,13 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	NodeService_RegisterNode_FullMethodName     = "/luminousmesh.NodeService/RegisterNode"
-	NodeService_Authenticate_FullMethodName     = "/luminousmesh.NodeService/Authenticate"
-	NodeService_StreamConnection_FullMethodName = "/luminousmesh.NodeService/StreamConnection"
-	NodeService_RotateToken_FullMethodName      = "/luminousmesh.NodeService/RotateToken"
+	NodeService_RegisterNode_FullMethodName          = "/luminousmesh.NodeService/RegisterNode"
+	NodeService_GetRegistrationStatus_FullMethodName = "/luminousmesh.NodeService/GetRegistrationStatus"
+	NodeService_Authenticate_FullMethodName          = "/luminousmesh.NodeService/Authenticate"
+	NodeService_StreamConnection_FullMethodName      = "/luminousmesh.NodeService/StreamConnection"
+	NodeService_RotateToken_FullMethodName           = "/luminousmesh.NodeService/RotateToken"
+	NodeService_RenewCertificate_FullMethodName      = "/luminousmesh.NodeService/RenewCertificate"
+	NodeService_GetTrustBundle_FullMethodName        = "/luminousmesh.NodeService/GetTrustBundle"
 )
 
 // NodeServiceClient is the client API for NodeService service.
@@ -33,12 +36,19 @@ const (
 type NodeServiceClient interface {
 	// Initial registration with bootstrap token
 	RegisterNode(ctx context.Context, in *RegisterNodeRequest, opts ...grpc.CallOption) (*RegisterNodeResponse, error)
+	// Poll a registration awaiting operator approval. The certificate and auth
+	// token of an approved registration are delivered once, on the first poll.
+	GetRegistrationStatus(ctx context.Context, in *RegistrationStatusRequest, opts ...grpc.CallOption) (*RegisterNodeResponse, error)
 	// Certificate-based authentication and connection establishment
 	Authenticate(ctx context.Context, in *AuthenticationRequest, opts ...grpc.CallOption) (*AuthenticationResponse, error)
 	// Bidirectional stream for node status updates and control plane commands
 	StreamConnection(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[NodeStatusUpdate, ControlPlaneCommand], error)
 	// Token rotation for enhanced security
 	RotateToken(ctx context.Context, in *TokenRotationRequest, opts ...grpc.CallOption) (*TokenRotationResponse, error)
+	// Certificate renewal, used to re-key onto the current issuing CA
+	RenewCertificate(ctx context.Context, in *CertificateRenewalRequest, opts ...grpc.CallOption) (*CertificateRenewalResponse, error)
+	// Current trust material, so nodes can refresh it without re-registering
+	GetTrustBundle(ctx context.Context, in *TrustBundleRequest, opts ...grpc.CallOption) (*TrustBundleResponse, error)
 }
 
 type nodeServiceClient struct {
@@ -59,6 +69,16 @@ func (c *nodeServiceClient) RegisterNode(ctx context.Context, in *RegisterNodeRe
 	return out, nil
 }
 
+func (c *nodeServiceClient) GetRegistrationStatus(ctx context.Context, in *RegistrationStatusRequest, opts ...grpc.CallOption) (*RegisterNodeResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RegisterNodeResponse)
+	err := c.cc.Invoke(ctx, NodeService_GetRegistrationStatus_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *nodeServiceClient) Authenticate(ctx context.Context, in *AuthenticationRequest, opts ...grpc.CallOption) (*AuthenticationResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(AuthenticationResponse)
@@ -92,6 +112,26 @@ func (c *nodeServiceClient) RotateToken(ctx context.Context, in *TokenRotationRe
 	return out, nil
 }
 
+func (c *nodeServiceClient) RenewCertificate(ctx context.Context, in *CertificateRenewalRequest, opts ...grpc.CallOption) (*CertificateRenewalResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CertificateRenewalResponse)
+	err := c.cc.Invoke(ctx, NodeService_RenewCertificate_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *nodeServiceClient) GetTrustBundle(ctx context.Context, in *TrustBundleRequest, opts ...grpc.CallOption) (*TrustBundleResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(TrustBundleResponse)
+	err := c.cc.Invoke(ctx, NodeService_GetTrustBundle_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // NodeServiceServer is the server API for NodeService service.
 // All implementations must embed UnimplementedNodeServiceServer
 // for forward compatibility.
@@ -100,12 +140,19 @@ func (c *nodeServiceClient) RotateToken(ctx context.Context, in *TokenRotationRe
 type NodeServiceServer interface {
 	// Initial registration with bootstrap token
 	RegisterNode(context.Context, *RegisterNodeRequest) (*RegisterNodeResponse, error)
+	// Poll a registration awaiting operator approval. The certificate and auth
+	// token of an approved registration are delivered once, on the first poll.
+	GetRegistrationStatus(context.Context, *RegistrationStatusRequest) (*RegisterNodeResponse, error)
 	// Certificate-based authentication and connection establishment
 	Authenticate(context.Context, *AuthenticationRequest) (*AuthenticationResponse, error)
 	// Bidirectional stream for node status updates and control plane commands
 	StreamConnection(grpc.BidiStreamingServer[NodeStatusUpdate, ControlPlaneCommand]) error
 	// Token rotation for enhanced security
 	RotateToken(context.Context, *TokenRotationRequest) (*TokenRotationResponse, error)
+	// Certificate renewal, used to re-key onto the current issuing CA
+	RenewCertificate(context.Context, *CertificateRenewalRequest) (*CertificateRenewalResponse, error)
+	// Current trust material, so nodes can refresh it without re-registering
+	GetTrustBundle(context.Context, *TrustBundleRequest) (*TrustBundleResponse, error)
 	mustEmbedUnimplementedNodeServiceServer()
 }
 
@@ -119,6 +166,9 @@ type UnimplementedNodeServiceServer struct{}
 func (UnimplementedNodeServiceServer) RegisterNode(context.Context, *RegisterNodeRequest) (*RegisterNodeResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method RegisterNode not implemented")
 }
+func (UnimplementedNodeServiceServer) GetRegistrationStatus(context.Context, *RegistrationStatusRequest) (*RegisterNodeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetRegistrationStatus not implemented")
+}
 func (UnimplementedNodeServiceServer) Authenticate(context.Context, *AuthenticationRequest) (*AuthenticationResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Authenticate not implemented")
 }
@@ -128,6 +178,12 @@ func (UnimplementedNodeServiceServer) StreamConnection(grpc.BidiStreamingServer[
 func (UnimplementedNodeServiceServer) RotateToken(context.Context, *TokenRotationRequest) (*TokenRotationResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method RotateToken not implemented")
 }
+func (UnimplementedNodeServiceServer) RenewCertificate(context.Context, *CertificateRenewalRequest) (*CertificateRenewalResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RenewCertificate not implemented")
+}
+func (UnimplementedNodeServiceServer) GetTrustBundle(context.Context, *TrustBundleRequest) (*TrustBundleResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetTrustBundle not implemented")
+}
 func (UnimplementedNodeServiceServer) mustEmbedUnimplementedNodeServiceServer() {}
 func (UnimplementedNodeServiceServer) testEmbeddedByValue()                     {}
 
@@ -167,6 +223,24 @@ func _NodeService_RegisterNode_Handler(srv interface{}, ctx context.Context, dec
 	return interceptor(ctx, in, info, handler)
 }
 
+func _NodeService_GetRegistrationStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RegistrationStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NodeServiceServer).GetRegistrationStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NodeService_GetRegistrationStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NodeServiceServer).GetRegistrationStatus(ctx, req.(*RegistrationStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _NodeService_Authenticate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(AuthenticationRequest)
 	if err := dec(in); err != nil {
@@ -210,6 +284,42 @@ func _NodeService_RotateToken_Handler(srv interface{}, ctx context.Context, dec
 	return interceptor(ctx, in, info, handler)
 }
 
+func _NodeService_RenewCertificate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CertificateRenewalRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NodeServiceServer).RenewCertificate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NodeService_RenewCertificate_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NodeServiceServer).RenewCertificate(ctx, req.(*CertificateRenewalRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NodeService_GetTrustBundle_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TrustBundleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NodeServiceServer).GetTrustBundle(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NodeService_GetTrustBundle_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NodeServiceServer).GetTrustBundle(ctx, req.(*TrustBundleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // NodeService_ServiceDesc is the grpc.ServiceDesc for NodeService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -221,6 +331,10 @@ var NodeService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "RegisterNode",
 			Handler:    _NodeService_RegisterNode_Handler,
 		},
+		{
+			MethodName: "GetRegistrationStatus",
+			Handler:    _NodeService_GetRegistrationStatus_Handler,
+		},
 		{
 			MethodName: "Authenticate",
 			Handler:    _NodeService_Authenticate_Handler,
@@ -229,6 +343,14 @@ var NodeService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "RotateToken",
 			Handler:    _NodeService_RotateToken_Handler,
 		},
+		{
+			MethodName: "RenewCertificate",
+			Handler:    _NodeService_RenewCertificate_Handler,
+		},
+		{
+			MethodName: "GetTrustBundle",
+			Handler:    _NodeService_GetTrustBundle_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{